@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -8,15 +10,27 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"strings"
 	"testing"
 )
 
-func TestAPI_OverAssignment(t *testing.T) {
-	// Initialize engine
-	engine = assignment.NewEngine(&InMemoryStore{}, &InMemoryRoster{}, &InMemoryRules{})
+// useTestStore points the handlers and the engine at a fresh memory store
+// until the test ends.
+func useTestStore(t testing.TB) *db.MemoryStore {
+	t.Helper()
+	s := db.NewMemoryStore()
+	oldStore, oldEngine := store, engine
+	store = s
+	engine = assignment.NewEngine(s, &db.StoreRoster{Repo: s}, &db.StoreRules{Repo: s})
+	t.Cleanup(func() { store, engine = oldStore, oldEngine })
+	return s
+}
 
-	// Reset global state to ensure clean test
-	radiologistWorkload = make(map[string]int64)
+func TestAPI_OverAssignment(t *testing.T) {
+	s := useTestStore(t)
+	s.SaveRadiologist(context.Background(), &models.Radiologist{ID: "rad_limited", MaxConcurrentStudies: 1, Status: "active"})
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -61,14 +75,13 @@ func TestAPI_OverAssignment(t *testing.T) {
 
 	// Find the created shift ID
 	var createdShiftID int64
-	shiftsMu.RLock()
-	for _, s := range shifts {
-		if s.Name == shiftName {
-			createdShiftID = s.ID
+	shifts, _ := s.ListShifts(context.Background())
+	for _, sh := range shifts {
+		if sh.Name == shiftName {
+			createdShiftID = sh.ID
 			break
 		}
 	}
-	shiftsMu.RUnlock()
 
 	if createdShiftID == 0 {
 		t.Fatalf("Failed to find created shift ID")
@@ -122,4 +135,72 @@ func TestAPI_OverAssignment(t *testing.T) {
 		t.Errorf("Expected 503 Service Unavailable (Over Capacity), got %d", resp2.StatusCode)
 	}
 	log.Println("TestAPI_OverAssignment: Simulate 2 done")
+
+	// 5. Cancelling the first order frees the radiologist's capacity
+	resp3, err := http.PostForm(ts.URL+"/api/simulate", url.Values{
+		"study_id":      {"STUDY_1"},
+		"modality":      {"XRAY"},
+		"order_control": {"CA"},
+	})
+	if err != nil {
+		t.Fatalf("Failed cancel API call: %v", err)
+	}
+	resp3.Body.Close()
+	if resp3.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 OK for cancel, got %d", resp3.StatusCode)
+	}
+
+	resp4, err := http.PostForm(ts.URL+"/api/simulate", url.Values{
+		"study_id": {"STUDY_2"},
+		"modality": {"XRAY"},
+	})
+	if err != nil {
+		t.Fatalf("Failed simulation 3 API call: %v", err)
+	}
+	defer resp4.Body.Close()
+	if resp4.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp4.Body)
+		t.Errorf("Expected 200 OK after cancel freed capacity, got %d. Body: %s", resp4.StatusCode, body)
+	}
+}
+
+func TestAPI_SimulateExplain(t *testing.T) {
+	s := useTestStore(t)
+	ctx := context.Background()
+	s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad1", Status: "active"})
+	s.CreateShift(ctx, &models.Shift{Name: "CT", WorkType: "CT"})
+	s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 1, RadiologistID: "rad1"})
+
+	simulate := func(studyID, modality string) (*httptest.ResponseRecorder, SimulateExplanation) {
+		form := url.Values{"study_id": {studyID}, "modality": {modality}}
+		req := httptest.NewRequest("POST", "/api/simulate?explain=1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handleSimulateAssignment(w, req)
+		var resp SimulateExplanation
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Expected a JSON explanation, got %v: %s", err, w.Body.String())
+		}
+		return w, resp
+	}
+
+	w, resp := simulate("S1", "CT")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Assignment == nil || resp.Assignment.RadiologistID != "rad1" || resp.Trace == nil || len(resp.Trace.Scores) != 1 {
+		t.Errorf("Expected rad1 with a trace, got %+v", resp)
+	}
+	stored, _ := s.GetAssignmentByStudyID(ctx, "S1")
+	if stored == nil || stored.Trace == nil || stored.Trace.Outcome != resp.Trace.Outcome {
+		t.Errorf("Expected the trace to be stored with the assignment, got %+v", stored)
+	}
+
+	w, resp = simulate("S2", "MR")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when nothing matches, got %d", w.Code)
+	}
+	if resp.Error == "" || resp.Trace == nil || !strings.HasPrefix(resp.Trace.Outcome, "failed: ") {
+		t.Errorf("Expected the failure to be explained, got %+v", resp)
+	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupAssignments(t *testing.T) *db.MemoryStore {
+	t.Helper()
+	s := useTestStore(t)
+	for _, a := range []*models.Assignment{
+		{StudyID: "ST1", RadiologistID: "rad1", AssignedAt: time.Now(), Status: models.StatusAssigned},
+		{StudyID: "ST2", RadiologistID: "rad1", AssignedAt: time.Now(), Status: models.StatusInProgress},
+		{StudyID: "ST3", RadiologistID: "rad1", AssignedAt: time.Now(), Status: models.StatusFinalized},
+	} {
+		s.SaveAssignment(context.Background(), a)
+	}
+	return s
+}
+
+func postStatus(studyID, status string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	form.Add("study_id", studyID)
+	form.Add("status", status)
+	req := httptest.NewRequest("POST", "/api/assignments/status", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handleAssignmentStatus(w, req)
+	return w
+}
+
+func TestHandleAssignmentStatus_UpdatesWorkload(t *testing.T) {
+	store := setupAssignments(t)
+
+	counts, _ := store.GetRadiologistWorkloads(context.Background(), []string{"rad1"})
+	if counts["rad1"] != 2 {
+		t.Errorf("Expected 2 open studies, got %d", counts["rad1"])
+	}
+
+	if w := postStatus("ST2", models.StatusReported); w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+	}
+
+	counts, _ = store.GetRadiologistWorkloads(context.Background(), []string{"rad1"})
+	if counts["rad1"] != 1 {
+		t.Errorf("Expected 1 open study after reporting, got %d", counts["rad1"])
+	}
+	if load, _ := store.GetRadiologistCurrentWorkload(context.Background(), "rad1"); load != 1 {
+		t.Errorf("Expected current workload 1, got %d", load)
+	}
+}
+
+func TestHandleAssignmentStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		studyID  string
+		status   string
+		wantCode int
+	}{
+		{"Start reading", "ST1", models.StatusInProgress, http.StatusSeeOther},
+		{"Cancel in progress", "ST2", models.StatusCancelled, http.StatusSeeOther},
+		{"Reopen finalized", "ST3", models.StatusInProgress, http.StatusConflict},
+		{"Unknown study", "ST9", models.StatusReported, http.StatusNotFound},
+		{"Unknown status", "ST1", "archived", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupAssignments(t)
+			if w := postStatus(tt.studyID, tt.status); w.Code != tt.wantCode {
+				t.Errorf("Expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
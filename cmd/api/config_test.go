@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,8 +11,7 @@ import (
 )
 
 func TestHandleAPISites(t *testing.T) {
-	// Setup
-	refData = &models.ReferenceData{Sites: []models.Site{}}
+	useTestStore(t)
 
 	form := url.Values{}
 	form.Add("code", "TEST_SITE")
@@ -27,19 +27,17 @@ func TestHandleAPISites(t *testing.T) {
 		t.Errorf("Expected redirect 303, got %d", w.Code)
 	}
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Sites) != 1 {
 		t.Errorf("Expected 1 site, got %d", len(refData.Sites))
 	} else if refData.Sites[0].Code != "TEST_SITE" {
 		t.Errorf("Site code mismatch: %s", refData.Sites[0].Code)
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleDeleteSite(t *testing.T) {
-	refData = &models.ReferenceData{
-		Sites: []models.Site{{Code: "DEL_SITE", Name: "Delete Me"}},
-	}
+	s := useTestStore(t)
+	s.SaveSite(context.Background(), models.Site{Code: "DEL_SITE", Name: "Delete Me"})
 
 	form := url.Values{}
 	form.Add("code", "DEL_SITE")
@@ -50,15 +48,14 @@ func TestHandleDeleteSite(t *testing.T) {
 
 	handleDeleteSite(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Sites) != 0 {
 		t.Errorf("Expected 0 sites, got %d", len(refData.Sites))
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleAPIModalities(t *testing.T) {
-	refData = &models.ReferenceData{Modalities: []models.Modality{}}
+	useTestStore(t)
 
 	form := url.Values{}
 	form.Add("code", "TEST_MOD")
@@ -70,15 +67,14 @@ func TestHandleAPIModalities(t *testing.T) {
 
 	handleAPIModalities(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Modalities) != 1 {
 		t.Errorf("Expected 1 modality, got %d", len(refData.Modalities))
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleAPIBodyParts(t *testing.T) {
-	refData = &models.ReferenceData{BodyParts: []models.BodyPart{}}
+	useTestStore(t)
 
 	form := url.Values{}
 	form.Add("name", "TestBodyPart")
@@ -89,15 +85,14 @@ func TestHandleAPIBodyParts(t *testing.T) {
 
 	handleAPIBodyParts(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.BodyParts) != 1 {
 		t.Errorf("Expected 1 body part, got %d", len(refData.BodyParts))
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleAPICredentials(t *testing.T) {
-	refData = &models.ReferenceData{Credentials: []models.Credential{}}
+	useTestStore(t)
 
 	form := url.Values{}
 	form.Add("code", "TEST_CRED")
@@ -109,17 +104,15 @@ func TestHandleAPICredentials(t *testing.T) {
 
 	handleAPICredentials(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Credentials) != 1 {
 		t.Errorf("Expected 1 credential, got %d", len(refData.Credentials))
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleEditSite(t *testing.T) {
-	refData = &models.ReferenceData{
-		Sites: []models.Site{{Code: "EDIT_SITE", Name: "Edit Me"}},
-	}
+	s := useTestStore(t)
+	s.SaveSite(context.Background(), models.Site{Code: "EDIT_SITE", Name: "Edit Me"})
 
 	form := url.Values{}
 	form.Add("original_code", "EDIT_SITE")
@@ -132,19 +125,17 @@ func TestHandleEditSite(t *testing.T) {
 
 	handleEditSite(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Sites) != 1 {
 		t.Errorf("Expected 1 site, got %d", len(refData.Sites))
 	} else if refData.Sites[0].Code != "EDITED_SITE" || refData.Sites[0].Name != "Edited Name" {
 		t.Errorf("Site not updated correctly: %+v", refData.Sites[0])
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleEditModality(t *testing.T) {
-	refData = &models.ReferenceData{
-		Modalities: []models.Modality{{Code: "EDIT_MOD", Name: "Edit Mod"}},
-	}
+	s := useTestStore(t)
+	s.SaveModality(context.Background(), models.Modality{Code: "EDIT_MOD", Name: "Edit Mod"})
 
 	form := url.Values{}
 	form.Add("original_code", "EDIT_MOD")
@@ -157,19 +148,17 @@ func TestHandleEditModality(t *testing.T) {
 
 	handleEditModality(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Modalities) != 1 {
 		t.Errorf("Expected 1 modality, got %d", len(refData.Modalities))
 	} else if refData.Modalities[0].Code != "EDITED_MOD" || refData.Modalities[0].Name != "Edited Mod Name" {
 		t.Errorf("Modality not updated correctly: %+v", refData.Modalities[0])
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleEditBodyPart(t *testing.T) {
-	refData = &models.ReferenceData{
-		BodyParts: []models.BodyPart{{Name: "EditBodyPart"}},
-	}
+	s := useTestStore(t)
+	s.SaveBodyPart(context.Background(), models.BodyPart{Name: "EditBodyPart"})
 
 	form := url.Values{}
 	form.Add("original_name", "EditBodyPart")
@@ -181,19 +170,17 @@ func TestHandleEditBodyPart(t *testing.T) {
 
 	handleEditBodyPart(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.BodyParts) != 1 {
 		t.Errorf("Expected 1 body part, got %d", len(refData.BodyParts))
 	} else if refData.BodyParts[0].Name != "EditedBodyPart" {
 		t.Errorf("Body part not updated correctly: %+v", refData.BodyParts[0])
 	}
-	configMu.RUnlock()
 }
 
 func TestHandleEditCredential(t *testing.T) {
-	refData = &models.ReferenceData{
-		Credentials: []models.Credential{{Code: "EDIT_CRED", Name: "Edit Cred"}},
-	}
+	s := useTestStore(t)
+	s.SaveCredential(context.Background(), models.Credential{Code: "EDIT_CRED", Name: "Edit Cred"})
 
 	form := url.Values{}
 	form.Add("original_code", "EDIT_CRED")
@@ -206,11 +193,10 @@ func TestHandleEditCredential(t *testing.T) {
 
 	handleEditCredential(w, req)
 
-	configMu.RLock()
+	refData, _ := store.GetReferenceData(context.Background())
 	if len(refData.Credentials) != 1 {
 		t.Errorf("Expected 1 credential, got %d", len(refData.Credentials))
 	} else if refData.Credentials[0].Code != "EDITED_CRED" || refData.Credentials[0].Name != "Edited Cred Name" {
 		t.Errorf("Credential not updated correctly: %+v", refData.Credentials[0])
 	}
-	configMu.RUnlock()
 }
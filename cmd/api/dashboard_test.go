@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -12,25 +13,16 @@ import (
 
 func TestHandleDashboard(t *testing.T) {
 	// Setup mock data
-	assignmentsMu.Lock()
-    oldAssignments := assignments
-    assignments = []*models.Assignment{}
+	s := useTestStore(t)
 	for i := 0; i < 60; i++ {
-		assignments = append(assignments, &models.Assignment{
-			ID:            int64(i),
+		s.SaveAssignment(context.Background(), &models.Assignment{
 			StudyID:       fmt.Sprintf("ST%d", i),
 			RadiologistID: "rad1",
 			ShiftID:       1,
 			AssignedAt:    time.Now(),
-            Strategy:      "test",
+			Strategy:      "test",
 		})
 	}
-	assignmentsMu.Unlock()
-    defer func() {
-        assignmentsMu.Lock()
-        assignments = oldAssignments
-        assignmentsMu.Unlock()
-    }()
 
 	req, err := http.NewRequest("GET", "/", nil)
 	if err != nil {
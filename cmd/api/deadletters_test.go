@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"strings"
+	"testing"
+)
+
+func setupDeadLetters(t *testing.T) {
+	t.Helper()
+	store, err := queue.NewDeadLetterStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create dead letter store: %v", err)
+	}
+	deadLetters = store
+
+	for _, id := range []string{"dl1", "dl2"} {
+		err := deadLetters.Add(&queue.DeadLetter{
+			ID: id,
+			Event: &models.AssignmentEvent{
+				Study:      &models.Study{ID: "ST-" + id},
+				Assignment: &models.Assignment{StudyID: "ST-" + id, RadiologistID: "rad1"},
+			},
+			Message:   "MSH|^~\\&|RAD||||\rZRA|1|rad1\r",
+			Attempts:  5,
+			LastError: "connection refused",
+		})
+		if err != nil {
+			t.Fatalf("Failed to add dead letter: %v", err)
+		}
+	}
+}
+
+func postDeadLetterAction(handler http.HandlerFunc, id string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	form.Add("id", id)
+	req := httptest.NewRequest("POST", "/api/deadletters", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestHandleDeadLetters(t *testing.T) {
+	setupDeadLetters(t)
+
+	req := httptest.NewRequest("GET", "/deadletters", nil)
+	w := httptest.NewRecorder()
+	handleDeadLetters(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"ST-dl1", "ST-dl2", "connection refused", "/api/deadletters/resend"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandleAPIDeadLetters(t *testing.T) {
+	setupDeadLetters(t)
+
+	req := httptest.NewRequest("GET", "/api/deadletters", nil)
+	w := httptest.NewRecorder()
+	handleAPIDeadLetters(w, req)
+
+	var list []*queue.DeadLetter
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("Expected 2 dead letters, got %d", len(list))
+	}
+}
+
+func TestHandleResendDeadLetter(t *testing.T) {
+	setupDeadLetters(t)
+
+	w := postDeadLetterAction(handleResendDeadLetter, "dl1")
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect 303, got %d", w.Code)
+	}
+
+	pending, _ := deadLetters.PendingResends()
+	if len(pending) != 1 || pending[0].ID != "dl1" {
+		t.Errorf("Expected dl1 to be queued for resend, got %v", pending)
+	}
+	list, _ := deadLetters.List()
+	if len(list) != 1 {
+		t.Errorf("Expected 1 remaining dead letter, got %d", len(list))
+	}
+}
+
+func TestHandleDiscardDeadLetter(t *testing.T) {
+	setupDeadLetters(t)
+
+	w := postDeadLetterAction(handleDiscardDeadLetter, "dl2")
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect 303, got %d", w.Code)
+	}
+	list, _ := deadLetters.List()
+	if len(list) != 1 || list[0].ID != "dl1" {
+		t.Errorf("Expected only dl1 to remain, got %v", list)
+	}
+
+	w = postDeadLetterAction(handleDiscardDeadLetter, "dl2")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown dead letter, got %d", w.Code)
+	}
+}
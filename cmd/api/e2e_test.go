@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"radiology-assignment/internal/assignment"
 	"strings"
 	"testing"
 	"time"
@@ -14,8 +13,10 @@ import (
 )
 
 func TestE2E(t *testing.T) {
-	// Initialize engine for testing as main() is skipped
-	engine = assignment.NewEngine(&InMemoryStore{}, &InMemoryRoster{}, &InMemoryRules{})
+	// Seed the demo data main() would, so there are shifts and radiologists
+	if err := seedStore(context.Background(), useTestStore(t), true); err != nil {
+		t.Fatalf("Failed to seed store: %v", err)
+	}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
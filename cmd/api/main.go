@@ -10,196 +10,26 @@ import (
 	"net/http"
 	"os"
 	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/cache"
+	"radiology-assignment/internal/db"
 	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 var (
-	// Mock Data Store
-	rulesMu sync.RWMutex
-	rules   = []*models.AssignmentRule{
-		{ID: 1, Name: "Priority Stroke", PriorityOrder: 1, ActionType: "ESCALATE", Enabled: true},
-		{ID: 2, Name: "MSK Load Balance", PriorityOrder: 2, ActionType: "ASSIGN_TO_SHIFT", Enabled: true},
-	}
-
-	assignmentsMu sync.RWMutex
-	assignments   = []*models.Assignment{
-		{ID: 101, StudyID: "ST1001", RadiologistID: "rad1", ShiftID: 1, Strategy: "load_balanced", AssignedAt: time.Now().Add(-5 * time.Minute)},
-		{ID: 102, StudyID: "ST1002", RadiologistID: "rad2", ShiftID: 1, Strategy: "primary", AssignedAt: time.Now().Add(-2 * time.Minute)},
-		{ID: 103, StudyID: "ST1003", RadiologistID: "rad_vip", ShiftID: 2, Strategy: "special_arrangement", AssignedAt: time.Now().Add(-1 * time.Minute)},
-	}
-
-	radiologistWorkload = make(map[string]int64)
-
-	shiftsMu sync.RWMutex
-	shifts   = []*models.Shift{
-		{ID: 1, Name: "Morning MRI", WorkType: "MRI", Sites: []string{"SiteA"}, PriorityLevel: 1, RequiredCredentials: []string{"MRI"}},
-		{ID: 2, Name: "Night CT", WorkType: "CT", Sites: []string{"SiteB"}, PriorityLevel: 2, RequiredCredentials: []string{"CT"}},
-	}
-
-	rosterMu sync.RWMutex
-	roster   = []*models.RosterEntry{
-		{ID: 1, ShiftID: 1, RadiologistID: "rad1", StartDate: time.Now(), Status: "active"},
-	}
-
-	proceduresMu sync.RWMutex
-	procedures   = []*models.Procedure{
-		{ID: 1, Code: "CTHEAD", Description: "CT Head without contrast", Modality: "CT", BodyPart: "Head", CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		{ID: 2, Code: "MRKNEE", Description: "MRI Knee", Modality: "MRI", BodyPart: "Knee", CreatedAt: time.Now(), UpdatedAt: time.Now()},
-	}
-
-	configMu sync.RWMutex
-	refData  = &models.ReferenceData{
-		Sites: []models.Site{
-			{Code: "SiteA", Name: "Site A Clinic"},
-			{Code: "SiteB", Name: "Site B Hospital"},
-		},
-		Modalities: []models.Modality{
-			{Code: "CT", Name: "Computed Tomography"},
-			{Code: "MRI", Name: "Magnetic Resonance Imaging"},
-			{Code: "US", Name: "Ultrasound"},
-			{Code: "XR", Name: "X-Ray"},
-		},
-		BodyParts: []models.BodyPart{
-			{Name: "Head"},
-			{Name: "Chest"},
-			{Name: "Abdomen"},
-			{Name: "Pelvis"},
-			{Name: "Spine"},
-			{Name: "Extremity"},
-		},
-		Credentials: []models.Credential{
-			{Code: "CT", Name: "CT Certified"},
-			{Code: "MRI", Name: "MRI Certified"},
-			{Code: "US", Name: "Ultrasound Certified"},
-			{Code: "Neuro", Name: "Neuroradiology"},
-			{Code: "MSK", Name: "Musculoskeletal"},
-		},
-	}
-
-	// Mock Radiologists for assignment
-	radiologists = []*models.Radiologist{
-		{ID: "rad1", FirstName: "John", LastName: "Doe", MaxConcurrentStudies: 5, Status: "active"},
-		{ID: "rad2", FirstName: "Jane", LastName: "Smith", MaxConcurrentStudies: 5, Status: "active"},
-		{ID: "rad3", FirstName: "Bob", LastName: "Jones", MaxConcurrentStudies: 5, Status: "active"},
-		{ID: "rad_limited", FirstName: "Limited", LastName: "Capacity", MaxConcurrentStudies: 1, Status: "active"},
-	}
-
-	radiologistsMap map[string]*models.Radiologist
+	// Backend for all admin data, see openStore
+	store db.Store = db.NewMemoryStore()
 
 	// Assignment Engine Instance
 	engine *assignment.Engine
-)
-
-func init() {
-	// Initialize workload map from static assignments
-	for _, a := range assignments {
-		radiologistWorkload[a.RadiologistID]++
-	}
-	radiologistsMap = make(map[string]*models.Radiologist)
-	for _, rad := range radiologists {
-		radiologistsMap[rad.ID] = rad
-
-	}
-}
-
-// Implement Interfaces
-type InMemoryStore struct{}
-
-func (s *InMemoryStore) GetShiftsByWorkType(ctx context.Context, modality, bodyPart string, site string) ([]*models.Shift, error) {
-	shiftsMu.RLock()
-	defer shiftsMu.RUnlock()
-	var result []*models.Shift
-	for _, shift := range shifts {
-		// Simple match logic for demo
-		if strings.Contains(shift.WorkType, modality) {
-			result = append(result, shift)
-		}
-	}
-	return result, nil
-}
-
-func (s *InMemoryStore) GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error) {
-	if rad, ok := radiologistsMap[id]; ok {
-		// Ensure Status is set for logic
-		rad.Status = "active"
-		return rad, nil
-	}
-	return nil, errors.New("radiologist not found")
-}
 
-func (s *InMemoryStore) GetRadiologists(ctx context.Context, ids []string) ([]*models.Radiologist, error) {
-	var result []*models.Radiologist
-	idMap := make(map[string]bool)
-	for _, id := range ids {
-		idMap[id] = true
-	}
-
-	for _, rad := range radiologists {
-		if idMap[rad.ID] {
-			result = append(result, rad)
-		}
-	}
-	return result, nil
-}
-
-func (s *InMemoryStore) GetRadiologistCurrentWorkload(ctx context.Context, radiologistID string) (int64, error) {
-	assignmentsMu.RLock()
-	defer assignmentsMu.RUnlock()
-	return radiologistWorkload[radiologistID], nil
-}
-
-func (s *InMemoryStore) GetRadiologistWorkloads(ctx context.Context, radiologistIDs []string) (map[string]int64, error) {
-	assignmentsMu.RLock()
-	defer assignmentsMu.RUnlock()
-	counts := make(map[string]int64)
-	targetIDs := make(map[string]bool)
-	for _, id := range radiologistIDs {
-		counts[id] = 0
-		targetIDs[id] = true
-	}
-
-	for _, a := range assignments {
-		if targetIDs[a.RadiologistID] {
-			counts[a.RadiologistID]++
-		}
-	}
-	return counts, nil
-}
-
-func (s *InMemoryStore) SaveAssignment(ctx context.Context, a *models.Assignment) error {
-	assignmentsMu.Lock()
-	defer assignmentsMu.Unlock()
-	a.ID = int64(len(assignments) + 1)
-	assignments = append(assignments, a)
-	radiologistWorkload[a.RadiologistID]++
-	return nil
-}
-
-type InMemoryRoster struct{}
-
-func (r *InMemoryRoster) GetByShift(shiftID int64) []*models.RosterEntry {
-	rosterMu.RLock()
-	defer rosterMu.RUnlock()
-	var result []*models.RosterEntry
-	for _, entry := range roster {
-		if entry.ShiftID == shiftID {
-			result = append(result, entry)
-		}
-	}
-	return result
-}
-
-type InMemoryRules struct{}
-
-func (r *InMemoryRules) GetActive() []*models.AssignmentRule {
-	rulesMu.RLock()
-	defer rulesMu.RUnlock()
-	return rules
-}
+	// Outbound ZRA messages the emitter could not deliver
+	deadLetters *queue.DeadLetterStore
+)
 
 // Data Structs for UI
 type DashboardData struct {
@@ -207,24 +37,32 @@ type DashboardData struct {
 	ActiveRads        int
 	PendingStudies    int
 	RecentAssignments []*models.Assignment
+	Statuses          []string
 }
 
 type RulesData struct {
-	Rules []*models.AssignmentRule
+	Rules  []*models.AssignmentRule
+	Fields []models.ConditionField // For the condition tree builder
+	Chains []string
 }
 
 type ShiftsData struct {
 	Shifts       []*models.Shift
 	Roster       map[int64][]*models.RosterEntry
+	Ineligible   map[int64]string // Roster entry ID to why the radiologist cannot work the shift
 	Radiologists []*models.Radiologist
 	Sites        []models.Site
 	Modalities   []models.Modality
+	BodyParts    []models.BodyPart
 	Credentials  []models.Credential
+	Urgencies    []string
+	Weekdays     []string
 }
 
 type CalendarData struct {
 	ViewName       string
 	View           string
+	LeadingBlanks  int // Empty cells before the first day in the month grid
 	Days           []CalendarDay
 	UnfilledShifts []CalendarShift
 }
@@ -238,24 +76,78 @@ type CalendarShift struct {
 	ShiftName   string
 	ShiftType   string
 	Date        time.Time
+	Start       time.Time // In the shift's time zone
+	End         time.Time
+	Overnight   bool
 	Filled      bool
 	Radiologist string
 }
 
+type RadiologistsData struct {
+	Radiologists   []*models.Radiologist
+	Availability   map[string]string // Radiologist ID to availability, at_capacity included
+	Workloads      map[string]int64
+	Credentials    []models.Credential
+	Statuses       []string
+	Availabilities []string
+}
+
 type ProceduresData struct {
 	Procedures []*models.Procedure
+	Unmapped   []*models.UnmappedProcedure
 	Modalities []models.Modality
 	BodyParts  []models.BodyPart
 }
 
+// SimulateExplanation is the /api/simulate response in explain mode.
+type SimulateExplanation struct {
+	Assignment *models.Assignment      `json:"assignment,omitempty"`
+	Trace      *models.AssignmentTrace `json:"trace"`
+	Error      string                  `json:"error,omitempty"`
+}
+
+type DeadLettersData struct {
+	DeadLetters []*queue.DeadLetter
+	Error       string
+}
+
 func main() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	refreshInterval := cache.DefaultRefreshInterval
+	if v := os.Getenv("CACHE_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid CACHE_REFRESH_INTERVAL %q", v)
+		}
+		refreshInterval = d
+	}
+
+	cached, err := openStore(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer cached.Close()
+	store = cached
+
+	// Edits made through the store reach the caches straight away; the
+	// refresh picks up changes made by other processes
+	go cached.Run(context.Background(), refreshInterval)
+
 	// Initialize Engine
-	engine = assignment.NewEngine(&InMemoryStore{}, &InMemoryRoster{}, &InMemoryRules{})
+	engine = assignment.NewEngine(cached, cached.Roster, cached.Rules)
+
+	deadLetterDir := os.Getenv("DEADLETTER_DIR")
+	if deadLetterDir == "" {
+		deadLetterDir = "data/deadletter"
+	}
+	deadLetters, err = queue.NewDeadLetterStore(deadLetterDir)
+	if err != nil {
+		log.Fatalf("Failed to open dead letter store: %v", err)
+	}
 
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("ui/static"))))
 	http.HandleFunc("/", handleDashboard)
@@ -270,10 +162,19 @@ func main() {
 	http.HandleFunc("/api/shifts/delete", handleDeleteShift)
 	http.HandleFunc("/api/shifts/assign", handleAssignRadiologist)
 
+	http.HandleFunc("/radiologists", handleRadiologists)
+	http.HandleFunc("/api/radiologists", handleAPIRadiologists)
+	http.HandleFunc("/api/radiologists/edit", handleEditRadiologist)
+	http.HandleFunc("/api/radiologists/deactivate", handleDeactivateRadiologist)
+	http.HandleFunc("/api/radiologists/availability", handleRadiologistAvailability)
+
 	http.HandleFunc("/procedures", handleProcedures)
 	http.HandleFunc("/api/procedures", handleAPIProcedures)
 	http.HandleFunc("/api/procedures/edit", handleEditProcedure)
 	http.HandleFunc("/api/procedures/delete", handleDeleteProcedure)
+	http.HandleFunc("/api/procedures/unmapped", handleAPIUnmappedProcedures)
+	http.HandleFunc("/api/procedures/unmapped/promote", handlePromoteUnmappedProcedure)
+	http.HandleFunc("/api/procedures/unmapped/delete", handleDismissUnmappedProcedure)
 
 	http.HandleFunc("/config", handleConfig)
 	http.HandleFunc("/api/config/sites", handleAPISites)
@@ -291,7 +192,13 @@ func main() {
 
 	http.HandleFunc("/calendar", handleCalendar)
 
+	http.HandleFunc("/deadletters", handleDeadLetters)
+	http.HandleFunc("/api/deadletters", handleAPIDeadLetters)
+	http.HandleFunc("/api/deadletters/resend", handleResendDeadLetter)
+	http.HandleFunc("/api/deadletters/delete", handleDiscardDeadLetter)
+
 	http.HandleFunc("/api/simulate", handleSimulateAssignment)
+	http.HandleFunc("/api/assignments/status", handleAssignmentStatus)
 
 	log.Printf("API/UI Server started on :%s", port)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
@@ -341,97 +248,75 @@ func render(w http.ResponseWriter, tmplName string, data interface{}, files ...s
 	}
 }
 
+// storeError reports a failed store call, as 404 when the record does not
+// exist and 409 when it would duplicate another.
+func storeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, db.ErrDuplicate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func handleDashboard(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
 
-	assignmentsMu.RLock()
-	totalCount := len(assignments)
 	// Limit to last 50 assignments for display
-	limit := 50
-	l := limit
-	if l > totalCount {
-		l = totalCount
+	recent, err := store.ListAssignments(r.Context(), 50)
+	if err != nil {
+		storeError(w, err)
+		return
 	}
-	start := totalCount - l
-	recent := make([]*models.Assignment, l)
-	copy(recent, assignments[start:])
-
-	// Reverse order for display
-	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
-		recent[i], recent[j] = recent[j], recent[i]
+	totalCount, pending, err := store.CountAssignments(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
 	}
-	assignmentsMu.RUnlock()
 
 	data := DashboardData{
-		AssignmentsCount:  totalCount,
+		AssignmentsCount:  int(totalCount),
 		ActiveRads:        18,
-		PendingStudies:    3,
+		PendingStudies:    int(pending),
 		RecentAssignments: recent,
+		Statuses: []string{
+			models.StatusAssigned, models.StatusInProgress, models.StatusReported,
+			models.StatusFinalized, models.StatusCancelled,
+		},
 	}
 	render(w, "dashboard", data, "ui/templates/dashboard.html")
 }
 
 func handleRules(w http.ResponseWriter, r *http.Request) {
-	rulesMu.RLock()
+	list, err := store.ListRules(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
 	data := RulesData{
-		Rules: rules,
+		Rules:  list,
+		Fields: models.ConditionFields(),
+		Chains: models.RuleChains,
 	}
-	rulesMu.RUnlock()
 
 	render(w, "rules", data, "ui/templates/rules.html")
 }
 
-func extractFilters(r *http.Request) map[string]interface{} {
+// extractFilters reads the condition tree the rules page builds, a JSON
+// object in the form described at models.ParseConditions.
+func extractFilters(r *http.Request) (map[string]interface{}, error) {
 	filters := make(map[string]interface{})
-
-	if val := r.FormValue("filter_urgency"); val != "" {
-		filters["urgency"] = val
-	}
-	if val := r.FormValue("filter_site"); val != "" {
-		filters["site"] = val
-	}
-	if val := r.FormValue("filter_ordering_physician"); val != "" {
-		filters["ordering_physician"] = val
-	}
-	if val := r.FormValue("filter_procedure_code"); val != "" {
-		filters["procedure_code"] = val
-	}
-	if val := r.FormValue("filter_procedure_description"); val != "" {
-		filters["procedure_description"] = val
-	}
-	if val := r.FormValue("filter_prior_location"); val != "" {
-		filters["prior_location"] = val
-	}
-	if val := r.FormValue("filter_technician"); val != "" {
-		filters["technician"] = val
-	}
-	if val := r.FormValue("filter_transcriptionist"); val != "" {
-		filters["transcriptionist"] = val
-	}
-	if val := r.FormValue("filter_patient_age_min"); val != "" {
-		if v, err := strconv.Atoi(val); err == nil {
-			filters["patient_age_min"] = v
-		}
-	}
-	if val := r.FormValue("filter_patient_age_max"); val != "" {
-		if v, err := strconv.Atoi(val); err == nil {
-			filters["patient_age_max"] = v
-		}
-	}
-	if val := r.FormValue("filter_days_of_week"); val != "" {
-		// split by comma
-		days := strings.Split(val, ",")
-		// trim spaces
-		for i := range days {
-			days[i] = strings.TrimSpace(days[i])
+	if val := strings.TrimSpace(r.FormValue("conditions")); val != "" {
+		if err := json.Unmarshal([]byte(val), &filters); err != nil {
+			return nil, fmt.Errorf("conditions are not a JSON object: %w", err)
 		}
-		filters["days_of_week"] = days
 	}
-
-	return filters
+	return filters, nil
 }
 
 func handleAPIRules(w http.ResponseWriter, r *http.Request) {
@@ -444,20 +329,35 @@ func handleAPIRules(w http.ResponseWriter, r *http.Request) {
 		name := r.FormValue("name")
 		action := r.FormValue("action")
 		target := r.FormValue("target")
-		filters := extractFilters(r)
+		filters, err := extractFilters(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		rulesMu.Lock()
+		existing, err := store.ListRules(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
 		newRule := &models.AssignmentRule{
-			ID:               int64(len(rules) + 1),
 			Name:             name,
 			ActionType:       action,
 			ActionTarget:     target,
 			ConditionFilters: filters,
+			Chain:            r.FormValue("chain"),
+			Soft:             r.FormValue("soft") != "",
 			Enabled:          true,
-			PriorityOrder:    len(rules) + 1,
+			PriorityOrder:    len(existing) + 1,
+		}
+		if err := newRule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.CreateRule(r.Context(), newRule); err != nil {
+			storeError(w, err)
+			return
 		}
-		rules = append(rules, newRule)
-		rulesMu.Unlock()
 
 		http.Redirect(w, r, "/rules", http.StatusSeeOther)
 		return
@@ -476,7 +376,11 @@ func handleEditRule(w http.ResponseWriter, r *http.Request) {
 		name := r.FormValue("name")
 		action := r.FormValue("action")
 		target := r.FormValue("target")
-		filters := extractFilters(r)
+		filters, err := extractFilters(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
@@ -484,17 +388,25 @@ func handleEditRule(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		rulesMu.Lock()
-		for _, rule := range rules {
-			if rule.ID == id {
-				rule.Name = name
-				rule.ActionType = action
-				rule.ActionTarget = target
-				rule.ConditionFilters = filters
-				break
-			}
+		rule, err := store.GetRule(r.Context(), id)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		rule.Name = name
+		rule.ActionType = action
+		rule.ActionTarget = target
+		rule.ConditionFilters = filters
+		rule.Chain = r.FormValue("chain")
+		rule.Soft = r.FormValue("soft") != ""
+		if err := rule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.UpdateRule(r.Context(), rule); err != nil {
+			storeError(w, err)
+			return
 		}
-		rulesMu.Unlock()
 
 		http.Redirect(w, r, "/rules", http.StatusSeeOther)
 		return
@@ -516,15 +428,10 @@ func handleDeleteRule(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		rulesMu.Lock()
-		newRules := []*models.AssignmentRule{}
-		for _, rule := range rules {
-			if rule.ID != id {
-				newRules = append(newRules, rule)
-			}
+		if err := store.DeleteRule(r.Context(), id); err != nil {
+			storeError(w, err)
+			return
 		}
-		rules = newRules
-		rulesMu.Unlock()
 
 		http.Redirect(w, r, "/rules", http.StatusSeeOther)
 		return
@@ -535,29 +442,64 @@ func handleDeleteRule(w http.ResponseWriter, r *http.Request) {
 // Shifts Handlers
 
 func handleShifts(w http.ResponseWriter, r *http.Request) {
-	shiftsMu.RLock()
-	rosterMu.RLock()
-	configMu.RLock()
+	ctx := r.Context()
+	shiftList, err := store.ListShifts(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	entries, err := store.ListRoster(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	rads, err := store.ListRadiologists(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	ref, err := store.GetReferenceData(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
 
 	// Map roster to shift IDs
 	rosterMap := make(map[int64][]*models.RosterEntry)
-	for _, entry := range roster {
+	for _, entry := range entries {
 		rosterMap[entry.ShiftID] = append(rosterMap[entry.ShiftID], entry)
 	}
 
+	// Flag rostered radiologists the engine will pass over
+	radMap := make(map[string]*models.Radiologist)
+	for _, rad := range rads {
+		radMap[rad.ID] = rad
+	}
+	ineligible := make(map[int64]string)
+	for _, sh := range shiftList {
+		for _, entry := range rosterMap[sh.ID] {
+			rad, ok := radMap[entry.RadiologistID]
+			if !ok {
+				ineligible[entry.ID] = "radiologist not found"
+			} else if reason := assignment.IneligibleReason(sh, rad); reason != "" {
+				ineligible[entry.ID] = reason
+			}
+		}
+	}
+
 	data := ShiftsData{
-		Shifts:       shifts,
+		Shifts:       shiftList,
 		Roster:       rosterMap,
-		Radiologists: radiologists,
-		Sites:        refData.Sites,
-		Modalities:   refData.Modalities,
-		Credentials:  refData.Credentials,
+		Ineligible:   ineligible,
+		Radiologists: rads,
+		Sites:        ref.Sites,
+		Modalities:   ref.Modalities,
+		BodyParts:    ref.BodyParts,
+		Credentials:  ref.Credentials,
+		Urgencies:    models.Urgencies,
+		Weekdays:     models.Weekdays,
 	}
 
-	configMu.RUnlock()
-	rosterMu.RUnlock()
-	shiftsMu.RUnlock()
-
 	render(w, "shifts", data, "ui/templates/shifts.html")
 }
 
@@ -570,25 +512,29 @@ func handleAPIShifts(w http.ResponseWriter, r *http.Request) {
 
 		name := r.FormValue("name")
 		workType := r.FormValue("work_type")
-		priorityStr := r.FormValue("priority")
-		priority, _ := strconv.Atoi(priorityStr)
-
-		// Capture multi-value fields
-		sites := r.Form["sites"]
-		creds := r.Form["credentials"]
+		priority := 0
+		if v := r.FormValue("priority"); v != "" {
+			var err error
+			if priority, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid priority %q", v), http.StatusBadRequest)
+				return
+			}
+		}
 
-		shiftsMu.Lock()
 		newShift := &models.Shift{
-			ID:                  int64(len(shifts) + 1),
-			Name:                name,
-			WorkType:            workType,
-			Sites:               sites,
-			PriorityLevel:       priority,
-			RequiredCredentials: creds,
-			CreatedAt:           time.Now(),
+			Name:          name,
+			WorkType:      workType,
+			PriorityLevel: priority,
+		}
+		readShiftCoverage(r, newShift)
+		if err := readShiftHours(r, newShift); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.CreateShift(r.Context(), newShift); err != nil {
+			storeError(w, err)
+			return
 		}
-		shifts = append(shifts, newShift)
-		shiftsMu.Unlock()
 
 		http.Redirect(w, r, "/shifts", http.StatusSeeOther)
 		return
@@ -608,20 +554,54 @@ func handleEditShift(w http.ResponseWriter, r *http.Request) {
 
 		id, _ := strconv.ParseInt(idStr, 10, 64)
 
-		shiftsMu.Lock()
-		for _, s := range shifts {
-			if s.ID == id {
-				s.Name = name
-				break
+		shift, err := store.GetShift(r.Context(), id)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		shift.Name = name
+		// Unticked checkboxes are not sent at all, so the edit form flags
+		// that it carries the coverage; older clients leave it alone
+		if r.FormValue("coverage") != "" {
+			readShiftCoverage(r, shift)
+		}
+		// Older clients only send the name
+		if _, ok := r.Form["start_time"]; ok {
+			if err := readShiftHours(r, shift); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
 		}
-		shiftsMu.Unlock()
+		if err := store.UpdateShift(r.Context(), shift); err != nil {
+			storeError(w, err)
+			return
+		}
 
 		http.Redirect(w, r, "/shifts", http.StatusSeeOther)
 		return
 	}
 }
 
+// readShiftCoverage copies the work a shift takes and the credentials it
+// requires from a parsed shift form. A field with nothing ticked covers
+// anything.
+func readShiftCoverage(r *http.Request, sh *models.Shift) {
+	sh.Modalities = r.Form["modalities"]
+	sh.BodyParts = r.Form["body_parts"]
+	sh.Urgencies = r.Form["urgencies"]
+	sh.Sites = r.Form["sites"]
+	sh.RequiredCredentials = r.Form["credentials"]
+}
+
+// readShiftHours copies the operating hours from a parsed shift form.
+func readShiftHours(r *http.Request, sh *models.Shift) error {
+	sh.StartTime = r.FormValue("start_time")
+	sh.EndTime = r.FormValue("end_time")
+	sh.Days = r.Form["days"]
+	sh.TimeZone = strings.TrimSpace(r.FormValue("time_zone"))
+	return sh.Validate()
+}
+
 func handleDeleteShift(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		if err := r.ParseForm(); err != nil {
@@ -632,15 +612,10 @@ func handleDeleteShift(w http.ResponseWriter, r *http.Request) {
 		idStr := r.FormValue("id")
 		id, _ := strconv.ParseInt(idStr, 10, 64)
 
-		shiftsMu.Lock()
-		var newShifts []*models.Shift
-		for _, s := range shifts {
-			if s.ID != id {
-				newShifts = append(newShifts, s)
-			}
+		if err := store.DeleteShift(r.Context(), id); err != nil {
+			storeError(w, err)
+			return
 		}
-		shifts = newShifts
-		shiftsMu.Unlock()
 
 		http.Redirect(w, r, "/shifts", http.StatusSeeOther)
 		return
@@ -659,34 +634,301 @@ func handleAssignRadiologist(w http.ResponseWriter, r *http.Request) {
 
 		shiftID, _ := strconv.ParseInt(shiftIDStr, 10, 64)
 
-		rosterMu.Lock()
 		newEntry := &models.RosterEntry{
-			ID:            int64(len(roster) + 1),
 			ShiftID:       shiftID,
 			RadiologistID: radID,
 			StartDate:     time.Now(),
 			Status:        "active",
 		}
-		roster = append(roster, newEntry)
-		rosterMu.Unlock()
+		if err := store.CreateRosterEntry(r.Context(), newEntry); err != nil {
+			storeError(w, err)
+			return
+		}
 
 		http.Redirect(w, r, "/shifts", http.StatusSeeOther)
 		return
 	}
 }
 
+// Radiologist Handlers
+
+func handleRadiologists(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rads, err := store.ListRadiologists(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	ref, err := store.GetReferenceData(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	ids := make([]string, len(rads))
+	for i, rad := range rads {
+		ids[i] = rad.ID
+	}
+	workloads, err := store.GetRadiologistWorkloads(ctx, ids)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	availability := make(map[string]string, len(rads))
+	for _, rad := range rads {
+		availability[rad.ID] = rad.CurrentAvailability(workloads[rad.ID])
+	}
+	data := RadiologistsData{
+		Radiologists:   rads,
+		Availability:   availability,
+		Workloads:      workloads,
+		Credentials:    ref.Credentials,
+		Statuses:       models.RadiologistStatuses,
+		Availabilities: models.Availabilities,
+	}
+
+	render(w, "radiologists", data, "ui/templates/radiologists.html")
+}
+
+// handleAPIRadiologists lists the radiologists as JSON, or returns one given
+// ?id=, and creates a radiologist from a JSON body or the add form.
+func handleAPIRadiologists(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if id := r.URL.Query().Get("id"); id != "" {
+			rad, err := store.GetRadiologist(r.Context(), id)
+			if err != nil {
+				storeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, rad)
+			return
+		}
+		list, err := store.ListRadiologists(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	case "POST":
+		rad, err := readRadiologist(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := store.GetRadiologist(r.Context(), rad.ID); err == nil {
+			http.Error(w, fmt.Sprintf("radiologist %s already exists", rad.ID), http.StatusConflict)
+			return
+		} else if !errors.Is(err, db.ErrNotFound) {
+			storeError(w, err)
+			return
+		}
+		if rad.Availability != "" {
+			rad.AvailabilityChangedAt = time.Now()
+		}
+		if !saveRadiologist(w, r, rad) {
+			return
+		}
+		respondRadiologist(w, r, http.StatusCreated, rad)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleEditRadiologist replaces an existing radiologist's details. Their
+// availability is kept; it changes through handleRadiologistAvailability.
+func handleEditRadiologist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rad, err := readRadiologist(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := store.GetRadiologist(r.Context(), rad.ID)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	rad.Availability = existing.Availability
+	rad.AvailabilityChangedAt = existing.AvailabilityChangedAt
+	if !saveRadiologist(w, r, rad) {
+		return
+	}
+	respondRadiologist(w, r, http.StatusOK, rad)
+}
+
+// handleDeactivateRadiologist marks a radiologist inactive. They stay on the
+// roster, but the engine passes over them until they are reactivated.
+func handleDeactivateRadiologist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rad, err := store.GetRadiologist(r.Context(), r.FormValue("id"))
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	rad.Status = models.RadiologistStatusInactive
+	if err := store.SaveRadiologist(r.Context(), rad); err != nil {
+		storeError(w, err)
+		return
+	}
+	respondRadiologist(w, r, http.StatusOK, rad)
+}
+
+// handleRadiologistAvailability moves a radiologist to a new availability,
+// given as id and availability form values or a JSON body with those fields.
+// Transitions the radiologist cannot make are rejected with 409.
+func handleRadiologistAvailability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID           string `json:"id"`
+		Availability string `json:"availability"`
+	}
+	if isJSON(r) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.ID = r.FormValue("id")
+		req.Availability = r.FormValue("availability")
+	}
+
+	// at_capacity is derived from open workload, so it is not settable either
+	known := false
+	for _, a := range models.Availabilities {
+		known = known || a == req.Availability
+	}
+	if !known {
+		http.Error(w, fmt.Sprintf("unknown availability %q", req.Availability), http.StatusBadRequest)
+		return
+	}
+
+	rad, err := store.GetRadiologist(r.Context(), req.ID)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if err := rad.SetAvailability(req.Availability, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err := store.SaveRadiologist(r.Context(), rad); err != nil {
+		storeError(w, err)
+		return
+	}
+	respondRadiologist(w, r, http.StatusOK, rad)
+}
+
+// readRadiologist decodes a radiologist from a JSON body or a form. Specialties
+// are comma separated in the form; a missing status means active.
+func readRadiologist(r *http.Request) (*models.Radiologist, error) {
+	rad := &models.Radiologist{}
+	if isJSON(r) {
+		if err := json.NewDecoder(r.Body).Decode(rad); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		rad.ID = r.FormValue("id")
+		rad.FirstName = r.FormValue("first_name")
+		rad.LastName = r.FormValue("last_name")
+		rad.Credentials = r.Form["credentials"]
+		for _, s := range strings.Split(r.FormValue("specialties"), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				rad.Specialties = append(rad.Specialties, s)
+			}
+		}
+		if v := r.FormValue("max_concurrent_studies"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("max concurrent studies %q is not a number", v)
+			}
+			rad.MaxConcurrentStudies = n
+		}
+		rad.Status = r.FormValue("status")
+		rad.Availability = r.FormValue("availability")
+	}
+	rad.ID = strings.TrimSpace(rad.ID)
+	if rad.Status == "" {
+		rad.Status = models.RadiologistStatusActive
+	}
+	return rad, nil
+}
+
+// saveRadiologist validates rad against the configured credentials and saves
+// it, writing the error response and returning false if either fails.
+func saveRadiologist(w http.ResponseWriter, r *http.Request, rad *models.Radiologist) bool {
+	ref, err := store.GetReferenceData(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return false
+	}
+	if err := rad.Validate(ref.Credentials); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := store.SaveRadiologist(r.Context(), rad); err != nil {
+		storeError(w, err)
+		return false
+	}
+	return true
+}
+
+// respondRadiologist answers JSON clients with the saved radiologist and
+// sends the browser back to the radiologists page.
+func respondRadiologist(w http.ResponseWriter, r *http.Request, status int, rad *models.Radiologist) {
+	if isJSON(r) || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, status, rad)
+		return
+	}
+	http.Redirect(w, r, "/radiologists", http.StatusSeeOther)
+}
+
+func isJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
 // Procedure Handlers
 
 func handleProcedures(w http.ResponseWriter, r *http.Request) {
-	proceduresMu.RLock()
-	configMu.RLock()
+	ctx := r.Context()
+	procs, err := store.ListProcedures(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	unmapped, err := store.ListUnmappedProcedures(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	ref, err := store.GetReferenceData(ctx)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
 	data := ProceduresData{
-		Procedures: procedures,
-		Modalities: refData.Modalities,
-		BodyParts:  refData.BodyParts,
+		Procedures: procs,
+		Unmapped:   unmapped,
+		Modalities: ref.Modalities,
+		BodyParts:  ref.BodyParts,
 	}
-	configMu.RUnlock()
-	proceduresMu.RUnlock()
 
 	render(w, "procedures", data, "ui/templates/procedures.html")
 }
@@ -698,23 +940,16 @@ func handleAPIProcedures(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		code := r.FormValue("code")
-		desc := r.FormValue("description")
-		modality := r.FormValue("modality")
-		bodyPart := r.FormValue("body_part")
-
-		proceduresMu.Lock()
 		newProc := &models.Procedure{
-			ID:          int64(len(procedures) + 1),
-			Code:        code,
-			Description: desc,
-			Modality:    modality,
-			BodyPart:    bodyPart,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			Code:        r.FormValue("code"),
+			Description: r.FormValue("description"),
+			Modality:    r.FormValue("modality"),
+			BodyPart:    r.FormValue("body_part"),
+		}
+		if err := store.SaveProcedure(r.Context(), newProc); err != nil {
+			storeError(w, err)
+			return
 		}
-		procedures = append(procedures, newProc)
-		proceduresMu.Unlock()
 
 		http.Redirect(w, r, "/procedures", http.StatusSeeOther)
 		return
@@ -730,21 +965,23 @@ func handleEditProcedure(w http.ResponseWriter, r *http.Request) {
 		}
 
 		code := r.FormValue("code")
-		desc := r.FormValue("description")
-		modality := r.FormValue("modality")
-		bodyPart := r.FormValue("body_part")
-
-		proceduresMu.Lock()
-		for _, p := range procedures {
-			if p.Code == code {
-				p.Description = desc
-				p.Modality = modality
-				p.BodyPart = bodyPart
-				p.UpdatedAt = time.Now()
-				break
-			}
+
+		existing, err := store.GetProcedureByCode(r.Context(), code)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		if existing == nil {
+			http.Error(w, "Procedure not found", http.StatusNotFound)
+			return
+		}
+		existing.Description = r.FormValue("description")
+		existing.Modality = r.FormValue("modality")
+		existing.BodyPart = r.FormValue("body_part")
+		if err := store.SaveProcedure(r.Context(), existing); err != nil {
+			storeError(w, err)
+			return
 		}
-		proceduresMu.Unlock()
 
 		http.Redirect(w, r, "/procedures", http.StatusSeeOther)
 		return
@@ -758,29 +995,183 @@ func handleDeleteProcedure(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		code := r.FormValue("code")
-
-		proceduresMu.Lock()
-		var newProcs []*models.Procedure
-		for _, p := range procedures {
-			if p.Code != code {
-				newProcs = append(newProcs, p)
-			}
+		if err := store.DeleteProcedure(r.Context(), r.FormValue("code")); err != nil {
+			storeError(w, err)
+			return
 		}
-		procedures = newProcs
-		proceduresMu.Unlock()
 
 		http.Redirect(w, r, "/procedures", http.StatusSeeOther)
 		return
 	}
 }
 
+func handleAPIUnmappedProcedures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	list, err := store.ListUnmappedProcedures(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handlePromoteUnmappedProcedure adds a queued code to the catalog with the
+// modality and body part chosen by the admin, and removes it from the queue.
+func handlePromoteUnmappedProcedure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	key := r.FormValue("key")
+	proc := &models.Procedure{
+		Code:        r.FormValue("code"),
+		Description: r.FormValue("description"),
+		Modality:    r.FormValue("modality"),
+		BodyPart:    r.FormValue("body_part"),
+	}
+	if proc.Code == "" || proc.Modality == "" || proc.BodyPart == "" {
+		http.Error(w, "Code, modality and body part are required", http.StatusBadRequest)
+		return
+	}
+
+	queued, err := store.ListUnmappedProcedures(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	found := false
+	for _, u := range queued {
+		if u.Key() == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "Unmapped procedure not found", http.StatusNotFound)
+		return
+	}
+
+	// An existing catalog entry keeps its description
+	existing, err := store.GetProcedureByCode(r.Context(), proc.Code)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if existing != nil {
+		proc.Code = existing.Code
+		proc.Description = existing.Description
+	}
+	if err := store.SaveProcedure(r.Context(), proc); err != nil {
+		storeError(w, err)
+		return
+	}
+	// Only leaves the queue once it is in the catalog; a concurrent dismiss
+	// may already have removed it
+	if err := store.DeleteUnmappedProcedure(r.Context(), key); err != nil && !errors.Is(err, db.ErrNotFound) {
+		storeError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/procedures", http.StatusSeeOther)
+}
+
+func handleDismissUnmappedProcedure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if err := store.DeleteUnmappedProcedure(r.Context(), r.FormValue("key")); err != nil {
+		storeError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/procedures", http.StatusSeeOther)
+}
+
+// Dead Letter Handlers
+
+func handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var data DeadLettersData
+	list, err := deadLetters.List()
+	if err != nil {
+		data.Error = err.Error()
+	}
+	data.DeadLetters = list
+
+	render(w, "deadletters", data, "ui/templates/deadletters.html")
+}
+
+func handleAPIDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	list, err := deadLetters.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []*queue.DeadLetter{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleResendDeadLetter hands a dead letter back to the emitter, which
+// picks it up on its next resend poll.
+func handleResendDeadLetter(w http.ResponseWriter, r *http.Request) {
+	handleDeadLetterAction(w, r, deadLetters.Resend)
+}
+
+func handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	handleDeadLetterAction(w, r, deadLetters.Discard)
+}
+
+func handleDeadLetterAction(w http.ResponseWriter, r *http.Request, action func(id string) error) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if err := action(r.FormValue("id")); err != nil {
+		if errors.Is(err, queue.ErrDeadLetterNotFound) {
+			http.Error(w, "Dead letter not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/deadletters", http.StatusSeeOther)
+}
+
 // Config Handlers
 
 func handleConfig(w http.ResponseWriter, r *http.Request) {
-	configMu.RLock()
-	data := refData
-	configMu.RUnlock()
+	data, err := store.GetReferenceData(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
 
 	render(w, "config", data, "ui/templates/config.html")
 }
@@ -791,11 +1182,11 @@ func handleAPISites(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-		configMu.Lock()
-		refData.Sites = append(refData.Sites, models.Site{Code: code, Name: name})
-		configMu.Unlock()
+		site := models.Site{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.SaveSite(r.Context(), site); err != nil {
+			storeError(w, err)
+			return
+		}
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -804,16 +1195,10 @@ func handleAPISites(w http.ResponseWriter, r *http.Request) {
 
 func handleDeleteSite(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		code := r.FormValue("code")
-		configMu.Lock()
-		newSites := []models.Site{}
-		for _, s := range refData.Sites {
-			if s.Code != code {
-				newSites = append(newSites, s)
-			}
+		if err := store.DeleteSite(r.Context(), r.FormValue("code")); err != nil {
+			storeError(w, err)
+			return
 		}
-		refData.Sites = newSites
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -825,19 +1210,11 @@ func handleEditSite(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
-		originalCode := r.FormValue("original_code")
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-
-		configMu.Lock()
-		for i, s := range refData.Sites {
-			if s.Code == originalCode {
-				refData.Sites[i].Code = code
-				refData.Sites[i].Name = name
-				break
-			}
+		site := models.Site{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.UpdateSite(r.Context(), r.FormValue("original_code"), site); err != nil {
+			storeError(w, err)
+			return
 		}
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -846,11 +1223,11 @@ func handleEditSite(w http.ResponseWriter, r *http.Request) {
 
 func handleAPIModalities(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-		configMu.Lock()
-		refData.Modalities = append(refData.Modalities, models.Modality{Code: code, Name: name})
-		configMu.Unlock()
+		m := models.Modality{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.SaveModality(r.Context(), m); err != nil {
+			storeError(w, err)
+			return
+		}
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -858,16 +1235,10 @@ func handleAPIModalities(w http.ResponseWriter, r *http.Request) {
 
 func handleDeleteModality(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		code := r.FormValue("code")
-		configMu.Lock()
-		newMods := []models.Modality{}
-		for _, m := range refData.Modalities {
-			if m.Code != code {
-				newMods = append(newMods, m)
-			}
+		if err := store.DeleteModality(r.Context(), r.FormValue("code")); err != nil {
+			storeError(w, err)
+			return
 		}
-		refData.Modalities = newMods
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -879,19 +1250,11 @@ func handleEditModality(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
-		originalCode := r.FormValue("original_code")
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-
-		configMu.Lock()
-		for i, m := range refData.Modalities {
-			if m.Code == originalCode {
-				refData.Modalities[i].Code = code
-				refData.Modalities[i].Name = name
-				break
-			}
+		m := models.Modality{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.UpdateModality(r.Context(), r.FormValue("original_code"), m); err != nil {
+			storeError(w, err)
+			return
 		}
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -900,10 +1263,11 @@ func handleEditModality(w http.ResponseWriter, r *http.Request) {
 
 func handleAPIBodyParts(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		name := r.FormValue("name")
-		configMu.Lock()
-		refData.BodyParts = append(refData.BodyParts, models.BodyPart{Name: name})
-		configMu.Unlock()
+		bp := models.BodyPart{Name: r.FormValue("name")}
+		if err := store.SaveBodyPart(r.Context(), bp); err != nil {
+			storeError(w, err)
+			return
+		}
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -911,16 +1275,10 @@ func handleAPIBodyParts(w http.ResponseWriter, r *http.Request) {
 
 func handleDeleteBodyPart(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		name := r.FormValue("name")
-		configMu.Lock()
-		newBPs := []models.BodyPart{}
-		for _, bp := range refData.BodyParts {
-			if bp.Name != name {
-				newBPs = append(newBPs, bp)
-			}
+		if err := store.DeleteBodyPart(r.Context(), r.FormValue("name")); err != nil {
+			storeError(w, err)
+			return
 		}
-		refData.BodyParts = newBPs
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -933,16 +1291,24 @@ func handleEditBodyPart(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		originalName := r.FormValue("original_name")
-		name := r.FormValue("name")
 
-		configMu.Lock()
-		for i, bp := range refData.BodyParts {
-			if bp.Name == originalName {
-				refData.BodyParts[i].Name = name
+		// The form only renames; keep the synonyms used for normalization
+		ref, err := store.GetReferenceData(r.Context())
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		bp := models.BodyPart{Name: r.FormValue("name")}
+		for _, existing := range ref.BodyParts {
+			if existing.Name == originalName {
+				bp.SearchString = existing.SearchString
 				break
 			}
 		}
-		configMu.Unlock()
+		if err := store.UpdateBodyPart(r.Context(), originalName, bp); err != nil {
+			storeError(w, err)
+			return
+		}
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -951,11 +1317,11 @@ func handleEditBodyPart(w http.ResponseWriter, r *http.Request) {
 
 func handleAPICredentials(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-		configMu.Lock()
-		refData.Credentials = append(refData.Credentials, models.Credential{Code: code, Name: name})
-		configMu.Unlock()
+		c := models.Credential{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.SaveCredential(r.Context(), c); err != nil {
+			storeError(w, err)
+			return
+		}
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -967,19 +1333,11 @@ func handleEditCredential(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
-		originalCode := r.FormValue("original_code")
-		code := r.FormValue("code")
-		name := r.FormValue("name")
-
-		configMu.Lock()
-		for i, c := range refData.Credentials {
-			if c.Code == originalCode {
-				refData.Credentials[i].Code = code
-				refData.Credentials[i].Name = name
-				break
-			}
+		c := models.Credential{Code: r.FormValue("code"), Name: r.FormValue("name")}
+		if err := store.UpdateCredential(r.Context(), r.FormValue("original_code"), c); err != nil {
+			storeError(w, err)
+			return
 		}
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -988,16 +1346,10 @@ func handleEditCredential(w http.ResponseWriter, r *http.Request) {
 
 func handleDeleteCredential(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		code := r.FormValue("code")
-		configMu.Lock()
-		newCreds := []models.Credential{}
-		for _, c := range refData.Credentials {
-			if c.Code != code {
-				newCreds = append(newCreds, c)
-			}
+		if err := store.DeleteCredential(r.Context(), r.FormValue("code")); err != nil {
+			storeError(w, err)
+			return
 		}
-		refData.Credentials = newCreds
-		configMu.Unlock()
 		http.Redirect(w, r, "/config", http.StatusSeeOther)
 		return
 	}
@@ -1013,21 +1365,34 @@ func handleCalendar(w http.ResponseWriter, r *http.Request) {
 	var days []CalendarDay
 	var unfilled []CalendarShift
 
-	shiftsMu.RLock()
-	rosterMu.RLock()
+	shiftList, err := store.ListShifts(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	entries, err := store.ListRoster(r.Context())
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	rosterMap := make(map[int64][]*models.RosterEntry)
+	for _, entry := range entries {
+		rosterMap[entry.ShiftID] = append(rosterMap[entry.ShiftID], entry)
+	}
 
 	// Determine date range based on view
 	// Simply taking current day for 'day', current week for 'week', current month for 'month'
 	var start, end time.Time
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	switch view {
 	case "day":
-		start = now
-		end = now
+		start = today
+		end = today
 	case "week":
 		// Find start of week (Sunday)
 		weekday := int(now.Weekday())
-		start = now.AddDate(0, 0, -weekday)
+		start = today.AddDate(0, 0, -weekday)
 		end = start.AddDate(0, 0, 6)
 	case "month":
 		// Find start of month
@@ -1035,25 +1400,21 @@ func handleCalendar(w http.ResponseWriter, r *http.Request) {
 		end = start.AddDate(0, 1, -1)
 	}
 
-	// Generate grid
+	// Generate grid: one block for each time a shift starts on the day
 	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
 		day := CalendarDay{Date: d}
 
-		for _, s := range shifts {
-			// Check if filled
-			filled := false
-			radName := ""
-			for _, entry := range roster {
-				if entry.ShiftID == s.ID {
-					// Check if date falls in roster entry range
-					// Simple check: StartDate only for now as defined in roster logic
-					// Assuming daily roster entries or handling logic
-					// Let's match Year/Month/Day
-					if entry.StartDate.Year() == d.Year() && entry.StartDate.Month() == d.Month() && entry.StartDate.Day() == d.Day() {
-						filled = true
-						radName = entry.RadiologistID // Should map to name via lookups
-						break
-					}
+		for _, s := range shiftList {
+			blockStart, blockEnd, ok := s.Window(d)
+			if !ok {
+				continue
+			}
+
+			// Filled if someone is rostered when the shift starts
+			var rostered []string
+			for _, entry := range rosterMap[s.ID] {
+				if entry.EffectiveAt(blockStart) {
+					rostered = append(rostered, entry.RadiologistID) // Should map to name via lookups
 				}
 			}
 
@@ -1061,24 +1422,34 @@ func handleCalendar(w http.ResponseWriter, r *http.Request) {
 				ShiftName:   s.Name,
 				ShiftType:   s.WorkType,
 				Date:        d,
-				Filled:      filled,
-				Radiologist: radName,
+				Start:       blockStart,
+				End:         blockEnd,
+				Overnight:   s.Overnight(),
+				Filled:      len(rostered) > 0,
+				Radiologist: strings.Join(rostered, ", "),
 			}
 			day.Shifts = append(day.Shifts, cs)
 
-			if !filled {
+			if !cs.Filled {
 				unfilled = append(unfilled, cs)
 			}
 		}
+		sort.SliceStable(day.Shifts, func(i, j int) bool {
+			return day.Shifts[i].Start.Before(day.Shifts[j].Start)
+		})
 		days = append(days, day)
 	}
 
-	rosterMu.RUnlock()
-	shiftsMu.RUnlock()
+	// Month view columns run Monday to Sunday
+	var leading int
+	if view == "month" {
+		leading = (int(start.Weekday()) + 6) % 7
+	}
 
 	data := CalendarData{
 		ViewName:       strings.Title(view),
 		View:           view,
+		LeadingBlanks:  leading,
 		Days:           days,
 		UnfilledShifts: unfilled,
 	}
@@ -1086,6 +1457,39 @@ func handleCalendar(w http.ResponseWriter, r *http.Request) {
 	render(w, "calendar", data, "ui/templates/calendar.html")
 }
 
+// handleAssignmentStatus moves a study through its lifecycle, e.g. when a
+// radiologist opens or signs off a study outside of the RIS feed.
+func handleAssignmentStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	status := r.FormValue("status")
+	if !models.ValidStatus(status) {
+		http.Error(w, "Unknown status "+status, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := engine.UpdateStatus(r.Context(), r.FormValue("study_id"), status); err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrNoAssignment):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrInvalidTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
 func handleSimulateAssignment(w http.ResponseWriter, r *http.Request) {
 	log.Println("handleSimulateAssignment: start")
 	defer log.Println("handleSimulateAssignment: end")
@@ -1126,7 +1530,7 @@ func handleSimulateAssignment(w http.ResponseWriter, r *http.Request) {
 		study := &models.Study{
 			ID:                   studyID,
 			Modality:             modality,
-			BodyPart:             "General",
+			BodyPart:             r.FormValue("body_part"), // Normalized by the engine
 			Site:                 site,
 			ProcedureCode:        procedureCode,
 			OrderingPhysician:    orderingPhysician,
@@ -1140,14 +1544,54 @@ func handleSimulateAssignment(w http.ResponseWriter, r *http.Request) {
 			Transcriptionist:     transcriptionist,
 		}
 
+		// An explicit ORC-1 code applies the order like the engine service
+		// does, so cancels and changes can be simulated too
+		if orderControl := strings.ToUpper(r.FormValue("order_control")); orderControl != "" {
+			study.OrderControl = orderControl
+			assignment, err := engine.Process(context.Background(), study)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Assignment Failed: %v", err), http.StatusServiceUnavailable)
+				return
+			}
+			switch {
+			case study.IsCancellation():
+				fmt.Fprintf(w, "Cancelled %s", studyID)
+				return
+			case assignment == nil:
+				fmt.Fprintf(w, "No change for %s", studyID)
+				return
+			}
+			fmt.Fprintf(w, "Assigned to %s", assignment.RadiologistID)
+			return
+		}
+
+		// ?explain=1 answers with the assignment and the engine's trace as JSON
+		if explain, _ := strconv.ParseBool(r.FormValue("explain")); explain {
+			assignment, trace, err := engine.AssignExplained(context.Background(), study)
+			resp := SimulateExplanation{Trace: trace}
+			if assignment != nil {
+				// The trace is reported once, alongside
+				a := *assignment
+				a.Trace = nil
+				resp.Assignment = &a
+			}
+			status := http.StatusOK
+			if err != nil {
+				resp.Error = err.Error()
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, resp)
+			return
+		}
+
 		assignment, err := engine.Assign(context.Background(), study)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Assignment Failed: %v", err), http.StatusServiceUnavailable)
 			return
 		}
 
-		if assignment.RadiologistID == "WORKLIST" {
-			fmt.Fprintf(w, "Assigned to Worklist: %s", assignment.Strategy)
+		if assignment.RadiologistID == models.WorklistAssignee {
+			fmt.Fprintf(w, "Assigned to Worklist: %s", assignment.Worklist)
 		} else {
 			fmt.Fprintf(w, "Assigned to %s", assignment.RadiologistID)
 		}
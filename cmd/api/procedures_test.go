@@ -1,17 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"radiology-assignment/internal/db"
 	"radiology-assignment/internal/models"
 )
 
 func TestHandleAPIProcedures(t *testing.T) {
-	// Setup initial state
-	procedures = []*models.Procedure{}
+	s := useTestStore(t)
 
 	// Test Create
 	form := url.Values{}
@@ -30,7 +32,7 @@ func TestHandleAPIProcedures(t *testing.T) {
 		t.Errorf("Expected redirect 303, got %d", w.Code)
 	}
 
-	proceduresMu.RLock()
+	procedures, _ := s.ListProcedures(context.Background())
 	if len(procedures) != 1 {
 		t.Errorf("Expected 1 procedure, got %d", len(procedures))
 	} else {
@@ -39,14 +41,11 @@ func TestHandleAPIProcedures(t *testing.T) {
 			t.Errorf("Procedure data mismatch: %+v", p)
 		}
 	}
-	proceduresMu.RUnlock()
 }
 
 func TestHandleEditProcedure(t *testing.T) {
-	// Setup
-	procedures = []*models.Procedure{
-		{Code: "TEST2", Description: "Old Desc", Modality: "MR", BodyPart: "KNEE"},
-	}
+	s := useTestStore(t)
+	s.SaveProcedure(context.Background(), &models.Procedure{Code: "TEST2", Description: "Old Desc", Modality: "MR", BodyPart: "KNEE"})
 
 	form := url.Values{}
 	form.Add("code", "TEST2")
@@ -60,19 +59,15 @@ func TestHandleEditProcedure(t *testing.T) {
 
 	handleEditProcedure(w, req)
 
-	proceduresMu.RLock()
-	p := procedures[0]
-	if p.Description != "New Desc" || p.BodyPart != "LEG" {
+	p, _ := s.GetProcedureByCode(context.Background(), "TEST2")
+	if p == nil || p.Description != "New Desc" || p.BodyPart != "LEG" {
 		t.Errorf("Procedure not updated: %+v", p)
 	}
-	proceduresMu.RUnlock()
 }
 
 func TestHandleDeleteProcedure(t *testing.T) {
-	// Setup
-	procedures = []*models.Procedure{
-		{Code: "TEST3"},
-	}
+	s := useTestStore(t)
+	s.SaveProcedure(context.Background(), &models.Procedure{Code: "TEST3"})
 
 	form := url.Values{}
 	form.Add("code", "TEST3")
@@ -83,18 +78,14 @@ func TestHandleDeleteProcedure(t *testing.T) {
 
 	handleDeleteProcedure(w, req)
 
-	proceduresMu.RLock()
-	if len(procedures) != 0 {
+	if procedures, _ := s.ListProcedures(context.Background()); len(procedures) != 0 {
 		t.Errorf("Expected 0 procedures, got %d", len(procedures))
 	}
-	proceduresMu.RUnlock()
 }
 
 func TestRenderProcedures(t *testing.T) {
-	// Setup
-	procedures = []*models.Procedure{
-		{Code: "P1", Description: "D1", Modality: "M1", BodyPart: "B1"},
-	}
+	s := useTestStore(t)
+	s.SaveProcedure(context.Background(), &models.Procedure{Code: "P1", Description: "D1", Modality: "M1", BodyPart: "B1"})
 
 	req := httptest.NewRequest("GET", "/procedures", nil)
 	w := httptest.NewRecorder()
@@ -117,3 +108,75 @@ func TestRenderProcedures(t *testing.T) {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestHandlePromoteUnmappedProcedure(t *testing.T) {
+	s := useTestStore(t)
+	s.RecordUnmappedProcedure(context.Background(), &models.Study{ID: "S1", ProcedureCode: "XYZ1", ProcedureDescription: "Odd Study", Modality: "CT"})
+
+	form := url.Values{}
+	form.Add("key", models.UnmappedProcedureKey("XYZ1", "Odd Study"))
+	form.Add("code", "XYZ1")
+	form.Add("description", "Odd Study")
+	form.Add("modality", "CT")
+	form.Add("body_part", "Chest")
+
+	req := httptest.NewRequest("POST", "/api/procedures/unmapped/promote", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handlePromoteUnmappedProcedure(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect 303, got %d", w.Code)
+	}
+	if list, _ := s.ListUnmappedProcedures(context.Background()); len(list) != 0 {
+		t.Error("Expected promoted procedure to leave the queue")
+	}
+
+	p, _ := s.GetProcedureByCode(context.Background(), "XYZ1")
+	if p == nil || p.BodyPart != "Chest" || p.Modality != "CT" {
+		t.Errorf("Expected XYZ1 in the catalog as CT Chest, got %+v", p)
+	}
+
+	// A second promote of the same entry is rejected
+	req = httptest.NewRequest("POST", "/api/procedures/unmapped/promote", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handlePromoteUnmappedProcedure(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+// failingProcedureStore fails every catalog save
+type failingProcedureStore struct {
+	db.Store
+}
+
+func (failingProcedureStore) SaveProcedure(ctx context.Context, p *models.Procedure) error {
+	return errors.New("disk full")
+}
+
+func TestHandlePromoteUnmappedProcedure_FailedSaveKeepsQueueEntry(t *testing.T) {
+	s := useTestStore(t)
+	store = failingProcedureStore{s}
+	s.RecordUnmappedProcedure(context.Background(), &models.Study{ID: "S1", ProcedureCode: "XYZ1", ProcedureDescription: "Odd Study", Modality: "CT"})
+
+	form := url.Values{}
+	form.Add("key", models.UnmappedProcedureKey("XYZ1", "Odd Study"))
+	form.Add("code", "XYZ1")
+	form.Add("modality", "CT")
+	form.Add("body_part", "Chest")
+
+	req := httptest.NewRequest("POST", "/api/procedures/unmapped/promote", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handlePromoteUnmappedProcedure(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+	if list, _ := s.ListUnmappedProcedures(context.Background()); len(list) != 1 {
+		t.Errorf("Expected the entry to stay queued, got %d entries", len(list))
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"radiology-assignment/internal/models"
+	"strings"
+	"testing"
+)
+
+func setupRadiologists(t *testing.T) {
+	s := useTestStore(t)
+	ctx := context.Background()
+	s.SaveCredential(ctx, models.Credential{Code: "CT", Name: "CT Certified"})
+	s.SaveCredential(ctx, models.Credential{Code: "Neuro", Name: "Neuroradiology"})
+	s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad1", FirstName: "John", Credentials: []string{"CT"}, Status: "active"})
+}
+
+func postRadiologistJSON(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/radiologists", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestHandleAPIRadiologists_Create(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"valid", `{"id": "rad2", "credentials": ["ct", "Neuro"], "max_concurrent_studies": 3}`, http.StatusCreated},
+		{"duplicate id", `{"id": "rad1"}`, http.StatusConflict},
+		{"missing id", `{"first_name": "Nobody"}`, http.StatusBadRequest},
+		{"unknown credential", `{"id": "rad2", "credentials": ["PET"]}`, http.StatusBadRequest},
+		{"unknown status", `{"id": "rad2", "status": "retired"}`, http.StatusBadRequest},
+		{"negative capacity", `{"id": "rad2", "max_concurrent_studies": -1}`, http.StatusBadRequest},
+		{"malformed", `{"id":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRadiologists(t)
+			w := postRadiologistJSON(handleAPIRadiologists, tt.body)
+			if w.Code != tt.code {
+				t.Fatalf("Expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	setupRadiologists(t)
+	postRadiologistJSON(handleAPIRadiologists, tests[0].body)
+	rad, err := store.GetRadiologist(context.Background(), "rad2")
+	if err != nil {
+		t.Fatalf("Expected rad2 to be saved, got %v", err)
+	}
+	if rad.Status != "active" || len(rad.Credentials) != 2 || rad.Credentials[0] != "CT" {
+		t.Errorf("Expected an active radiologist with reference credential codes, got %+v", rad)
+	}
+}
+
+func TestHandleAPIRadiologists_List(t *testing.T) {
+	setupRadiologists(t)
+
+	req := httptest.NewRequest("GET", "/api/radiologists", nil)
+	w := httptest.NewRecorder()
+	handleAPIRadiologists(w, req)
+
+	var list []*models.Radiologist
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Expected a JSON list, got %v: %s", err, w.Body.String())
+	}
+	if len(list) != 1 || list[0].ID != "rad1" {
+		t.Errorf("Expected [rad1], got %+v", list)
+	}
+
+	req = httptest.NewRequest("GET", "/api/radiologists?id=missing", nil)
+	w = httptest.NewRecorder()
+	handleAPIRadiologists(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown radiologist, got %d", w.Code)
+	}
+}
+
+func TestHandleEditRadiologist(t *testing.T) {
+	setupRadiologists(t)
+
+	form := url.Values{
+		"id":                     {"rad1"},
+		"first_name":             {"Johnny"},
+		"credentials":            {"CT", "Neuro"},
+		"specialties":            {"Neuro, Paediatrics"},
+		"max_concurrent_studies": {"4"},
+		"status":                 {"active"},
+	}
+	req := httptest.NewRequest("POST", "/api/radiologists/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handleEditRadiologist(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+	}
+	rad, _ := store.GetRadiologist(context.Background(), "rad1")
+	if rad.FirstName != "Johnny" || len(rad.Credentials) != 2 || len(rad.Specialties) != 2 || rad.MaxConcurrentStudies != 4 {
+		t.Errorf("Expected the edit to be saved, got %+v", rad)
+	}
+
+	form.Set("id", "missing")
+	req = httptest.NewRequest("POST", "/api/radiologists/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handleEditRadiologist(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown radiologist, got %d", w.Code)
+	}
+}
+
+func TestHandleDeactivateRadiologist(t *testing.T) {
+	setupRadiologists(t)
+
+	req := httptest.NewRequest("POST", "/api/radiologists/deactivate?id=rad1", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	handleDeactivateRadiologist(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	rad, _ := store.GetRadiologist(context.Background(), "rad1")
+	if rad.Status != models.RadiologistStatusInactive {
+		t.Errorf("Expected rad1 to be inactive, got %q", rad.Status)
+	}
+}
+
+func TestHandleRadiologists(t *testing.T) {
+	setupRadiologists(t)
+
+	req := httptest.NewRequest("GET", "/radiologists", nil)
+	w := httptest.NewRecorder()
+	handleRadiologists(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Radiologist Management", "rad1", "Neuroradiology"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q", want)
+		}
+	}
+}
+
+func TestHandleRadiologistAvailability(t *testing.T) {
+	setupRadiologists(t)
+
+	tests := []struct {
+		name         string
+		availability string
+		code         int
+		want         string
+	}{
+		{"go offline", "offline", http.StatusOK, "offline"},
+		{"break while offline", "break", http.StatusConflict, "offline"},
+		{"log back in", "on_shift", http.StatusOK, "on_shift"},
+		{"take a break", "break", http.StatusOK, "break"},
+		{"capacity is derived", "at_capacity", http.StatusBadRequest, "break"},
+		{"unknown", "lunch", http.StatusBadRequest, "break"},
+	}
+
+	for _, tt := range tests {
+		w := postRadiologistJSON(handleRadiologistAvailability, `{"id": "rad1", "availability": "`+tt.availability+`"}`)
+		if w.Code != tt.code {
+			t.Errorf("%s: Expected %d, got %d: %s", tt.name, tt.code, w.Code, w.Body.String())
+		}
+		rad, _ := store.GetRadiologist(context.Background(), "rad1")
+		if rad.Availability != tt.want {
+			t.Errorf("%s: Expected %s, got %s", tt.name, tt.want, rad.Availability)
+		}
+		if rad.AvailabilityChangedAt.IsZero() {
+			t.Errorf("%s: Expected the change time to be recorded", tt.name)
+		}
+	}
+
+	// Editing other details leaves the availability alone
+	form := url.Values{"id": {"rad1"}, "first_name": {"John"}, "status": {"active"}}
+	req := httptest.NewRequest("POST", "/api/radiologists/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handleEditRadiologist(httptest.NewRecorder(), req)
+	if rad, _ := store.GetRadiologist(context.Background(), "rad1"); rad.Availability != "break" {
+		t.Errorf("Expected the edit to keep the availability, got %q", rad.Availability)
+	}
+}
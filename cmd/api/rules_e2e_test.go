@@ -2,10 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"radiology-assignment/internal/assignment"
-	"radiology-assignment/internal/models"
 	"strings"
 	"testing"
 	"time"
@@ -14,12 +13,7 @@ import (
 )
 
 func TestRulesE2E(t *testing.T) {
-	// Initialize engine for testing
-	rulesMu.Lock()
-	rules = []*models.AssignmentRule{}
-	rulesMu.Unlock()
-
-	engine = assignment.NewEngine(&InMemoryStore{}, &InMemoryRoster{}, &InMemoryRules{})
+	useTestStore(t)
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -65,9 +59,9 @@ func TestRulesE2E(t *testing.T) {
 
 		err := chromedp.Run(ctx,
 			chromedp.Navigate(ts.URL+"/rules"),
-			chromedp.WaitVisible(`button[onclick="ui('#add-rule-modal')"]`, chromedp.ByQuery),
+			chromedp.WaitVisible(`button[onclick="openAddModal()"]`, chromedp.ByQuery),
 			// Open Modal
-			chromedp.Click(`button[onclick="ui('#add-rule-modal')"]`, chromedp.ByQuery),
+			chromedp.Click(`button[onclick="openAddModal()"]`, chromedp.ByQuery),
 			chromedp.Sleep(500*time.Millisecond),
 
 			// Fill Name
@@ -79,17 +73,20 @@ func TestRulesE2E(t *testing.T) {
 			// Set Target
 			chromedp.SendKeys(`#add-rule-modal input[name="target"]`, "UrgentQueue", chromedp.ByQuery),
 
-			// Add Criteria: Urgency
-			chromedp.SetValue(`#add-criteria-select-add`, "urgency", chromedp.ByQuery),
-			chromedp.Click(`#add-rule-modal button[onclick="addCriteria('add')"]`, chromedp.ByQuery),
-			chromedp.WaitVisible(`#criteria-container-add input[name="filter_urgency"]`, chromedp.ByQuery),
-			chromedp.SendKeys(`#criteria-container-add input[name="filter_urgency"]`, "STAT", chromedp.ByQuery),
-
-			// Add Criteria: Site
-			chromedp.SetValue(`#add-criteria-select-add`, "site", chromedp.ByQuery),
-			chromedp.Click(`#add-rule-modal button[onclick="addCriteria('add')"]`, chromedp.ByQuery),
-			chromedp.WaitVisible(`#criteria-container-add input[name="filter_site"]`, chromedp.ByQuery),
-			chromedp.SendKeys(`#criteria-container-add input[name="filter_site"]`, "SiteA", chromedp.ByQuery),
+			// Condition: urgency is STAT
+			chromedp.Click(`#tree-add .add-condition`, chromedp.ByQuery),
+			chromedp.WaitVisible(`#tree-add .condition-row .cond-value`, chromedp.ByQuery),
+			chromedp.SetValue(`#tree-add .condition-row .cond-field`, "urgency", chromedp.ByQuery),
+			chromedp.SendKeys(`#tree-add .condition-row .cond-value`, "STAT", chromedp.ByQuery),
+
+			// Group: any of site in SiteA, SiteB
+			chromedp.Click(`#tree-add .add-group`, chromedp.ByQuery),
+			chromedp.WaitVisible(`#tree-add .condition-group .condition-group .add-condition`, chromedp.ByQuery),
+			chromedp.Click(`#tree-add .condition-group .condition-group .add-condition`, chromedp.ByQuery),
+			chromedp.WaitVisible(`#tree-add .condition-group .condition-group .cond-value`, chromedp.ByQuery),
+			chromedp.SetValue(`#tree-add .condition-group .condition-group .cond-field`, "site", chromedp.ByQuery),
+			chromedp.SetValue(`#tree-add .condition-group .condition-group .cond-op`, "in", chromedp.ByQuery),
+			chromedp.SendKeys(`#tree-add .condition-group .condition-group .cond-value`, "SiteA, SiteB", chromedp.ByQuery),
 
 			// Save
 			chromedp.Click(`#add-rule-modal button[type="submit"]`, chromedp.ByQuery),
@@ -102,6 +99,16 @@ func TestRulesE2E(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to add rule: %v", err)
 		}
+
+		rules, _ := store.ListRules(context.Background())
+		if len(rules) != 1 {
+			t.Fatalf("Expected 1 rule, got %d", len(rules))
+		}
+		got, _ := json.Marshal(rules[0].ConditionFilters)
+		want := `{"any":[{"site":{"in":["SiteA","SiteB"]}}],"urgency":"STAT"}`
+		if string(got) != want {
+			t.Errorf("Expected conditions %s, got %s", want, got)
+		}
 	})
 
 	/*
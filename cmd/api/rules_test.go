@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandleAPIRules_ValidatesConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+		code int
+	}{
+		{"no conditions", url.Values{}, http.StatusSeeOther},
+		{"plain equality", url.Values{"conditions": {`{"urgency": "STAT"}`}}, http.StatusSeeOther},
+		{"operators", url.Values{"conditions": {`{"modality": {"in": ["CT", "MR"]}, "indication": {"regex": "(?i)stroke"}}`}}, http.StatusSeeOther},
+		{"numeric urgency", url.Values{"conditions": {`{"urgency": 1}`}}, http.StatusBadRequest},
+		{"unknown field", url.Values{"conditions": {`{"colour": "red"}`}}, http.StatusBadRequest},
+		{"unknown operator", url.Values{"conditions": {`{"modality": {"like": "C%"}}`}}, http.StatusBadRequest},
+		{"bad regex", url.Values{"conditions": {`{"indication": {"regex": "("}}`}}, http.StatusBadRequest},
+		{"range on text", url.Values{"conditions": {`{"site": {"gte": 3}}`}}, http.StatusBadRequest},
+		{"groups", url.Values{"conditions": {`{"any": [{"urgency": "STAT", "site": "Remote"}, {"site": "Partner"}], "not": {"modality": "XR"}}`}}, http.StatusSeeOther},
+		{"empty group", url.Values{"conditions": {`{"any": []}`}}, http.StatusBadRequest},
+		{"not without an object", url.Values{"conditions": {`{"not": "XR"}`}}, http.StatusBadRequest},
+		{"bad nested filter", url.Values{"conditions": {`{"all": [{"any": [{"urgency": 1}]}]}`}}, http.StatusBadRequest},
+		{"terminal chain", url.Values{"chain": {"terminal"}, "soft": {"1"}}, http.StatusSeeOther},
+		{"unknown chain", url.Values{"chain": {"sometimes"}}, http.StatusBadRequest},
+		{"not JSON", url.Values{"conditions": {`modality=CT`}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestStore(t)
+			tt.form.Set("name", "Rule")
+			tt.form.Set("action", "ESCALATE")
+			req := httptest.NewRequest("POST", "/api/rules", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handleAPIRules(w, req)
+			if w.Code != tt.code {
+				t.Fatalf("Expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+
+			rules, _ := store.ListRules(context.Background())
+			if saved := len(rules) == 1; saved != (tt.code == http.StatusSeeOther) {
+				t.Errorf("Expected the rule to be saved only when valid, got %d rules", len(rules))
+			}
+		})
+	}
+}
@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"radiology-assignment/internal/models"
 	"strings"
 	"testing"
-	"radiology-assignment/internal/models"
+	"time"
 )
 
 func TestHandleAPIShifts(t *testing.T) {
-	// Setup
-	shifts = []*models.Shift{}
+	s := useTestStore(t)
 
 	form := url.Values{}
 	form.Add("name", "Test Shift")
@@ -32,7 +33,7 @@ func TestHandleAPIShifts(t *testing.T) {
 		t.Errorf("Expected redirect 303, got %d", w.Code)
 	}
 
-	shiftsMu.RLock()
+	shifts, _ := s.ListShifts(context.Background())
 	if len(shifts) != 1 {
 		t.Errorf("Expected 1 shift, got %d", len(shifts))
 	} else {
@@ -47,5 +48,195 @@ func TestHandleAPIShifts(t *testing.T) {
 			t.Errorf("Expected 2 credentials, got %d", len(s.RequiredCredentials))
 		}
 	}
-	shiftsMu.RUnlock()
+}
+
+func TestHandleDeleteShift_IDsNotReused(t *testing.T) {
+	s := useTestStore(t)
+
+	post := func(handler http.HandlerFunc, form url.Values) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	post(handleAPIShifts, url.Values{"name": {"First"}, "work_type": {"CT"}})
+	post(handleAPIShifts, url.Values{"name": {"Second"}, "work_type": {"CT"}})
+	post(handleDeleteShift, url.Values{"id": {"2"}})
+	post(handleAPIShifts, url.Values{"name": {"Third"}, "work_type": {"CT"}})
+
+	shifts, _ := s.ListShifts(context.Background())
+	if len(shifts) != 2 {
+		t.Fatalf("Expected 2 shifts, got %d", len(shifts))
+	}
+	if shifts[0].ID == shifts[1].ID {
+		t.Errorf("Expected distinct IDs, got %d twice", shifts[0].ID)
+	}
+	if shifts[1].Name != "Third" || shifts[1].ID != 3 {
+		t.Errorf("Expected Third to get ID 3, got %+v", shifts[1])
+	}
+}
+
+func TestHandleAPIShifts_OperatingHours(t *testing.T) {
+	s := useTestStore(t)
+
+	post := func(handler http.HandlerFunc, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := post(handleAPIShifts, url.Values{"name": {"Night CT"}, "work_type": {"CT"}, "start_time": {"19:00"}, "end_time": {"07:00"}, "days": {"Fri", "Sat"}, "time_zone": {"UTC"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+	}
+	shifts, _ := s.ListShifts(context.Background())
+	if len(shifts) != 1 || shifts[0].StartTime != "19:00" || shifts[0].EndTime != "07:00" || len(shifts[0].Days) != 2 || !shifts[0].Overnight() {
+		t.Fatalf("Expected an overnight Fri/Sat shift, got %+v", shifts)
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"bad start", url.Values{"name": {"Bad"}, "work_type": {"CT"}, "start_time": {"7am"}}},
+		{"bad day", url.Values{"name": {"Bad"}, "work_type": {"CT"}, "days": {"Funday"}}},
+		{"bad zone", url.Values{"name": {"Bad"}, "work_type": {"CT"}, "time_zone": {"Mars/Olympus"}}},
+		{"negative priority", url.Values{"name": {"Bad"}, "work_type": {"CT"}, "priority": {"-1"}}},
+		{"bad priority", url.Values{"name": {"Bad"}, "work_type": {"CT"}, "priority": {"high"}}},
+	}
+	for _, tt := range tests {
+		if w := post(handleAPIShifts, tt.form); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", tt.name, w.Code)
+		}
+	}
+	if w := post(handleAPIShifts, url.Values{"name": {"Night CT"}, "work_type": {"MRI"}}); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a second Night CT, got %d", w.Code)
+	}
+
+	if shifts, _ := s.ListShifts(context.Background()); len(shifts) != 1 {
+		t.Errorf("Expected rejected shifts not to be saved, got %d shifts", len(shifts))
+	}
+
+	// Editing only the name keeps the hours
+	post(handleEditShift, url.Values{"id": {"1"}, "name": {"Late CT"}})
+	sh, _ := s.GetShift(context.Background(), 1)
+	if sh.Name != "Late CT" || sh.StartTime != "19:00" {
+		t.Errorf("Expected hours to survive a rename, got %+v", sh)
+	}
+
+	post(handleEditShift, url.Values{"id": {"1"}, "name": {"Late CT"}, "start_time": {"20:00"}, "end_time": {"04:00"}})
+	sh, _ = s.GetShift(context.Background(), 1)
+	if sh.StartTime != "20:00" || sh.EndTime != "04:00" || len(sh.Days) != 0 {
+		t.Errorf("Expected edited hours every day, got %+v", sh)
+	}
+}
+
+func TestHandleCalendar_RendersShiftBlocks(t *testing.T) {
+	s := useTestStore(t)
+	ctx := context.Background()
+	s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad1", Status: "active"})
+	s.CreateShift(ctx, &models.Shift{Name: "Day MRI", WorkType: "MRI", StartTime: "07:00", EndTime: "19:00"})
+	s.CreateShift(ctx, &models.Shift{Name: "Night MRI", WorkType: "MRI", StartTime: "19:00", EndTime: "07:00"})
+	s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 1, RadiologistID: "rad1", StartDate: time.Now().AddDate(0, 0, -1)})
+
+	req := httptest.NewRequest("GET", "/calendar?view=day", nil)
+	w := httptest.NewRecorder()
+	handleCalendar(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"07:00 - 19:00", "19:00 - 07:00 (next day)", "rad1", "Night MRI (MRI)"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected calendar to contain %q", want)
+		}
+	}
+	if strings.Index(body, "Day MRI</td>") > strings.Index(body, "Night MRI</td>") {
+		t.Error("Expected blocks in start time order")
+	}
+}
+
+func TestHandleAPIShifts_Coverage(t *testing.T) {
+	s := useTestStore(t)
+
+	form := url.Values{
+		"name":       {"Neuro"},
+		"work_type":  {"CT"},
+		"modalities": {"CT", "MRI"},
+		"body_parts": {"Head", "Spine"},
+		"urgencies":  {"STAT"},
+	}
+	req := httptest.NewRequest("POST", "/api/shifts", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handleAPIShifts(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ctx := context.Background()
+	if got, _ := s.GetShiftsByWorkType(ctx, models.ShiftQuery{Modality: "MRI", BodyPart: "Spine", Urgency: "STAT"}); len(got) != 1 {
+		t.Errorf("Expected the shift to cover a STAT MRI spine, got %d shifts", len(got))
+	}
+	if got, _ := s.GetShiftsByWorkType(ctx, models.ShiftQuery{Modality: "MRI", BodyPart: "Spine", Urgency: "ROUTINE"}); len(got) != 0 {
+		t.Errorf("Expected the shift not to cover routine work, got %d shifts", len(got))
+	}
+
+	// The edit form sends the whole coverage; nothing ticked covers anything
+	form = url.Values{
+		"id":          {"1"},
+		"name":        {"Neuro"},
+		"coverage":    {"1"},
+		"modalities":  {"MRI"},
+		"sites":       {"SiteA"},
+		"credentials": {"Neuro"},
+	}
+	req = httptest.NewRequest("POST", "/api/shifts/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handleEditShift(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected redirect 303, got %d: %s", w.Code, w.Body.String())
+	}
+	if got, _ := s.GetShiftsByWorkType(ctx, models.ShiftQuery{Modality: "MRI", BodyPart: "Knee", Urgency: "ROUTINE", Site: "SiteA"}); len(got) != 1 {
+		t.Errorf("Expected the edited shift to cover any MRI at SiteA, got %d shifts", len(got))
+	}
+	if got, _ := s.GetShiftsByWorkType(ctx, models.ShiftQuery{Modality: "CT", Site: "SiteA"}); len(got) != 0 {
+		t.Errorf("Expected the edited shift to stop covering CT, got %d shifts", len(got))
+	}
+	if sh, _ := s.GetShift(ctx, 1); len(sh.RequiredCredentials) != 1 || sh.RequiredCredentials[0] != "Neuro" {
+		t.Errorf("Expected the edit to set the required credentials, got %v", sh.RequiredCredentials)
+	}
+}
+
+func TestHandleShifts_FlagsIneligibleRoster(t *testing.T) {
+	s := useTestStore(t)
+	ctx := context.Background()
+	s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad_ok", Status: "active", Credentials: []string{"Neuro"}})
+	s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad_gp", Status: "active"})
+	s.CreateShift(ctx, &models.Shift{Name: "Neuro CT", WorkType: "CT", RequiredCredentials: []string{"Neuro"}})
+	s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 1, RadiologistID: "rad_ok"})
+	s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 1, RadiologistID: "rad_gp"})
+
+	req := httptest.NewRequest("GET", "/shifts", nil)
+	w := httptest.NewRecorder()
+	handleShifts(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Not eligible: missing credentials Neuro") {
+		t.Error("Expected a warning for rad_gp")
+	}
+	if strings.Count(body, "Not eligible") != 1 {
+		t.Errorf("Expected only rad_gp to be flagged, got %d warnings", strings.Count(body, "Not eligible"))
+	}
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"radiology-assignment/internal/cache"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"time"
+)
+
+// openStore selects the storage backend from STORE_BACKEND (memory, bolt or
+// postgres), STORE_PATH and DATABASE_URL, and wraps it with the roster and
+// rules caches the engine reads from.
+func openStore(ctx context.Context) (*cache.Store, error) {
+	backend := os.Getenv("STORE_BACKEND")
+	if backend == "" {
+		backend = db.BackendMemory
+	}
+	path := os.Getenv("STORE_PATH")
+	if path == "" {
+		path = "data/assignment.db"
+	}
+	s, err := db.Open(ctx, db.Config{Backend: backend, Path: path, DatabaseURL: os.Getenv("DATABASE_URL")})
+	if err != nil {
+		return nil, err
+	}
+	// The memory store starts empty on every run, so it also gets the demo
+	// shifts, rules and assignments
+	if err := seedStore(ctx, s, backend == db.BackendMemory); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("seed %s store: %w", backend, err)
+	}
+	cs, err := cache.NewStore(ctx, s)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("load caches: %w", err)
+	}
+	return cs, nil
+}
+
+// seedStore copies the demo radiologists, reference data and procedure
+// catalog into a new store, so a fresh database has something to roster
+// against. Stores that already have radiologists are left alone.
+func seedStore(ctx context.Context, s db.Store, withDemoWork bool) error {
+	existing, err := s.ListRadiologists(ctx)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	log.Printf("Seeding empty store with demo data")
+
+	for _, rad := range demoRadiologists() {
+		if err := s.SaveRadiologist(ctx, rad); err != nil {
+			return err
+		}
+	}
+	ref := demoReferenceData()
+	for _, site := range ref.Sites {
+		if err := s.SaveSite(ctx, site); err != nil {
+			return err
+		}
+	}
+	for _, m := range ref.Modalities {
+		if err := s.SaveModality(ctx, m); err != nil {
+			return err
+		}
+	}
+	for _, bp := range ref.BodyParts {
+		if err := s.SaveBodyPart(ctx, bp); err != nil {
+			return err
+		}
+	}
+	for _, c := range ref.Credentials {
+		if err := s.SaveCredential(ctx, c); err != nil {
+			return err
+		}
+	}
+	for _, p := range demoProcedures() {
+		if err := s.SaveProcedure(ctx, p); err != nil {
+			return err
+		}
+	}
+	if !withDemoWork {
+		return nil
+	}
+
+	shifts := []*models.Shift{
+		{Name: "Morning MRI", WorkType: "MRI", Sites: []string{"SiteA"}, PriorityLevel: 1, RequiredCredentials: []string{"MRI"}, StartTime: "07:00", EndTime: "19:00"},
+		{Name: "Night CT", WorkType: "CT", Sites: []string{"SiteB"}, PriorityLevel: 2, RequiredCredentials: []string{"CT"}, StartTime: "19:00", EndTime: "07:00"},
+	}
+	for _, sh := range shifts {
+		if err := s.CreateShift(ctx, sh); err != nil {
+			return err
+		}
+	}
+	if err := s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: shifts[0].ID, RadiologistID: "rad1", StartDate: time.Now()}); err != nil {
+		return err
+	}
+	rules := []*models.AssignmentRule{
+		{Name: "Priority Stroke", PriorityOrder: 1, ActionType: "ESCALATE", Enabled: true},
+		{Name: "MSK Load Balance", PriorityOrder: 2, ActionType: "ASSIGN_TO_SHIFT", Enabled: true},
+	}
+	for _, r := range rules {
+		if err := s.CreateRule(ctx, r); err != nil {
+			return err
+		}
+	}
+	assignments := []*models.Assignment{
+		{StudyID: "ST1001", RadiologistID: "rad1", ShiftID: shifts[0].ID, Strategy: models.StrategyShiftPrimary, AssignedAt: time.Now().Add(-5 * time.Minute), Status: models.StatusAssigned},
+		{StudyID: "ST1002", RadiologistID: "rad2", ShiftID: shifts[0].ID, Strategy: models.StrategyShiftPrimary, AssignedAt: time.Now().Add(-2 * time.Minute), Status: models.StatusInProgress},
+		{StudyID: "ST1003", RadiologistID: "rad_vip", ShiftID: shifts[1].ID, Strategy: models.StrategySpecialArrangement, AssignedAt: time.Now().Add(-1 * time.Minute), Status: models.StatusAssigned},
+	}
+	for _, a := range assignments {
+		if err := s.SaveAssignment(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func demoRadiologists() []*models.Radiologist {
+	return []*models.Radiologist{
+		{ID: "rad1", FirstName: "John", LastName: "Doe", Credentials: []string{"MRI", "CT"}, MaxConcurrentStudies: 5, Status: "active"},
+		{ID: "rad2", FirstName: "Jane", LastName: "Smith", Credentials: []string{"MRI", "MSK"}, MaxConcurrentStudies: 5, Status: "active"},
+		{ID: "rad3", FirstName: "Bob", LastName: "Jones", Credentials: []string{"CT", "Neuro"}, MaxConcurrentStudies: 5, Status: "active"},
+		{ID: "rad_limited", FirstName: "Limited", LastName: "Capacity", Credentials: []string{"CT"}, MaxConcurrentStudies: 1, Status: "active"},
+	}
+}
+
+func demoProcedures() []*models.Procedure {
+	return []*models.Procedure{
+		{Code: "CTHEAD", Description: "CT Head without contrast", Modality: "CT", BodyPart: "Head"},
+		{Code: "MRKNEE", Description: "MRI Knee", Modality: "MRI", BodyPart: "Knee"},
+	}
+}
+
+func demoReferenceData() *models.ReferenceData {
+	return &models.ReferenceData{
+		Sites: []models.Site{
+			{Code: "SiteA", Name: "Site A Clinic"},
+			{Code: "SiteB", Name: "Site B Hospital"},
+		},
+		Modalities: []models.Modality{
+			{Code: "CT", Name: "Computed Tomography"},
+			{Code: "MRI", Name: "Magnetic Resonance Imaging"},
+			{Code: "US", Name: "Ultrasound"},
+			{Code: "XR", Name: "X-Ray"},
+		},
+		BodyParts: []models.BodyPart{
+			{Name: "Head"},
+			{Name: "Chest"},
+			{Name: "Abdomen"},
+			{Name: "Pelvis"},
+			{Name: "Spine"},
+			{Name: "Extremity"},
+		},
+		Credentials: []models.Credential{
+			{Code: "CT", Name: "CT Certified"},
+			{Code: "MRI", Name: "MRI Certified"},
+			{Code: "US", Name: "Ultrasound Certified"},
+			{Code: "Neuro", Name: "Neuroradiology"},
+			{Code: "MSK", Name: "Musculoskeletal"},
+		},
+	}
+}
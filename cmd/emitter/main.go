@@ -1,10 +1,270 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"radiology-assignment/internal/hl7"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"strconv"
+	"syscall"
+	"time"
 )
 
+const (
+	defaultOutboundDir    = "data/outbound"
+	defaultDeadLetterDir  = "data/deadletter"
+	defaultDownstreamAddr = "localhost:2576"
+	defaultMaxAttempts    = 5
+	consumerName          = "emitter"
+
+	baseRetryDelay     = 1 * time.Second
+	maxRetryDelay      = 5 * time.Minute
+	resendPollInterval = 5 * time.Second
+)
+
+// sender is the part of hl7.Client used for delivery
+type sender interface {
+	Send(ctx context.Context, msg *hl7.Message) (*hl7.Message, error)
+}
+
+// deliverer sends outbound messages with exponential backoff and moves the
+// ones that keep failing to the dead letter store.
+type deliverer struct {
+	client      sender
+	deadLetters *queue.DeadLetterStore
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
 func main() {
-	log.Println("HL7 Emitter Service started")
-	// Outbound HL7 logic would go here
+	outboundDir := os.Getenv("OUTBOUND_DIR")
+	if outboundDir == "" {
+		outboundDir = defaultOutboundDir
+	}
+	deadLetterDir := os.Getenv("DEADLETTER_DIR")
+	if deadLetterDir == "" {
+		deadLetterDir = defaultDeadLetterDir
+	}
+	downstream := os.Getenv("DOWNSTREAM_ADDR")
+	if downstream == "" {
+		downstream = defaultDownstreamAddr
+	}
+	maxAttempts := defaultMaxAttempts
+	if v := os.Getenv("EMITTER_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid EMITTER_MAX_ATTEMPTS %q", v)
+		}
+		maxAttempts = n
+	}
+
+	consumer, err := queue.NewConsumer[models.AssignmentEvent](outboundDir, consumerName)
+	if err != nil {
+		log.Fatalf("Failed to open outbound journal: %v", err)
+	}
+	defer consumer.Close()
+
+	deadLetters, err := queue.NewDeadLetterStore(deadLetterDir)
+	if err != nil {
+		log.Fatalf("Failed to open dead letter store: %v", err)
+	}
+
+	client := hl7.NewClient(downstream)
+	defer client.Close()
+
+	d := &deliverer{
+		client:      client,
+		deadLetters: deadLetters,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseRetryDelay,
+		maxDelay:    maxRetryDelay,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("HL7 Emitter Service started, delivering to %s", downstream)
+
+	go d.processResends(ctx, resendPollInterval)
+
+	if err := d.run(ctx, consumer); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Emitter stopped: %v", err)
+	}
+}
+
+// run delivers completed assignments in journal order. A record is committed
+// once it has either been accepted downstream or moved to the dead letter
+// store, so no notification is lost across restarts.
+func (d *deliverer) run(ctx context.Context, consumer *queue.Consumer[models.AssignmentEvent]) error {
+	for {
+		rec, err := consumer.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		msg, err := buildMessage(rec.Value, time.Now())
+		attempts := 0
+		if err == nil {
+			attempts, err = d.deliver(ctx, msg)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				// Shutting down: leave the record uncommitted for next start
+				return ctx.Err()
+			}
+			if err := d.deadLetter(rec.Value, msg, attempts, err); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("Delivered ORU %s for study %s", msg.ControlID(), studyID(rec.Value))
+		}
+
+		if err := consumer.Commit(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// deliver sends msg until it is accepted, rejected outright (AR), or
+// maxAttempts is reached. It returns the number of attempts made.
+func (d *deliverer) deliver(ctx context.Context, msg *hl7.Message) (int, error) {
+	var lastErr error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		_, err := d.client.Send(ctx, msg)
+		if err == nil {
+			return attempt, nil
+		}
+		lastErr = err
+
+		var nak *hl7.NAKError
+		if errors.As(err, &nak) && nak.Code == hl7.AckReject {
+			// The receiver will never accept this message as-is
+			return attempt, err
+		}
+		if attempt == d.maxAttempts {
+			break
+		}
+
+		delay := d.backoff(attempt)
+		log.Printf("Delivery of %s failed (attempt %d/%d), retrying in %s: %v", msg.ControlID(), attempt, d.maxAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return d.maxAttempts, lastErr
+}
+
+// backoff returns the delay after the given attempt: base, 2*base, 4*base...
+// capped at maxDelay.
+func (d *deliverer) backoff(attempt int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.maxDelay {
+			return d.maxDelay
+		}
+	}
+	return delay
+}
+
+func (d *deliverer) deadLetter(event *models.AssignmentEvent, msg *hl7.Message, attempts int, cause error) error {
+	dl := &queue.DeadLetter{
+		Event:     event,
+		Attempts:  attempts,
+		LastError: cause.Error(),
+	}
+	if msg != nil {
+		dl.Message = msg.String()
+	}
+	if err := d.deadLetters.Add(dl); err != nil {
+		return fmt.Errorf("dead letter study %s: %w", studyID(event), err)
+	}
+	log.Printf("Moved study %s to dead letter %s after %d attempts: %v", studyID(event), dl.ID, attempts, cause)
+	return nil
+}
+
+// processResends periodically delivers dead letters that an operator asked
+// to resend from the API.
+func (d *deliverer) processResends(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.resendPending(ctx)
+		}
+	}
+}
+
+func (d *deliverer) resendPending(ctx context.Context) {
+	pending, err := d.deadLetters.PendingResends()
+	if err != nil {
+		log.Printf("Failed to list resend requests: %v", err)
+		return
+	}
+
+	for _, dl := range pending {
+		msg, err := buildMessage(dl.Event, time.Now())
+		attempts := 0
+		if err == nil {
+			attempts, err = d.deliver(ctx, msg)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			log.Printf("Resent dead letter %s for study %s", dl.ID, studyID(dl.Event))
+			if err := d.deadLetters.Delivered(dl.ID); err != nil {
+				log.Printf("Failed to clear resent dead letter %s: %v", dl.ID, err)
+			}
+			continue
+		}
+
+		dl.Attempts += attempts
+		dl.LastError = err.Error()
+		dl.FailedAt = time.Now()
+		if msg != nil {
+			dl.Message = msg.String()
+		}
+		if err := d.deadLetters.Requeue(dl); err != nil {
+			log.Printf("Failed to requeue dead letter %s: %v", dl.ID, err)
+		}
+	}
+}
+
+// buildMessage parses the original order carried on the event and enriches it
+// with the ZRA assignment segment.
+func buildMessage(event *models.AssignmentEvent, now time.Time) (*hl7.Message, error) {
+	if event == nil || event.Study == nil || event.Study.RawMessage == "" {
+		return nil, errors.New("original inbound message not available")
+	}
+	original, err := hl7.ParseMessage(event.Study.RawMessage)
+	if err != nil {
+		return nil, fmt.Errorf("original message: %w", err)
+	}
+	return hl7.BuildORU(original, event, now)
+}
+
+func studyID(event *models.AssignmentEvent) string {
+	if event == nil {
+		return ""
+	}
+	if event.Study != nil {
+		return event.Study.ID
+	}
+	if event.Assignment != nil {
+		return event.Assignment.StudyID
+	}
+	return ""
 }
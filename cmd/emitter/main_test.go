@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"radiology-assignment/internal/hl7"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"testing"
+	"time"
+)
+
+const testORM = "MSH|^~\\&|PACS|Robina|ASSIGNMENT_ENGINE|ENTERPRISE|20260204120530||ORM^O01|CTRL1|P|2.5.1\r" +
+	"OBR|1|STUDY_ID_123|ACCESSION_456|MRI MSK^MRI MUSCULOSKELETAL|||20260204120000\r" +
+	"ZRD|1|Robina^Site Code|MRI^Modality|STAT^Urgency\r"
+
+func TestBuildMessage(t *testing.T) {
+	event := &models.AssignmentEvent{
+		Study:       &models.Study{ID: "ACCESSION_456", RawMessage: testORM},
+		Assignment:  &models.Assignment{StudyID: "ACCESSION_456", RadiologistID: "rad1", Strategy: "load_balanced"},
+		Radiologist: &models.Radiologist{ID: "rad1", FirstName: "John", LastName: "Doe"},
+		ShiftName:   "Morning MRI",
+	}
+
+	msg, err := buildMessage(event, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := msg.Get("ZRA", 2, 2); got != "Doe" {
+		t.Errorf("Expected radiologist family name in ZRA-2, got %s", got)
+	}
+	if got := msg.Get("ZRA", 3, 1); got != "Morning MRI" {
+		t.Errorf("Expected shift name in ZRA-3, got %s", got)
+	}
+}
+
+func TestBuildMessage_NoOriginal(t *testing.T) {
+	event := &models.AssignmentEvent{
+		Study:      &models.Study{ID: "S1"},
+		Assignment: &models.Assignment{StudyID: "S1"},
+	}
+	if _, err := buildMessage(event, time.Now()); err == nil {
+		t.Fatal("Expected error when the original message is missing")
+	}
+}
+
+// scriptedSender returns the queued errors in order, then succeeds.
+type scriptedSender struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedSender) Send(ctx context.Context, msg *hl7.Message) (*hl7.Message, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	return msg, nil
+}
+
+func newTestDeliverer(t *testing.T, s sender) *deliverer {
+	store, err := queue.NewDeadLetterStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create dead letter store: %v", err)
+	}
+	return &deliverer{
+		client:      s,
+		deadLetters: store,
+		maxAttempts: 3,
+		baseDelay:   time.Millisecond,
+		maxDelay:    4 * time.Millisecond,
+	}
+}
+
+func testEvent(id string) *models.AssignmentEvent {
+	return &models.AssignmentEvent{
+		Study:      &models.Study{ID: id, RawMessage: testORM},
+		Assignment: &models.Assignment{StudyID: id, RadiologistID: "rad1", Strategy: "load_balanced"},
+	}
+}
+
+func TestDeliver_RetriesApplicationErrors(t *testing.T) {
+	s := &scriptedSender{errs: []error{
+		&hl7.NAKError{Code: hl7.AckError},
+		errors.New("i/o timeout"),
+	}}
+	d := newTestDeliverer(t, s)
+	msg, _ := buildMessage(testEvent("S1"), time.Now())
+
+	attempts, err := d.deliver(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDeliver_RejectIsNotRetried(t *testing.T) {
+	s := &scriptedSender{errs: []error{&hl7.NAKError{Code: hl7.AckReject}}}
+	d := newTestDeliverer(t, s)
+	msg, _ := buildMessage(testEvent("S1"), time.Now())
+
+	if _, err := d.deliver(context.Background(), msg); err == nil {
+		t.Fatal("Expected AR to fail delivery")
+	}
+	if s.calls != 1 {
+		t.Errorf("Expected a single attempt for AR, got %d", s.calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &deliverer{baseDelay: time.Second, maxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w {
+			t.Errorf("Attempt %d: expected %s, got %s", i+1, w, got)
+		}
+	}
+}
+
+func TestRun_DeadLettersAfterMaxAttempts(t *testing.T) {
+	dir := t.TempDir()
+	j, err := queue.Open[models.AssignmentEvent](dir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer j.Close()
+	j.Append(testEvent("S1"))
+	j.Append(testEvent("S2"))
+
+	consumer, err := queue.NewConsumer[models.AssignmentEvent](dir, consumerName)
+	if err != nil {
+		t.Fatalf("Failed to open consumer: %v", err)
+	}
+	defer consumer.Close()
+	consumer.PollInterval = time.Millisecond
+
+	timeout := errors.New("i/o timeout")
+	s := &scriptedSender{errs: []error{timeout, timeout, timeout}}
+	d := newTestDeliverer(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	d.run(ctx, consumer)
+
+	dead, err := d.deadLetters.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(dead))
+	}
+	if dead[0].Event.Study.ID != "S1" || dead[0].Attempts != 3 {
+		t.Errorf("Unexpected dead letter: study %s, attempts %d", dead[0].Event.Study.ID, dead[0].Attempts)
+	}
+	if s.calls != 4 {
+		t.Errorf("Expected S2 to be delivered after S1 was dead-lettered, got %d sends", s.calls)
+	}
+}
+
+func TestResendPending(t *testing.T) {
+	s := &scriptedSender{errs: []error{errors.New("down"), errors.New("down"), errors.New("down")}}
+	d := newTestDeliverer(t, s)
+
+	d.deadLetters.Add(&queue.DeadLetter{ID: "dl1", Event: testEvent("S1"), Attempts: 3})
+	d.deadLetters.Add(&queue.DeadLetter{ID: "dl2", Event: testEvent("S2"), Attempts: 3})
+	d.deadLetters.Resend("dl1")
+	d.deadLetters.Resend("dl2")
+
+	// dl1 fails again and goes back to the queue, dl2 is delivered
+	d.resendPending(context.Background())
+
+	pending, _ := d.deadLetters.PendingResends()
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending resends, got %d", len(pending))
+	}
+	dead, _ := d.deadLetters.List()
+	if len(dead) != 1 || dead[0].ID != "dl1" {
+		t.Fatalf("Expected dl1 back in the dead letter queue, got %v", dead)
+	}
+	if dead[0].Attempts != 6 {
+		t.Errorf("Expected attempts to accumulate to 6, got %d", dead[0].Attempts)
+	}
+}
@@ -1,36 +1,210 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	// "radiology-assignment/internal/cache"
-	// "radiology-assignment/internal/db"
+	"os"
+	"os/signal"
+	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/cache"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultJournalDir  = "data/journal"
+	defaultOutboundDir = "data/outbound"
+	consumerName       = "engine"
+
+	baseRetryDelay = 1 * time.Second
+	maxRetryDelay  = 1 * time.Minute
 )
 
 func main() {
-	// Initialize database (Pseudo-code as actual DB impl is not part of this specific task step)
-	// pgConn := db.Connect()
-	// defer pgConn.Close()
-
-	// Initialize caches
-	// rosterCache := cache.NewRosterCache(pgConn)
-	// rulesCache := cache.NewRulesCache(pgConn)
-
-	// Refresh caches periodically
-	/*
-		go func() {
-			ticker := time.NewTicker(5 * time.Minute)
-			for range ticker.C {
-				rosterCache.Refresh(context.Background())
-				rulesCache.Refresh(context.Background())
+	journalDir := os.Getenv("JOURNAL_DIR")
+	if journalDir == "" {
+		journalDir = defaultJournalDir
+	}
+
+	outboundDir := os.Getenv("OUTBOUND_DIR")
+	if outboundDir == "" {
+		outboundDir = defaultOutboundDir
+	}
+
+	consumer, err := queue.NewConsumer[models.Study](journalDir, consumerName)
+	if err != nil {
+		log.Fatalf("Failed to open journal: %v", err)
+	}
+	defer consumer.Close()
+
+	outbound, err := queue.Open[models.AssignmentEvent](outboundDir)
+	if err != nil {
+		log.Fatalf("Failed to open outbound journal: %v", err)
+	}
+	defer outbound.Close()
+
+	refreshInterval := cache.DefaultRefreshInterval
+	if v := os.Getenv("CACHE_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid CACHE_REFRESH_INTERVAL %q", v)
+		}
+		refreshInterval = d
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cached, err := openStore(ctx)
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer cached.Close()
+
+	// Edits made through cmd/api reach the caches on the next refresh
+	go cached.Run(ctx, refreshInterval)
+
+	p := &processor{engine: assignment.NewEngine(cached, cached.Roster, cached.Rules), store: cached}
+
+	log.Printf("Assignment Engine Service started, consuming %s from offset %d", journalDir, consumer.Cursor())
+
+	if err := run(ctx, consumer, outbound, p.processStudy, baseRetryDelay); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Journal consumer stopped: %v", err)
+	}
+}
+
+// run consumes the inbound journal until ctx is cancelled. A study is only
+// committed once it has been applied and any assignment handed to the
+// emitter, or once the engine has rejected it for good. Other failures, such
+// as the database being unreachable, are retried with backoff while the study
+// stays uncommitted, so a crash or an outage delays orders rather than losing
+// them.
+func run(ctx context.Context, consumer *queue.Consumer[models.Study], outbound *queue.Journal[models.AssignmentEvent], process func(context.Context, *models.Study) (*models.AssignmentEvent, error), retryDelay time.Duration) error {
+	for {
+		rec, err := consumer.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		event, err := process(ctx, rec.Value)
+		for attempt := 1; err != nil && !rejected(err); attempt++ {
+			delay := backoff(retryDelay, attempt)
+			log.Printf("Processing study %s failed (attempt %d), retrying in %s: %v", rec.Value.ID, attempt, delay, err)
+			select {
+			case <-ctx.Done():
+				// Shutting down: leave the study uncommitted for next start
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			event, err = process(ctx, rec.Value)
+		}
+
+		if err != nil {
+			log.Printf("Rejected %s order for study %s: %v", rec.Value.OrderControl, rec.Value.ID, err)
+		} else if event != nil {
+			// Emit event for outbound HL7
+			if _, err := outbound.Append(event); err != nil {
+				return err
 			}
-		}()
-	*/
+		}
+
+		if err := consumer.Commit(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// rejected reports whether the engine can never apply an order as sent, so
+// retrying it would only hold up the orders behind it.
+func rejected(err error) bool {
+	return errors.Is(err, assignment.ErrNoAssignment) || errors.Is(err, models.ErrInvalidTransition)
+}
+
+// backoff returns the delay after the given attempt: base, 2*base, 4*base...
+// capped at maxRetryDelay.
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// processor applies dequeued orders through the engine and builds the events
+// the emitter publishes.
+type processor struct {
+	engine *assignment.Engine
+	store  db.Store
+}
+
+// openStore connects to the database cmd/api manages, from STORE_BACKEND and
+// DATABASE_URL, and wraps it with the roster and rules caches the engine reads
+// from. Only postgres can be shared between the two processes: the memory
+// store lives inside cmd/api and bolt holds an exclusive lock on its file for
+// as long as cmd/api has it open.
+func openStore(ctx context.Context) (*cache.Store, error) {
+	backend := os.Getenv("STORE_BACKEND")
+	if backend != db.BackendPostgres {
+		return nil, fmt.Errorf("STORE_BACKEND must be %s to share the store with cmd/api, got %q", db.BackendPostgres, backend)
+	}
+	s, err := db.Open(ctx, db.Config{Backend: backend, DatabaseURL: os.Getenv("DATABASE_URL")})
+	if err != nil {
+		return nil, err
+	}
+	cs, err := cache.NewStore(ctx, s)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("load caches: %w", err)
+	}
+	return cs, nil
+}
 
-	// Initialize assignment engine
-	// engine := assignment.NewEngine(pgConn, rosterCache, rulesCache)
+// processStudy applies an order according to ORC-1: NW assigns, CA/DC cancel,
+// XO reassigns on routing changes and SC/RE move the assignment through its
+// lifecycle. A study nobody can take goes to the manual worklist. It returns
+// an event only when the order produced a new assignment.
+func (p *processor) processStudy(ctx context.Context, study *models.Study) (*models.AssignmentEvent, error) {
+	if study.IsStatusUpdate() {
+		// The listener journals SC and ORU messages so the feed, not just
+		// /api/assignments/status, closes studies and frees capacity
+		log.Printf("Dequeued %s status %q for study %s", study.OrderControl, study.ReportStatus, study.ID)
+	} else {
+		log.Printf("Dequeued %s order for study %s (%s %s, %s)", study.OrderControl, study.ID, study.Modality, study.BodyPart, study.Urgency)
+	}
 
-	log.Println("Assignment Engine Service started (Skeleton)")
+	a, err := p.engine.Process(ctx, study)
+	if errors.Is(err, assignment.ErrNoCandidate) {
+		// Nobody can take the study, so it waits on the manual worklist
+		log.Printf("Escalating study %s to the manual worklist: %v", study.ID, err)
+		a, err = p.engine.Escalate(ctx, study)
+	}
+	if err != nil || a == nil {
+		return nil, err
+	}
 
-	// Process queue loop would go here
-	// for study := range queue.Channel() { ... }
+	// The assignment is saved by now, so a failed lookup only leaves the
+	// outbound message without a name
+	event := &models.AssignmentEvent{Assignment: a, Study: study}
+	if a.RadiologistID != models.WorklistAssignee {
+		if event.Radiologist, err = p.store.GetRadiologist(ctx, a.RadiologistID); err != nil {
+			log.Printf("Failed to look up radiologist %s for study %s: %v", a.RadiologistID, study.ID, err)
+		}
+	}
+	if a.ShiftID != 0 {
+		if sh, err := p.store.GetShift(ctx, a.ShiftID); err != nil {
+			log.Printf("Failed to look up shift %d for study %s: %v", a.ShiftID, study.ID, err)
+		} else {
+			event.ShiftName = sh.Name
+		}
+	}
+	log.Printf("Assigned study %s to %s (%s)", study.ID, a.RadiologistID, a.Strategy)
+	return event, nil
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/cache"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"testing"
+	"time"
+)
+
+func TestRun_CommitsProcessedStudies(t *testing.T) {
+	dir := t.TempDir()
+	j, err := queue.Open[models.Study](dir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer j.Close()
+	for _, id := range []string{"S1", "S2", "S3", "S4"} {
+		j.Append(&models.Study{ID: id})
+	}
+
+	consumer, err := queue.NewConsumer[models.Study](dir, consumerName)
+	if err != nil {
+		t.Fatalf("Failed to open consumer: %v", err)
+	}
+	consumer.PollInterval = 5 * time.Millisecond
+
+	outDir := t.TempDir()
+	outbound, err := queue.Open[models.AssignmentEvent](outDir)
+	if err != nil {
+		t.Fatalf("Failed to open outbound journal: %v", err)
+	}
+	defer outbound.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var seen []string
+	process := func(ctx context.Context, study *models.Study) (*models.AssignmentEvent, error) {
+		seen = append(seen, study.ID)
+		switch {
+		case study.ID == "S1":
+			return &models.AssignmentEvent{Study: study, Assignment: &models.Assignment{StudyID: study.ID, RadiologistID: "rad1"}}, nil
+		case study.ID == "S2" && len(seen) == 2:
+			return nil, errors.New("database unavailable")
+		case study.ID == "S2":
+			return &models.AssignmentEvent{Study: study, Assignment: &models.Assignment{StudyID: study.ID, RadiologistID: "rad2"}}, nil
+		case study.ID == "S3":
+			return nil, assignment.ErrNoAssignment
+		}
+		if len(seen) == 6 {
+			cancel()
+		}
+		return nil, errors.New("database unavailable")
+	}
+
+	if err := run(ctx, consumer, outbound, process, time.Millisecond); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	consumer.Close()
+
+	want := []string{"S1", "S2", "S2", "S3", "S4", "S4"}
+	if len(seen) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, seen)
+			break
+		}
+	}
+
+	// S2 reached the emitter once its retry succeeded
+	emitter, err := queue.NewConsumer[models.AssignmentEvent](outDir, "emitter")
+	if err != nil {
+		t.Fatalf("Failed to open outbound consumer: %v", err)
+	}
+	defer emitter.Close()
+	emitter.PollInterval = 5 * time.Millisecond
+	for _, id := range []string{"S1", "S2"} {
+		evCtx, evCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		ev, err := emitter.Next(evCtx)
+		evCancel()
+		if err != nil {
+			t.Fatalf("Expected assignment event for %s, got %v", id, err)
+		}
+		if ev.Value.Assignment.StudyID != id {
+			t.Errorf("Expected event for %s, got %s", id, ev.Value.Assignment.StudyID)
+		}
+		emitter.Commit(ev)
+	}
+
+	// The rejected S3 was committed; S4, still failing at shutdown, was not
+	consumer, err = queue.NewConsumer[models.Study](dir, consumerName)
+	if err != nil {
+		t.Fatalf("Failed to reopen consumer: %v", err)
+	}
+	defer consumer.Close()
+	consumer.PollInterval = 5 * time.Millisecond
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec, err := consumer.Next(ctx)
+	if err != nil || rec.Value.ID != "S4" {
+		t.Errorf("Expected S4 to be redelivered after restart, got %+v, %v", rec, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := backoff(time.Second, tt.attempt); got != tt.want {
+			t.Errorf("Expected %s after attempt %d, got %s", tt.want, tt.attempt, got)
+		}
+	}
+}
+
+// newTestProcessor returns a processor over a memory store with rad1 rostered
+// on an MRI shift.
+func newTestProcessor(t *testing.T) (*processor, db.Store) {
+	ctx := context.Background()
+	s := db.NewMemoryStore()
+	if err := s.SaveRadiologist(ctx, &models.Radiologist{ID: "rad1", FirstName: "John", LastName: "Doe", Status: "active"}); err != nil {
+		t.Fatalf("Failed to save radiologist: %v", err)
+	}
+	shift := &models.Shift{Name: "MRI Days", WorkType: "MRI"}
+	if err := s.CreateShift(ctx, shift); err != nil {
+		t.Fatalf("Failed to create shift: %v", err)
+	}
+	if err := s.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: shift.ID, RadiologistID: "rad1", StartDate: time.Now().Add(-24 * time.Hour)}); err != nil {
+		t.Fatalf("Failed to roster rad1: %v", err)
+	}
+	cached, err := cache.NewStore(ctx, s)
+	if err != nil {
+		t.Fatalf("Failed to load caches: %v", err)
+	}
+	return &processor{engine: assignment.NewEngine(cached, cached.Roster, cached.Rules), store: cached}, s
+}
+
+func TestProcessStudy_AppliesOrders(t *testing.T) {
+	p, s := newTestProcessor(t)
+	ctx := context.Background()
+	order := models.Study{ID: "S1", OrderControl: models.OrderControlNew, Modality: "MRI", Urgency: "ROUTINE"}
+
+	study := order
+	event, err := p.processStudy(ctx, &study)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event == nil || event.Assignment.RadiologistID != "rad1" {
+		t.Fatalf("Expected an event assigning rad1, got %+v", event)
+	}
+	if since := time.Since(event.Assignment.AssignedAt); since < 0 || since > time.Minute {
+		t.Errorf("Expected AssignedAt to be the decision time, got %s", event.Assignment.AssignedAt)
+	}
+	if event.Study != &study || event.Radiologist == nil || event.Radiologist.LastName != "Doe" || event.ShiftName != "MRI Days" {
+		t.Errorf("Expected the event to carry the study, radiologist and shift name, got %+v", event)
+	}
+
+	// A redelivered order is not assigned again
+	study = order
+	if event, err := p.processStudy(ctx, &study); err != nil || event != nil {
+		t.Errorf("Expected no event for a redelivered order, got %+v, %v", event, err)
+	}
+
+	study = order
+	study.OrderControl = models.OrderControlCancel
+	if event, err := p.processStudy(ctx, &study); err != nil || event != nil {
+		t.Errorf("Expected no event for a cancellation, got %+v, %v", event, err)
+	}
+	a, err := s.GetAssignmentByStudyID(ctx, "S1")
+	if err != nil || a.CurrentStatus() != models.StatusCancelled {
+		t.Errorf("Expected the assignment to be cancelled, got %+v, %v", a, err)
+	}
+	if load, _ := s.GetRadiologistCurrentWorkload(ctx, "rad1"); load != 0 {
+		t.Errorf("Expected rad1's workload to drop to 0, got %d", load)
+	}
+}
+
+func TestProcessStudy_EscalatesUnassignableStudies(t *testing.T) {
+	p, s := newTestProcessor(t)
+	ctx := context.Background()
+
+	// No shift covers CT
+	event, err := p.processStudy(ctx, &models.Study{ID: "S1", OrderControl: models.OrderControlNew, Modality: "CT"})
+	if err != nil || event == nil {
+		t.Fatalf("Expected an event for the escalated study, got %+v, %v", event, err)
+	}
+	a := event.Assignment
+	if a.RadiologistID != models.WorklistAssignee || a.Worklist != models.ManualWorklist || a.Strategy != models.StrategyManual {
+		t.Errorf("Expected a manual worklist assignment, got %+v", a)
+	}
+	if saved, err := s.GetAssignmentByStudyID(ctx, "S1"); err != nil || saved == nil || saved.Worklist != models.ManualWorklist {
+		t.Errorf("Expected the manual worklist assignment to be saved, got %+v, %v", saved, err)
+	}
+}
+
+func TestProcessStudy_StatusUpdatesDriveLifecycle(t *testing.T) {
+	p, s := newTestProcessor(t)
+	ctx := context.Background()
+	if _, err := p.processStudy(ctx, &models.Study{ID: "S1", OrderControl: models.OrderControlNew, Modality: "MRI"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	updates := []struct {
+		control, status string
+	}{
+		{models.OrderControlStatus, models.StatusInProgress},
+		{models.OrderControlResult, models.StatusFinalized},
+		{models.OrderControlResult, models.StatusFinalized}, // Redelivered
+	}
+	for _, u := range updates {
+		event, err := p.processStudy(ctx, &models.Study{ID: "S1", OrderControl: u.control, ReportStatus: u.status})
+		if err != nil || event != nil {
+			t.Fatalf("Expected %s %s to be applied without an event, got %+v, %v", u.control, u.status, event, err)
+		}
+		a, err := s.GetAssignmentByStudyID(ctx, "S1")
+		if err != nil || a.CurrentStatus() != u.status {
+			t.Errorf("Expected status %s, got %+v, %v", u.status, a, err)
+		}
+	}
+	if load, _ := s.GetRadiologistCurrentWorkload(ctx, "rad1"); load != 0 {
+		t.Errorf("Expected a finalized study to free rad1's capacity, got load %d", load)
+	}
+
+	if _, err := p.processStudy(ctx, &models.Study{ID: "S9", OrderControl: models.OrderControlStatus, ReportStatus: models.StatusInProgress}); !errors.Is(err, assignment.ErrNoAssignment) {
+		t.Errorf("Expected ErrNoAssignment for an unknown study, got %v", err)
+	}
+}
@@ -1,24 +1,63 @@
 package main
 
 import (
+	"errors"
+	"io"
 	"log"
 	"net"
 	"os"
+	"radiology-assignment/internal/hl7"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
 	"time"
 )
 
 const (
 	defaultPort        = ":2575"
+	defaultJournalDir  = "data/journal"
 	maxConcurrentConns = 100
 	readTimeout        = 30 * time.Second
+	writeTimeout       = 10 * time.Second
 )
 
+// inbound is the durable journal studies are written to before they are
+// acknowledged; the engine service consumes it.
+var inbound *queue.Journal[models.Study]
+
+// supportedMessageTypes lists the MSH-9 values the listener accepts
+var supportedMessageTypes = map[string]bool{
+	"ORM^O01": true,
+	"ORU^R01": true,
+}
+
+// supportedOrderControls lists the ORC-1 codes the engine knows how to apply
+var supportedOrderControls = map[string]bool{
+	models.OrderControlNew:         true,
+	models.OrderControlCancel:      true,
+	models.OrderControlDiscontinue: true,
+	models.OrderControlChange:      true,
+	models.OrderControlStatus:      true,
+	models.OrderControlResult:      true,
+}
+
 func main() {
 	port := os.Getenv("HL7_PORT")
 	if port == "" {
 		port = defaultPort
 	}
 
+	journalDir := os.Getenv("JOURNAL_DIR")
+	if journalDir == "" {
+		journalDir = defaultJournalDir
+	}
+
+	var err error
+	inbound, err = queue.Open[models.Study](journalDir)
+	if err != nil {
+		log.Fatalf("Failed to open journal: %v", err)
+	}
+	defer inbound.Close()
+
 	listener, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
@@ -53,18 +92,109 @@ func main() {
 func handleConnection(conn net.Conn) {
 	defer conn.Close()
 
-	// Set read deadline to prevent slow-client attacks
-	if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
-		log.Printf("Failed to set read deadline: %v", err)
-		return
+	reader := hl7.NewMLLPReader(conn)
+	for {
+		// Reset the deadline per message so idle persistent connections are
+		// reaped while active senders can keep the socket open indefinitely
+		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			log.Printf("Failed to set read deadline: %v", err)
+			return
+		}
+
+		payload, err := reader.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Read error from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		ack := handleMessage(payload)
+
+		if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			log.Printf("Failed to set write deadline: %v", err)
+			return
+		}
+		if err := hl7.WriteMessage(conn, []byte(ack)); err != nil {
+			log.Printf("Failed to send ACK to %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// handleMessage processes one inbound message and returns the ACK to send.
+// Every message gets a reply: AA on success, AE when a segment or required
+// field cannot be decoded, and AR when the message type is not supported.
+func handleMessage(payload []byte) string {
+	now := time.Now()
+	raw := string(payload)
+
+	msh, err := hl7.ParseHeader(raw)
+	if err != nil {
+		log.Printf("Rejecting message with unreadable header: %v", err)
+		return hl7.BuildACK(nil, hl7.Ack{Code: hl7.AckReject, Text: err.Error(), ErrorCode: hl7.ErrCodeSegmentSequence}, now)
+	}
+
+	msg, err := hl7.ParseMessage(raw)
+	if err != nil {
+		log.Printf("Parse error: %v", err)
+		return hl7.BuildACK(msh, hl7.AckForError(err), now)
 	}
 
-	// HL7 processing logic would go here
-	// For skeleton, we just log and maybe read a bit to demonstrate
+	if msgType := msg.MessageType(); !supportedMessageTypes[msgType] {
+		log.Printf("Rejecting unsupported message type %q (control ID %s)", msgType, msg.ControlID())
+		return hl7.BuildACK(msh, hl7.Ack{
+			Code:      hl7.AckReject,
+			Text:      "Unsupported message type " + msgType,
+			ErrorCode: hl7.ErrCodeUnsupportedMessageType,
+			Segment:   "MSH",
+			Sequence:  1,
+			Field:     9,
+		}, now)
+	}
+
+	study, err := hl7.ExtractStudy(msg)
+	if err != nil {
+		log.Printf("Failed to extract study from message %s: %v", msg.ControlID(), err)
+		return hl7.BuildACK(msh, hl7.AckForError(err), now)
+	}
+	if !supportedOrderControls[study.OrderControl] {
+		log.Printf("Rejecting unsupported order control %q for study %s", study.OrderControl, study.ID)
+		return hl7.BuildACK(msh, hl7.Ack{
+			Code:      hl7.AckError,
+			Text:      "Unsupported order control " + study.OrderControl,
+			ErrorCode: hl7.ErrCodeTableValueNotFound,
+			Segment:   "ORC",
+			Sequence:  1,
+			Field:     1,
+		}, now)
+	}
+	if study.IsStatusUpdate() && study.ReportStatus == "" {
+		log.Printf("Rejecting status update for study %s without a recognised status", study.ID)
+		return hl7.BuildACK(msh, hl7.Ack{
+			Code:      hl7.AckError,
+			Text:      "Unrecognised order or result status",
+			ErrorCode: hl7.ErrCodeTableValueNotFound,
+			Segment:   "OBR",
+			Sequence:  1,
+			Field:     25,
+		}, now)
+	}
+	study.IngestTime = now
+	study.RawMessage = raw
+
+	// Only acknowledge once the study is on disk; if this fails the sender
+	// retries and nothing is lost
+	if _, err := inbound.Append(study); err != nil {
+		log.Printf("Failed to journal study %s: %v", study.ID, err)
+		return hl7.BuildACK(msh, hl7.Ack{
+			Code:      hl7.AckError,
+			Text:      "Unable to persist message",
+			ErrorCode: hl7.ErrCodeApplicationInternal,
+		}, now)
+	}
 
-	// Example read (not implemented in full parser yet)
-	// buf := make([]byte, 4096)
-	// _, err := conn.Read(buf)
+	log.Printf("Received %s order for study %s from %s (%s %s, %s)", study.OrderControl, study.ID, study.Site, study.Modality, study.BodyPart, study.Urgency)
 
-	log.Println("Connection accepted and handled securely")
+	return hl7.BuildACK(msh, hl7.Ack{Code: hl7.AckAccept, Text: "Message accepted"}, now)
 }
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"net"
+	"radiology-assignment/internal/hl7"
+	"radiology-assignment/internal/models"
+	"radiology-assignment/internal/queue"
+	"testing"
+	"time"
+)
+
+const testORM = "MSH|^~\\&|PACS|Robina|ASSIGNMENT_ENGINE|ENTERPRISE|20260204120530||ORM^O01|CTRL1|P|2.5.1\r" +
+	"PID|1||MRN123^^^Robina||Doe^John||19800115|M\r" +
+	"OBR|1|STUDY_ID_123|ACCESSION_456|MRI MSK^MRI MUSCULOSKELETAL|||20260204120000\r" +
+	"ZRD|1|Robina^Site Code|MRI^Modality|STAT^Urgency\r"
+
+func setupJournal(t *testing.T) string {
+	dir := t.TempDir()
+	j, err := queue.Open[models.Study](dir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	inbound = j
+	return dir
+}
+
+func TestHandleMessage_AckCodes(t *testing.T) {
+	setupJournal(t)
+
+	tests := []struct {
+		name     string
+		raw      string
+		wantCode string
+		wantID   string
+	}{
+		{name: "Accepted", raw: testORM, wantCode: "AA", wantID: "CTRL1"},
+		{
+			name:     "Unsupported type",
+			raw:      "MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ADT^A01|CTRL2|P|2.5.1\rPID|1\r",
+			wantCode: "AR",
+			wantID:   "CTRL2",
+		},
+		{
+			name:     "Bad segment",
+			raw:      "MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ORM^O01|CTRL3|P|2.5.1\rbad segment\r",
+			wantCode: "AE",
+			wantID:   "CTRL3",
+		},
+		{
+			name:     "Missing accession",
+			raw:      "MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ORM^O01|CTRL4|P|2.5.1\rOBR|1|||CT HEAD\r",
+			wantCode: "AE",
+			wantID:   "CTRL4",
+		},
+		{
+			name:     "Cancel",
+			raw:      "MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ORM^O01|CTRL5|P|2.5.1\rORC|CA|STUDY_ID_123\rOBR|1|STUDY_ID_123|ACCESSION_456|CT HEAD\r",
+			wantCode: "AA",
+			wantID:   "CTRL5",
+		},
+		{
+			name:     "Unsupported order control",
+			raw:      "MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ORM^O01|CTRL6|P|2.5.1\rORC|HD|STUDY_ID_123\rOBR|1|STUDY_ID_123|ACCESSION_456|CT HEAD\r",
+			wantCode: "AE",
+			wantID:   "CTRL6",
+		},
+		{
+			name:     "Result status",
+			raw:      "MSH|^~\\&|RIS|Robina|ENGINE|ENT|20260204120530||ORU^R01|CTRL7|P|2.5.1\rOBR|1|STUDY_ID_123|ACCESSION_456|CT HEAD|||||||||||||||||||||F\r",
+			wantCode: "AA",
+			wantID:   "CTRL7",
+		},
+		{
+			name:     "Status change without status",
+			raw:      "MSH|^~\\&|RIS|Robina|ENGINE|ENT|20260204120530||ORM^O01|CTRL8|P|2.5.1\rORC|SC|STUDY_ID_123\rOBR|1|STUDY_ID_123|ACCESSION_456|CT HEAD\r",
+			wantCode: "AE",
+			wantID:   "CTRL8",
+		},
+		{name: "No header", raw: "PID|1||MRN\r", wantCode: "AR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack, err := hl7.ParseMessage(handleMessage([]byte(tt.raw)))
+			if err != nil {
+				t.Fatalf("ACK did not parse: %v", err)
+			}
+			if got := ack.Get("MSA", 1, 1); got != tt.wantCode {
+				t.Errorf("Expected %s, got %s", tt.wantCode, got)
+			}
+			if got := ack.Get("MSA", 2, 1); got != tt.wantID {
+				t.Errorf("Expected MSA-2 %q, got %q", tt.wantID, got)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_JournalsBeforeAck(t *testing.T) {
+	dir := setupJournal(t)
+
+	handleMessage([]byte(testORM))
+
+	consumer, err := queue.NewConsumer[models.Study](dir, "test")
+	if err != nil {
+		t.Fatalf("Failed to open consumer: %v", err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rec, err := consumer.Next(ctx)
+	if err != nil {
+		t.Fatalf("Expected journaled study, got %v", err)
+	}
+	if rec.Value.ID != "ACCESSION_456" || rec.Value.MessageID != "CTRL1" {
+		t.Errorf("Unexpected journaled study: %+v", rec.Value)
+	}
+	if rec.Value.RawMessage != testORM {
+		t.Errorf("Expected raw message to be journaled with the study")
+	}
+}
+
+func TestHandleMessage_JournalFailure(t *testing.T) {
+	setupJournal(t)
+	inbound.Close()
+
+	ack, err := hl7.ParseMessage(handleMessage([]byte(testORM)))
+	if err != nil {
+		t.Fatalf("ACK did not parse: %v", err)
+	}
+	if got := ack.Get("MSA", 1, 1); got != "AE" {
+		t.Errorf("Expected AE when the journal is unavailable, got %s", got)
+	}
+}
+
+func TestHandleConnection_PersistentConnection(t *testing.T) {
+	setupJournal(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		handleConnection(server)
+		close(done)
+	}()
+
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := hl7.NewMLLPReader(client)
+
+	for i := 0; i < 2; i++ {
+		if err := hl7.WriteMessage(client, []byte(testORM)); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+		payload, err := reader.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read ACK %d: %v", i, err)
+		}
+		ack, err := hl7.ParseMessage(string(payload))
+		if err != nil {
+			t.Fatalf("ACK %d did not parse: %v", i, err)
+		}
+		if got := ack.Get("MSA", 1, 1); got != "AA" {
+			t.Errorf("Expected AA for message %d, got %s", i, got)
+		}
+	}
+
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleConnection did not return after client closed")
+	}
+}
@@ -1,8 +1,12 @@
 module radiology-assignment
 
-go 1.25
+go 1.25.0
 
-require github.com/chromedp/chromedp v0.14.2
+require (
+	github.com/chromedp/chromedp v0.14.2
+	github.com/jackc/pgx/v5 v5.9.2
+	go.etcd.io/bbolt v1.4.3
+)
 
 require (
 	github.com/chromedp/cdproto v0.0.0-20250803210736-d308e07a266d // indirect
@@ -11,5 +15,10 @@ require (
 	github.com/gobwas/httphead v0.1.0 // indirect
 	github.com/gobwas/pool v0.2.1 // indirect
 	github.com/gobwas/ws v1.4.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 )
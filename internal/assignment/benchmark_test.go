@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"radiology-assignment/internal/models"
+	"sort"
 	"testing"
+	"time"
 )
 
 // FR-4.3.2: The system shall support at least 1,000 active assignment rules.
@@ -94,3 +96,40 @@ func BenchmarkAssign_EndToEnd(b *testing.B) {
 		_, _ = engine.Assign(context.Background(), study)
 	}
 }
+
+// FR-4.3.2, NFR-5.2.3 and NFR-5.1.1 together: 1,000 active rules against
+// 1,500 candidates, reporting the p95 assignment latency.
+func BenchmarkAssign_1000Rules1500Candidates(b *testing.B) {
+	numRads := 1500
+	rads := make([]*models.Radiologist, numRads)
+	rosterList := make([]string, numRads)
+	for i := 0; i < numRads; i++ {
+		id := fmt.Sprintf("rad%d", i)
+		rads[i] = &models.Radiologist{ID: id, Status: "active", Credentials: []string{"MRI"}}
+		rosterList[i] = id
+	}
+
+	shift := &models.Shift{ID: 1, WorkType: "MRI"}
+	study := &models.Study{ID: "bench_study", Modality: "MRI", Site: "MAIN", ProcedureCode: "PROC1", Urgency: "ROUTINE"}
+
+	engine := setupEngine(b, []*models.Shift{shift}, rads, map[int64][]string{1: rosterList}, nil)
+	engine.rules = &MockVersionedRulesService{Rules: benchmarkRules(1000), Version: 1}
+
+	durations := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		if _, err := engine.Assign(context.Background(), study); err != nil {
+			b.Fatalf("Assign: %v", err)
+		}
+		durations = append(durations, time.Since(start))
+	}
+	b.StopTimer()
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	p95 := durations[len(durations)*95/100]
+	b.ReportMetric(float64(p95.Microseconds())/1000, "p95-ms")
+	if p95 > 500*time.Millisecond {
+		b.Errorf("p95 latency %v exceeds 500ms", p95)
+	}
+}
@@ -0,0 +1,79 @@
+package assignment
+
+import (
+	"fmt"
+	"radiology-assignment/internal/models"
+	"strings"
+)
+
+// Steps at which a rostered radiologist can be excluded.
+const (
+	stepEligibility = "eligibility"
+	stepCapacity    = "capacity"
+)
+
+// Exclusion records why a rostered radiologist was not made a candidate for
+// a shift.
+type Exclusion struct {
+	RadiologistID string
+	ShiftID       int64
+	Step          string // eligibility or capacity
+	Reason        string
+}
+
+func (x Exclusion) String() string {
+	return fmt.Sprintf("%s on shift %d: %s", x.RadiologistID, x.ShiftID, x.Reason)
+}
+
+// IneligibleReason explains why rad cannot take work from shift, or returns
+// "" if they can: they must be active, available and hold every credential
+// the shift requires. Capacity is checked separately, against open workload.
+func IneligibleReason(shift *models.Shift, rad *models.Radiologist) string {
+	if rad.Status != models.RadiologistStatusActive {
+		return fmt.Sprintf("radiologist is %s", orUnknown(rad.Status))
+	}
+	if !rad.TakesWork() {
+		return fmt.Sprintf("radiologist availability is %s", rad.StoredAvailability())
+	}
+	if missing := MissingCredentials(shift, rad); len(missing) > 0 {
+		return "missing credentials " + strings.Join(missing, ", ")
+	}
+	return ""
+}
+
+// MissingCredentials returns the shift's required credentials that rad does
+// not hold. Codes compare case-insensitively; blank codes are ignored.
+func MissingCredentials(shift *models.Shift, rad *models.Radiologist) []string {
+	var missing []string
+	for _, req := range shift.RequiredCredentials {
+		if strings.TrimSpace(req) == "" {
+			continue
+		}
+		held := false
+		for _, cred := range rad.Credentials {
+			if strings.EqualFold(cred, req) {
+				held = true
+				break
+			}
+		}
+		if !held {
+			missing = append(missing, req)
+		}
+	}
+	return missing
+}
+
+func orUnknown(status string) string {
+	if status == "" {
+		return "without a status"
+	}
+	return status
+}
+
+func joinExclusions(excluded []Exclusion) string {
+	parts := make([]string, len(excluded))
+	for i, x := range excluded {
+		parts[i] = x.String()
+	}
+	return strings.Join(parts, "; ")
+}
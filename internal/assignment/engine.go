@@ -3,24 +3,31 @@ package assignment
 import (
 	"context"
 	"fmt"
+	"log"
 	"radiology-assignment/internal/models"
-	"sort"
+	"radiology-assignment/internal/normalize"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Engine struct {
-	db     DataStore
-	roster RosterService
-	rules  RulesService
+	db         DataStore
+	roster     RosterService
+	rules      RulesService
+	normalizer *normalize.Normalizer
+
+	plan   atomic.Pointer[rulePlan] // Latest compiled rules from a VersionedRulesService
+	planMu sync.Mutex               // Held while compiling, so a new version is compiled once
 }
 
 func NewEngine(db DataStore, roster RosterService, rules RulesService) *Engine {
 	return &Engine{
-		db:     db,
-		roster: roster,
-		rules:  rules,
+		db:         db,
+		roster:     roster,
+		rules:      rules,
+		normalizer: normalize.Default(),
 	}
 }
 
@@ -31,56 +38,91 @@ type candidate struct {
 }
 
 func (e *Engine) Assign(ctx context.Context, study *models.Study) (*models.Assignment, error) {
+	return e.assign(ctx, study, nil)
+}
+
+// AssignExplained assigns the study like Assign and also traces how the
+// decision was reached. The trace is kept on the saved assignment, and is
+// returned even when no assignment could be made.
+func (e *Engine) AssignExplained(ctx context.Context, study *models.Study) (*models.Assignment, *models.AssignmentTrace, error) {
+	tr := &models.AssignmentTrace{}
+	assignment, err := e.assign(ctx, study, tr)
+	if err != nil {
+		tr.Outcome = "failed: " + err.Error()
+	}
+	return assignment, tr, err
+}
+
+// assign runs the pipeline, recording each step in tr unless it is nil.
+func (e *Engine) assign(ctx context.Context, study *models.Study, tr *models.AssignmentTrace) (*models.Assignment, error) {
 	if study == nil {
 		return nil, fmt.Errorf("study cannot be nil")
 	}
 
-	// Step 1: Match shifts based on study characteristics
+	// Step 1: Normalize modality and body part, then match shifts
+	if err := e.normalize(ctx, study); err != nil {
+		return nil, err
+	}
 	shifts, err := e.matchShifts(ctx, study)
 	if err != nil {
 		return nil, err
 	}
+	traceShifts(tr, shifts)
 	if len(shifts) == 0 {
-		return nil, fmt.Errorf("no matching shifts for study %s", study.ID)
+		return nil, fmt.Errorf("%w: no matching shifts for study %s", ErrNoCandidate, study.ID)
 	}
 
 	// Step 2: Resolve radiologists from roster for matched shifts
-	candidates, err := e.resolveRadiologists(ctx, shifts)
+	candidates, excluded, err := e.resolveRadiologists(ctx, shifts, studyTime(study))
 	if err != nil {
 		return nil, err
 	}
+	for _, x := range excluded {
+		log.Printf("Study %s: excluded %s", study.ID, x)
+	}
+	traceResolved(tr, candidates, excluded)
 	if len(candidates) == 0 {
-		return nil, fmt.Errorf("no available radiologists for shifts")
+		if len(excluded) > 0 {
+			return nil, fmt.Errorf("%w: no available radiologists for shifts: %s", ErrNoCandidate, joinExclusions(excluded))
+		}
+		return nil, fmt.Errorf("%w: no available radiologists for shifts", ErrNoCandidate)
 	}
 
 	// Step 3: Apply rule-based assignment pipeline
-	selected, worklistTarget, escalated, err := e.evaluateRules(ctx, study, candidates)
+	d, err := e.evaluateRules(ctx, study, candidates, tr)
 	if err != nil {
 		return nil, err
 	}
 
-	if worklistTarget != "" {
-		return &models.Assignment{
-			StudyID:       study.ID,
-			RadiologistID: "WORKLIST",
-			ShiftID:       0,
-			AssignedAt:    study.IngestTime,
-			Escalated:     escalated,
-			Strategy:      worklistTarget,
-		}, nil
-	}
-
-	if selected == nil {
-		return nil, fmt.Errorf("no candidate selected after rule evaluation")
-	}
-
 	assignment := &models.Assignment{
-		StudyID:       study.ID,
-		RadiologistID: selected.Radiologist.ID,
-		ShiftID:       selected.ShiftID,
-		AssignedAt:    study.IngestTime, // Should be Now(), but using IngestTime for simplicity or mock it
-		Escalated:     escalated,
-		Strategy:      "load_balanced", // Default
+		StudyID:    study.ID,
+		AssignedAt: time.Now(),
+		Escalated:  d.escalated,
+		Strategy:   d.strategy(shifts),
+		RulesFired: d.fired,
+		Status:     models.StatusAssigned,
+		Trace:      tr,
+	}
+	if d.decidedBy != nil {
+		id := d.decidedBy.ID
+		assignment.RuleMatchedID = &id
+	}
+
+	if d.worklist != "" {
+		if tr != nil {
+			tr.Outcome = "sent to worklist " + d.worklist
+		}
+		assignment.RadiologistID = models.WorklistAssignee
+		assignment.Worklist = d.worklist
+	} else {
+		if d.selected == nil {
+			return nil, fmt.Errorf("%w: no candidate selected after rule evaluation", ErrNoCandidate)
+		}
+		assignment.RadiologistID = d.selected.Radiologist.ID
+		assignment.ShiftID = d.selected.ShiftID
+		if tr != nil {
+			tr.Outcome = fmt.Sprintf("assigned to %s on shift %d (%s)", d.selected.Radiologist.ID, d.selected.ShiftID, assignment.Strategy)
+		}
 	}
 
 	// Save assignment (optional step in logic flow, but good for completeness)
@@ -91,315 +133,353 @@ func (e *Engine) Assign(ctx context.Context, study *models.Study) (*models.Assig
 	return assignment, nil
 }
 
+// normalize resolves the study's procedure against the catalog, falling back
+// to the body part and attribute synonym tables. A study is only normalized
+// once so the result stays stable when it is re-processed.
+func (e *Engine) normalize(ctx context.Context, study *models.Study) error {
+	if study.NormalizationSource != "" {
+		return nil
+	}
+	var proc *models.Procedure
+	if study.ProcedureCode != "" {
+		var err error
+		if proc, err = e.db.GetProcedureByCode(ctx, study.ProcedureCode); err != nil {
+			return err
+		}
+	}
+	e.normalizer.Normalize(study, proc)
+
+	if study.NormalizationSource == normalize.SourceUnmapped {
+		// Queue for review but keep assigning; a catalog problem must not
+		// hold up the study
+		if err := e.db.RecordUnmappedProcedure(ctx, study); err != nil {
+			log.Printf("Failed to queue unmapped procedure %q for study %s: %v", study.ProcedureCode, study.ID, err)
+		}
+	}
+	return nil
+}
+
+// matchShifts finds shifts covering the study's modality, body part, urgency
+// and site that are running at the study's time. When the procedure could not
+// be normalized the body part is only the site's guess, so if it matches
+// nothing the study falls back to shifts that ignore the body part.
 func (e *Engine) matchShifts(ctx context.Context, study *models.Study) ([]*models.Shift, error) {
-	return e.db.GetShiftsByWorkType(ctx, study.Modality, study.BodyPart, study.Site)
+	at := studyTime(study)
+	q := models.ShiftQuery{Modality: study.Modality, BodyPart: study.BodyPart, Urgency: study.Urgency, Site: study.Site}
+	shifts, err := e.db.GetShiftsByWorkType(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	shifts = activeShifts(shifts, at)
+	if len(shifts) > 0 || study.NormalizationSource != normalize.SourceUnmapped || study.BodyPart == "" {
+		return shifts, nil
+	}
+	q.BodyPart = ""
+	shifts, err = e.db.GetShiftsByWorkType(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return activeShifts(shifts, at), nil
 }
 
-func (e *Engine) resolveRadiologists(ctx context.Context, shifts []*models.Shift) ([]*candidate, error) {
-	radShiftMap := make(map[string]int64)
+func activeShifts(shifts []*models.Shift, at time.Time) []*models.Shift {
+	var result []*models.Shift
+	for _, sh := range shifts {
+		if sh.ActiveAt(at) {
+			result = append(result, sh)
+		}
+	}
+	return result
+}
+
+// studyTime is the moment shifts and the roster are read at: when the exam
+// was performed, or now for studies that carry no time at all.
+func studyTime(study *models.Study) time.Time {
+	if t := study.GetExamTime(); !t.IsZero() {
+		return t
+	}
+	return time.Now()
+}
+
+// resolveRadiologists collects the radiologists rostered to the shifts at the
+// given time who are eligible to work them and below capacity. A radiologist
+// on several shifts is credited to the first they are eligible for; every
+// rostered radiologist passed over is returned as an exclusion.
+func (e *Engine) resolveRadiologists(ctx context.Context, shifts []*models.Shift, at time.Time) ([]*candidate, []Exclusion, error) {
+	type rostered struct {
+		shift *models.Shift
+		radID string
+	}
+	var entries []rostered
+	seen := make(map[string]bool)
 	var uniqueIDs []string
 
 	for _, shift := range shifts {
-		entries := e.roster.GetByShift(shift.ID)
-		for _, entry := range entries {
-			if _, exists := radShiftMap[entry.RadiologistID]; !exists {
-				radShiftMap[entry.RadiologistID] = shift.ID
+		for _, entry := range e.roster.GetByShift(shift.ID) {
+			if !entry.EffectiveAt(at) {
+				continue
+			}
+			entries = append(entries, rostered{shift: shift, radID: entry.RadiologistID})
+			if !seen[entry.RadiologistID] {
+				seen[entry.RadiologistID] = true
 				uniqueIDs = append(uniqueIDs, entry.RadiologistID)
 			}
 		}
 	}
 
 	if len(uniqueIDs) == 0 {
-		return []*candidate{}, nil
+		return []*candidate{}, nil, nil
 	}
 
 	radiologists, err := e.db.GetRadiologists(ctx, uniqueIDs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	byID := make(map[string]*models.Radiologist, len(radiologists))
+	for _, rad := range radiologists {
+		byID[rad.ID] = rad
 	}
 
 	var result []*candidate
-	for _, rad := range radiologists {
-		if rad.Status != "active" {
+	var excluded []Exclusion
+	chosen := make(map[string]bool)
+	for _, r := range entries {
+		if chosen[r.radID] {
 			continue
 		}
-
-		shiftID, ok := radShiftMap[rad.ID]
+		rad, ok := byID[r.radID]
 		if !ok {
+			excluded = append(excluded, Exclusion{RadiologistID: r.radID, ShiftID: r.shift.ID, Step: stepEligibility, Reason: "radiologist not found"})
 			continue
 		}
-
+		if reason := IneligibleReason(r.shift, rad); reason != "" {
+			excluded = append(excluded, Exclusion{RadiologistID: r.radID, ShiftID: r.shift.ID, Step: stepEligibility, Reason: reason})
+			continue
+		}
+		chosen[r.radID] = true
 		result = append(result, &candidate{
 			Radiologist: rad,
-			ShiftID:     shiftID,
+			ShiftID:     r.shift.ID,
 		})
 	}
 
-	return result, nil
+	available, err := e.filterByCapacity(ctx, result)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(available) < len(result) {
+		kept := make(map[*candidate]bool, len(available))
+		for _, c := range available {
+			kept[c] = true
+		}
+		for _, c := range result {
+			if !kept[c] {
+				excluded = append(excluded, Exclusion{RadiologistID: c.Radiologist.ID, ShiftID: c.ShiftID, Step: stepCapacity, Reason: "radiologist is at capacity"})
+			}
+		}
+	}
+
+	return available, excluded, nil
 }
 
-func (e *Engine) evaluateRules(ctx context.Context, study *models.Study, candidates []*candidate) (*candidate, string, bool, error) {
-	rules := e.rules.GetActive()
+// decision is what the rules made of the candidates.
+type decision struct {
+	selected  *candidate
+	worklist  string
+	escalated bool
+	fired     []int64                // IDs of the rules that matched, in order
+	decidedBy *models.AssignmentRule // The rule that chose the assignee, if any
+}
 
-	// Sort rules by priority (lower number = higher priority)
-	sort.Slice(rules, func(i, j int) bool {
-		return rules[i].PriorityOrder < rules[j].PriorityOrder
-	})
+// strategy names how the assignee was reached. shifts are the matched shifts,
+// highest priority first.
+func (d *decision) strategy(shifts []*models.Shift) string {
+	switch {
+	case d.worklist != "":
+		return models.StrategyWorklist
+	case d.escalated:
+		return models.StrategyEscalated
+	case d.decidedBy != nil:
+		return models.StrategySpecialArrangement
+	}
+	if d.selected != nil {
+		for _, sh := range shifts {
+			if sh.ID == d.selected.ShiftID && sh.PriorityLevel < shifts[0].PriorityLevel {
+				return models.StrategyOverflow
+			}
+		}
+	}
+	return models.StrategyShiftPrimary
+}
 
+func (e *Engine) evaluateRules(ctx context.Context, study *models.Study, candidates []*candidate, tr *models.AssignmentTrace) (*decision, error) {
+	plan := e.rulePlan()
+	// Explained, every rule is evaluated so the trace shows why each failed
+	var positions []int
+	if tr == nil {
+		positions = plan.candidates(study)
+	} else {
+		positions = make([]int, len(plan.rules))
+		for i := range positions {
+			positions[i] = i
+		}
+	}
+
+	d := &decision{}
 	currentCandidates := candidates
-	isEscalated := false
-	var worklistTarget string
-	var matchedRule *models.AssignmentRule
 
-	for _, rule := range rules {
-		if !e.ruleMatches(rule, study) {
-			continue
+	for _, pos := range positions {
+		compiled := plan.rules[pos]
+		rule := compiled.rule
+		var traced *models.TraceRule
+		var conditions *[]models.TraceCondition
+		if tr != nil {
+			tr.Rules = append(tr.Rules, models.TraceRule{ID: rule.ID, Name: rule.Name, ActionType: rule.ActionType})
+			traced = &tr.Rules[len(tr.Rules)-1]
+			conditions = &traced.Conditions
+		}
+		note := func(msg string) {
+			if traced != nil {
+				traced.Note = msg
+			}
 		}
 
-		matchedRule = rule // Keep track of last matched rule
+		// A fallback only comes into play once the pool is empty, and then
+		// starts again from every resolved candidate
+		pool := currentCandidates
+		if rule.ChainMode() == models.RuleChainFallback {
+			if len(currentCandidates) > 0 {
+				note("fallback not needed, candidates remain")
+				continue
+			}
+			pool = candidates
+		}
 
+		matched := evalConditions(compiled.conditions, compiled.err, study, conditions)
+		if traced != nil {
+			traced.Matched = matched
+		}
+		if !matched {
+			continue
+		}
+
+		next := pool
+		var reason string
+		decides := false
 		switch rule.ActionType {
 		case "FILTER_COMPETENCY":
 			// Simple implementation: Filter candidates who have credentials matching study.Modality
-			currentCandidates = e.filterByCompetency(currentCandidates, study.Modality)
+			next = e.filterByCompetency(pool, study.Modality)
+			reason = "lacks credential " + study.Modality
 
 		case "ASSIGN_TO_RADIOLOGIST":
 			// Filter specifically for this radiologist
 			if target := rule.ActionTarget; target != "" {
-				currentCandidates = e.filterByRadiologistID(currentCandidates, target)
+				next = e.filterByRadiologistID(pool, target)
+				reason = "rule assigns to radiologist " + target
+				decides = true
 			}
 
 		case "ASSIGN_TO_SHIFT":
 			if target := rule.ActionTarget; target != "" {
 				shiftID, err := strconv.ParseInt(target, 10, 64)
 				if err == nil {
-					currentCandidates = e.filterByShiftID(currentCandidates, shiftID)
+					next = e.filterByShiftID(pool, shiftID)
+					reason = "rule assigns to shift " + target
+					decides = true
 				}
 			}
 
 		case "ASSIGN_TO_WORKLIST":
-			worklistTarget = rule.ActionTarget
-			// If assigned to worklist, we stop processing candidates and return immediately
-			return nil, worklistTarget, isEscalated, nil
+			// Sending the study to a worklist ends evaluation whatever the chain
+			d.fired = append(d.fired, rule.ID)
+			d.worklist = rule.ActionTarget
+			d.decidedBy = rule
+			return d, nil
 
 		case "ESCALATE":
-			isEscalated = true
+			d.escalated = true
+			if d.decidedBy == nil {
+				d.decidedBy = rule
+			}
 		}
-	}
 
-	// Filter by Capacity
-	// If escalated, maybe we ignore capacity? Requirements say "Reassign to available radiologists with higher priority".
-	// But let's assume capacity limits still apply unless forced.
-	// FR-4.6.3: "respect maximum concurrent study limits per radiologist if configured"
-	// Let's filter by capacity.
-	currentCandidates, err := e.filterByCapacity(ctx, currentCandidates)
-	if err != nil {
-		return nil, "", false, err
+		if rule.Soft && len(next) == 0 && len(pool) > 0 {
+			note("soft rule skipped, it would leave no candidates")
+			continue
+		}
+		d.fired = append(d.fired, rule.ID)
+		traceFiltered(tr, rule, pool, next, reason)
+		currentCandidates = next
+		if decides {
+			d.decidedBy = rule
+		}
+
+		if rule.ChainMode() == models.RuleChainTerminal {
+			note("terminal rule, later rules not evaluated")
+			break
+		}
 	}
 
+	// Capacity (FR-4.6.3) was applied when the candidates were resolved
 	if len(currentCandidates) == 0 {
-		return nil, "", isEscalated, nil
+		return d, nil
 	}
 
 	// Load Balance
 	selected, err := e.loadBalance(ctx, currentCandidates)
 	if err != nil {
-		return nil, "", false, err
+		return nil, err
 	}
+	traceScores(tr, currentCandidates, selected)
+	d.selected = selected
 
-	// If we had a matched rule, we might note it in the assignment (not added to return yet)
-	_ = matchedRule
-
-	return selected, "", isEscalated, nil
+	return d, nil
 }
 
-func (e *Engine) ruleMatches(rule *models.AssignmentRule, study *models.Study) bool {
-	// Matcher based on ConditionFilters map
-	filters := rule.ConditionFilters
-
-	// 1. Min Age (Wait time)
-	if val, ok := filters["min_age_minutes"]; ok {
-		var minAge float64
-		switch v := val.(type) {
-		case int:
-			minAge = float64(v)
-		case float64:
-			minAge = v
-		case int64:
-			minAge = float64(v)
-		}
-
-		age := e.calculateStudyAgeMinutes(study)
-		if age <= minAge {
-			return false
-		}
-	}
-
-	// 2. Urgency
-	if val, ok := filters["urgency"]; ok {
-		if study.Urgency != val.(string) {
-			return false
-		}
-	}
-
-	// 3. Procedure Code
-	if val, ok := filters["procedure_code"]; ok {
-		if study.ProcedureCode != val.(string) {
-			return false
-		}
-	}
-
-	// 4. Body Part
-	if val, ok := filters["body_part"]; ok {
-		if study.BodyPart != val.(string) {
-			return false
-		}
-	}
-
-	// 5. Ordering Physician
-	if val, ok := filters["ordering_physician"]; ok {
-		if study.OrderingPhysician != val.(string) {
-			return false
-		}
-	}
-
-	// 6. Site
-	if val, ok := filters["site"]; ok {
-		if study.Site != val.(string) {
-			return false
-		}
-	}
-
-	// 7. Patient Age Range
-	if val, ok := filters["patient_age_min"]; ok {
-		min := toFloat(val)
-		if float64(study.PatientAge) < min {
-			return false
-		}
-	}
-	if val, ok := filters["patient_age_max"]; ok {
-		max := toFloat(val)
-		if float64(study.PatientAge) > max {
-			return false
-		}
-	}
-
-	// 8. Exam Time Range (HH:MM-HH:MM)
-	if val, ok := filters["exam_time_range"]; ok {
-		if !e.matchesTimeRange(study.GetExamTime(), val.(string)) {
-			return false
-		}
-	}
-
-	// 9. Day of Week
-	if val, ok := filters["days_of_week"]; ok {
-		if !e.matchesDayOfWeek(study.GetExamTime(), val) {
-			return false
-		}
-	}
-
-	// 10. Procedure Description
-	if val, ok := filters["procedure_description"]; ok {
-		if study.ProcedureDescription != val.(string) {
-			return false
-		}
-	}
-
-	// 11. Prior Location
-	if val, ok := filters["prior_location"]; ok {
-		if study.PriorLocation != val.(string) {
-			return false
-		}
-	}
-
-	// 12. Technician
-	if val, ok := filters["technician"]; ok {
-		if study.Technician != val.(string) {
-			return false
-		}
-	}
-
-	// 13. Transcriptionist
-	if val, ok := filters["transcriptionist"]; ok {
-		if study.Transcriptionist != val.(string) {
-			return false
+// evalConditions tests a rule's parsed conditions, or reports parseErr when
+// they could not be parsed. With a trace every condition is checked and
+// recorded; without one the first failure ends the check.
+func evalConditions(conditions *models.ConditionGroup, parseErr error, study *models.Study, trace *[]models.TraceCondition) bool {
+	if parseErr != nil {
+		if trace != nil {
+			*trace = append(*trace, models.TraceCondition{Field: "condition_filters", Expected: parseErr.Error()})
 		}
-	}
-
-	return true
-}
-
-func toFloat(val interface{}) float64 {
-	switch v := val.(type) {
-	case int:
-		return float64(v)
-	case float64:
-		return v
-	case int64:
-		return float64(v)
-	}
-	return 0
-}
-
-func (e *Engine) matchesTimeRange(t time.Time, rangeStr string) bool {
-	parts := strings.Split(rangeStr, "-")
-	if len(parts) != 2 {
-		return false
-	}
-	startStr, endStr := parts[0], parts[1]
-
-	layout := "15:04"
-	start, err := time.Parse(layout, startStr)
-	if err != nil {
-		return false
-	}
-	end, err := time.Parse(layout, endStr)
-	if err != nil {
 		return false
 	}
 
-	// Normalize t to just time part for comparison
-	currentStr := t.Format(layout)
-	current, _ := time.Parse(layout, currentStr)
-
-	// Handle overnight ranges e.g. 22:00-06:00
-	if end.Before(start) {
-		return !current.Before(start) || !current.After(end)
+	var visit func(g *models.ConditionGroup, c *models.Condition, actual interface{}, passed bool)
+	if trace != nil {
+		visit = func(g *models.ConditionGroup, c *models.Condition, actual interface{}, passed bool) {
+			*trace = append(*trace, models.TraceCondition{
+				Group: g.Path, Field: c.Key, Op: c.Op, Expected: c.Operand, Actual: actual, Passed: passed,
+			})
+		}
 	}
-
-	return (current.Equal(start) || current.After(start)) && (current.Equal(end) || current.Before(end))
+	return conditions.Eval(study, time.Now(), visit)
 }
 
-func (e *Engine) matchesDayOfWeek(t time.Time, daysVal interface{}) bool {
-	day := t.Weekday().String() // "Monday", "Tuesday", etc.
-	// Allow 3-letter abbreviations too? Let's check against what's provided.
-
-	var allowedDays []string
-	switch v := daysVal.(type) {
-	case []string:
-		allowedDays = v
-	case []interface{}:
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				allowedDays = append(allowedDays, s)
-			}
-		}
+// rulePlan returns the active rules compiled for evaluation. Rules from a
+// VersionedRulesService are compiled once per version and the plan swapped
+// in atomically; anything else is compiled on every call.
+func (e *Engine) rulePlan() *rulePlan {
+	versioned, ok := e.rules.(VersionedRulesService)
+	if !ok {
+		return compilePlan(e.rules.GetActive(), 0)
 	}
-
-	for _, d := range allowedDays {
-		if strings.EqualFold(d, day) || strings.EqualFold(d, day[:3]) {
-			return true
-		}
+	rules, version := versioned.GetActiveVersion()
+	if p := e.plan.Load(); p != nil && p.version == version {
+		return p
 	}
-	return false
-}
 
-func (e *Engine) calculateStudyAgeMinutes(study *models.Study) float64 {
-	// Use IngestTime if available
-	if !study.IngestTime.IsZero() {
-		return time.Since(study.IngestTime).Minutes()
+	e.planMu.Lock()
+	defer e.planMu.Unlock()
+	if p := e.plan.Load(); p != nil && p.version >= version {
+		return p
 	}
-	return 0
+	p := compilePlan(rules, version)
+	e.plan.Store(p)
+	return p
 }
 
 func (e *Engine) filterByCompetency(candidates []*candidate, requiredCredential string) []*candidate {
@@ -458,7 +538,7 @@ func (e *Engine) filterByCapacity(ctx context.Context, candidates []*candidate)
 	for _, c := range candidates {
 		load := workloads[c.Radiologist.ID]
 		c.CurrentLoad = load
-		if int(load) < c.Radiologist.MaxConcurrentStudies || c.Radiologist.MaxConcurrentStudies == 0 {
+		if c.Radiologist.CurrentAvailability(load) != models.AvailabilityAtCapacity {
 			filtered = append(filtered, c)
 		}
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"radiology-assignment/internal/models"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,7 +19,7 @@ func setupEngine(t testing.TB, shifts []*models.Shift, radiologists []*models.Ra
 	}
 
 	mockDB := &MockDataStore{
-		GetShiftsByWorkTypeFunc: func(ctx context.Context, mod, body, site string) ([]*models.Shift, error) {
+		GetShiftsByWorkTypeFunc: func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
 			return shifts, nil
 		},
 		GetRadiologistFunc: func(ctx context.Context, id string) (*models.Radiologist, error) {
@@ -291,3 +293,510 @@ func TestAssign_NoAvailableRadiologists(t *testing.T) {
 		t.Fatal("Expected error when no radiologists found")
 	}
 }
+
+func TestAssign_NormalizesBeforeShiftMatching(t *testing.T) {
+	tests := []struct {
+		name         string
+		study        *models.Study
+		wantBodyPart string
+		wantSource   string
+	}{
+		{
+			name:         "Catalog",
+			study:        &models.Study{ID: "s1", ProcedureCode: "MRKNEE", ProcedureDescription: "MRI left knee", Modality: "MRI"},
+			wantBodyPart: "Knee",
+			wantSource:   "catalog",
+		},
+		{
+			name:         "Synonym",
+			study:        &models.Study{ID: "s2", ProcedureCode: "US99", ProcedureDescription: "US ABDOEMN", Modality: "US"},
+			wantBodyPart: "ABDOMEN",
+			wantSource:   "description",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shift := &models.Shift{ID: 1}
+			rad := &models.Radiologist{ID: "rad1", Status: "active"}
+			engine := setupEngine(t, []*models.Shift{shift}, []*models.Radiologist{rad}, map[int64][]string{1: {"rad1"}}, nil)
+
+			var matchedBodyPart string
+			mockDB := engine.db.(*MockDataStore)
+			mockDB.GetProcedureByCodeFunc = func(ctx context.Context, code string) (*models.Procedure, error) {
+				if code == "MRKNEE" {
+					return &models.Procedure{Code: code, Modality: "MRI", BodyPart: "Knee"}, nil
+				}
+				return nil, nil
+			}
+			mockDB.GetShiftsByWorkTypeFunc = func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+				matchedBodyPart = q.BodyPart
+				return []*models.Shift{shift}, nil
+			}
+
+			if _, err := engine.Assign(context.Background(), tt.study); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if matchedBodyPart != tt.wantBodyPart {
+				t.Errorf("Expected shifts matched on %q, got %q", tt.wantBodyPart, matchedBodyPart)
+			}
+			if tt.study.NormalizationSource != tt.wantSource {
+				t.Errorf("Expected source %q, got %q", tt.wantSource, tt.study.NormalizationSource)
+			}
+		})
+	}
+}
+
+func TestAssign_UnmappedProcedureFallsBackToModality(t *testing.T) {
+	shift := &models.Shift{ID: 1, WorkType: "CT"}
+	rad := &models.Radiologist{ID: "rad1", Status: "active"}
+	engine := setupEngine(t, []*models.Shift{shift}, []*models.Radiologist{rad}, map[int64][]string{1: {"rad1"}}, nil)
+
+	var queued []*models.Study
+	var lookups []string
+	mockDB := engine.db.(*MockDataStore)
+	mockDB.RecordUnmappedProcedureFunc = func(ctx context.Context, s *models.Study) error {
+		queued = append(queued, s)
+		return nil
+	}
+	mockDB.GetShiftsByWorkTypeFunc = func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+		lookups = append(lookups, q.Modality+"/"+q.BodyPart)
+		if q.BodyPart == "" {
+			return []*models.Shift{shift}, nil
+		}
+		return nil, nil
+	}
+
+	study := &models.Study{ID: "s1", ProcedureCode: "CT NEWPROTO", ProcedureDescription: "Research protocol 7", Modality: "CT", BodyPart: "NEWPROTO"}
+	assignment, err := engine.Assign(context.Background(), study)
+	if err != nil {
+		t.Fatalf("Expected unmapped study to be assigned, got %v", err)
+	}
+	if assignment.RadiologistID != "rad1" {
+		t.Errorf("Expected rad1, got %s", assignment.RadiologistID)
+	}
+	if len(queued) != 1 || queued[0].ProcedureCode != "CT NEWPROTO" {
+		t.Errorf("Expected procedure to be queued for review, got %v", queued)
+	}
+	if want := []string{"CT/NEWPROTO", "CT/"}; !reflect.DeepEqual(lookups, want) {
+		t.Errorf("Expected shift lookups %v, got %v", want, lookups)
+	}
+}
+
+func TestAssign_RosterEffectiveDates(t *testing.T) {
+	// Exam performed 10 Oct 2023 at 09:00
+	examTime := "20231010090000"
+	day := func(d int) time.Time { return time.Date(2023, 10, d, 0, 0, 0, 0, time.Local) }
+	dayPtr := func(d int) *time.Time { t := day(d); return &t }
+
+	tests := []struct {
+		name      string
+		entry     models.RosterEntry
+		timestamp string
+		rostered  bool
+	}{
+		{"open-ended from an earlier day", models.RosterEntry{StartDate: day(1)}, examTime, true},
+		{"no dates at all", models.RosterEntry{}, examTime, true},
+		{"starts later the same day", models.RosterEntry{StartDate: day(10).Add(15 * time.Hour)}, examTime, true},
+		{"ends on the exam day", models.RosterEntry{StartDate: day(1), EndDate: dayPtr(10)}, examTime, true},
+		{"expired the day before", models.RosterEntry{StartDate: day(1), EndDate: dayPtr(9)}, examTime, false},
+		{"starts the next day", models.RosterEntry{StartDate: day(11)}, examTime, false},
+		{"inactive within its window", models.RosterEntry{StartDate: day(1), Status: models.RosterStatusInactive}, examTime, false},
+		{"active within its window", models.RosterEntry{StartDate: day(1), EndDate: dayPtr(31), Status: models.RosterStatusActive}, examTime, true},
+		{"expired entry without an exam time uses now", models.RosterEntry{StartDate: day(1), EndDate: dayPtr(9)}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shift := &models.Shift{ID: 1}
+			rad := &models.Radiologist{ID: "rad1", Status: "active"}
+			engine := setupEngine(t, []*models.Shift{shift}, []*models.Radiologist{rad}, nil, nil)
+			engine.roster = &MockRosterService{
+				GetByShiftFunc: func(shiftID int64) []*models.RosterEntry {
+					entry := tt.entry
+					entry.ShiftID, entry.RadiologistID = shiftID, "rad1"
+					return []*models.RosterEntry{&entry}
+				},
+			}
+
+			study := &models.Study{ID: "study1", Timestamp: tt.timestamp}
+			assignment, err := engine.Assign(context.Background(), study)
+			if tt.rostered {
+				if err != nil {
+					t.Fatalf("Expected rad1 to be rostered, got %v", err)
+				}
+				if assignment.RadiologistID != "rad1" {
+					t.Errorf("Expected rad1, got %s", assignment.RadiologistID)
+				}
+			} else if err == nil {
+				t.Errorf("Expected no rostered radiologist, got %s", assignment.RadiologistID)
+			}
+		})
+	}
+}
+
+func TestAssign_ShiftOperatingHours(t *testing.T) {
+	brisbane, err := time.LoadLocation("Australia/Brisbane")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 9 and 10 Oct 2023 are a Monday and a Tuesday
+	at := func(day, hour, min int) string {
+		return time.Date(2023, 10, day, hour, min, 0, 0, time.Local).Format("20060102150405")
+	}
+	brisbaneAt := func(day, hour int) string {
+		return time.Date(2023, 10, day, hour, 0, 0, 0, brisbane).In(time.Local).Format("20060102150405")
+	}
+
+	tests := []struct {
+		name      string
+		shift     models.Shift
+		timestamp string
+		active    bool
+	}{
+		{"no hours runs all day", models.Shift{}, at(10, 3, 0), true},
+		{"within day hours", models.Shift{StartTime: "07:00", EndTime: "19:00"}, at(10, 7, 0), true},
+		{"end is exclusive", models.Shift{StartTime: "07:00", EndTime: "19:00"}, at(10, 19, 0), false},
+		{"before day hours", models.Shift{StartTime: "07:00", EndTime: "19:00"}, at(10, 6, 59), false},
+		{"overnight before midnight", models.Shift{StartTime: "19:00", EndTime: "07:00"}, at(10, 23, 0), true},
+		{"overnight after midnight", models.Shift{StartTime: "19:00", EndTime: "07:00"}, at(10, 5, 0), true},
+		{"overnight gap", models.Shift{StartTime: "19:00", EndTime: "07:00"}, at(10, 12, 0), false},
+		{"weekday only on a Monday", models.Shift{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}}, at(9, 12, 0), true},
+		{"weekend only on a Monday", models.Shift{Days: []string{"Sat", "Sun"}}, at(9, 12, 0), false},
+		{"Sunday night shift on Monday morning", models.Shift{StartTime: "22:00", EndTime: "06:00", Days: []string{"Sun"}}, at(9, 2, 0), true},
+		{"Sunday night shift on Monday night", models.Shift{StartTime: "22:00", EndTime: "06:00", Days: []string{"Sun"}}, at(9, 23, 0), false},
+		{"time zone inside hours", models.Shift{StartTime: "08:00", EndTime: "17:00", TimeZone: "Australia/Brisbane"}, brisbaneAt(10, 9), true},
+		{"time zone outside hours", models.Shift{StartTime: "08:00", EndTime: "17:00", TimeZone: "Australia/Brisbane"}, brisbaneAt(10, 18), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shift := tt.shift
+			shift.ID = 1
+			rad := &models.Radiologist{ID: "rad1", Status: "active"}
+			engine := setupEngine(t, []*models.Shift{&shift}, []*models.Radiologist{rad}, map[int64][]string{1: {"rad1"}}, nil)
+
+			_, err := engine.Assign(context.Background(), &models.Study{ID: "study1", Timestamp: tt.timestamp})
+			if tt.active && err != nil {
+				t.Errorf("Expected the shift to be running, got %v", err)
+			}
+			if !tt.active && err == nil {
+				t.Error("Expected no running shift")
+			}
+		})
+	}
+}
+
+func TestAssign_RequiredCredentials(t *testing.T) {
+	neuro := &models.Shift{ID: 1, RequiredCredentials: []string{"Neuro"}, PriorityLevel: 2}
+	general := &models.Shift{ID: 2, PriorityLevel: 1}
+
+	tests := []struct {
+		name      string
+		rad       *models.Radiologist
+		shifts    []*models.Shift
+		wantShift int64
+		wantErr   string
+	}{
+		{"holds the credential", &models.Radiologist{ID: "rad1", Status: "active", Credentials: []string{"neuro"}}, []*models.Shift{neuro}, 1, ""},
+		{"missing the credential", &models.Radiologist{ID: "rad1", Status: "active", Credentials: []string{"CT"}}, []*models.Shift{neuro}, 0, "rad1 on shift 1: missing credentials Neuro"},
+		{"credited to the next eligible shift", &models.Radiologist{ID: "rad1", Status: "active"}, []*models.Shift{neuro, general}, 2, ""},
+		{"inactive", &models.Radiologist{ID: "rad1", Status: "on_leave", Credentials: []string{"Neuro"}}, []*models.Shift{neuro}, 0, "rad1 on shift 1: radiologist is on_leave"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roster := map[int64][]string{1: {"rad1"}, 2: {"rad1"}}
+			engine := setupEngine(t, tt.shifts, []*models.Radiologist{tt.rad}, roster, nil)
+
+			assignment, err := engine.Assign(context.Background(), &models.Study{ID: "study1"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if assignment.ShiftID != tt.wantShift {
+				t.Errorf("Expected shift %d, got %d", tt.wantShift, assignment.ShiftID)
+			}
+		})
+	}
+}
+
+func TestAssign_Availability(t *testing.T) {
+	tests := []struct {
+		name    string
+		rad     models.Radiologist
+		load    int64
+		wantErr string
+	}{
+		{"never set", models.Radiologist{}, 0, ""},
+		{"on shift", models.Radiologist{Availability: models.AvailabilityOnShift}, 0, ""},
+		{"offline", models.Radiologist{Availability: models.AvailabilityOffline}, 0, "radiologist availability is offline"},
+		{"on a break", models.Radiologist{Availability: models.AvailabilityBreak}, 0, "radiologist availability is break"},
+		{"below capacity", models.Radiologist{MaxConcurrentStudies: 2}, 1, ""},
+		{"at capacity", models.Radiologist{MaxConcurrentStudies: 2}, 2, "radiologist is at capacity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rad := tt.rad
+			rad.ID = "rad1"
+			rad.Status = "active"
+			engine := setupEngine(t, []*models.Shift{{ID: 1}}, []*models.Radiologist{&rad}, map[int64][]string{1: {"rad1"}}, nil)
+			engine.db.(*MockDataStore).GetRadiologistCurrentWorkloadFunc = func(ctx context.Context, id string) (int64, error) {
+				return tt.load, nil
+			}
+
+			_, err := engine.Assign(context.Background(), &models.Study{ID: "study1"})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected an assignment, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAssignExplained_Trace(t *testing.T) {
+	study := &models.Study{ID: "study1", Modality: "CT", Urgency: "STAT"}
+	shift := &models.Shift{ID: 1, Name: "CT", RequiredCredentials: []string{"CT"}}
+	rads := []*models.Radiologist{
+		{ID: "rad1", Status: "active", Credentials: []string{"CT"}},
+		{ID: "rad2", Status: "active", Credentials: []string{"CT"}},
+		{ID: "rad3", Status: "active"},
+	}
+	rules := []*models.AssignmentRule{
+		{ID: 1, Name: "Routine only", ConditionFilters: map[string]interface{}{"urgency": "ROUTINE"}, ActionType: "ESCALATE", PriorityOrder: 1},
+		{ID: 2, Name: "STAT to rad2", ConditionFilters: map[string]interface{}{"urgency": "STAT"}, ActionType: "ASSIGN_TO_RADIOLOGIST", ActionTarget: "rad2", PriorityOrder: 2},
+	}
+	engine := setupEngine(t, []*models.Shift{shift}, rads, map[int64][]string{1: {"rad1", "rad2", "rad3"}}, rules)
+
+	assignment, trace, err := engine.AssignExplained(context.Background(), study)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if assignment.Trace != trace {
+		t.Error("Expected the trace to be kept on the assignment")
+	}
+	if len(trace.Shifts) != 1 || trace.Shifts[0].ID != 1 {
+		t.Errorf("Expected shift 1 to be matched, got %+v", trace.Shifts)
+	}
+	if len(trace.Candidates) != 2 {
+		t.Errorf("Expected rad1 and rad2 as candidates, got %+v", trace.Candidates)
+	}
+	if len(trace.Rules) != 2 || trace.Rules[0].Matched || !trace.Rules[1].Matched {
+		t.Fatalf("Expected the first rule to fail and the second to match, got %+v", trace.Rules)
+	}
+	if c := trace.Rules[0].Conditions; len(c) != 1 || c[0].Field != "urgency" || c[0].Actual != "STAT" || c[0].Passed {
+		t.Errorf("Expected a failed urgency condition, got %+v", c)
+	}
+
+	removed := make(map[string]string)
+	for _, r := range trace.Removed {
+		removed[r.RadiologistID] = r.Step
+	}
+	want := map[string]string{"rad3": "eligibility", "rad1": "ASSIGN_TO_RADIOLOGIST"}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("Expected removals %v, got %v", want, removed)
+	}
+	if len(trace.Scores) != 1 || !trace.Scores[0].Selected || trace.Scores[0].RadiologistID != "rad2" {
+		t.Errorf("Expected rad2 to be scored and selected, got %+v", trace.Scores)
+	}
+	if trace.Outcome != "assigned to rad2 on shift 1 (special_arrangement)" {
+		t.Errorf("Expected the outcome to name rad2, got %q", trace.Outcome)
+	}
+
+	// Without explain mode nothing is traced
+	assignment, err = engine.Assign(context.Background(), study)
+	if err != nil || assignment.Trace != nil {
+		t.Errorf("Expected an untraced assignment, got %+v, %v", assignment, err)
+	}
+}
+
+func TestAssignExplained_WorklistTraceIsSaved(t *testing.T) {
+	shift := &models.Shift{ID: 1}
+	rads := []*models.Radiologist{{ID: "rad1", Status: "active"}}
+	rules := []*models.AssignmentRule{
+		{ID: 1, Name: "Neuro worklist", ActionType: "ASSIGN_TO_WORKLIST", ActionTarget: "neuro", PriorityOrder: 1},
+	}
+	engine := setupEngine(t, []*models.Shift{shift}, rads, map[int64][]string{1: {"rad1"}}, rules)
+	var saved *models.Assignment
+	engine.db.(*MockDataStore).SaveAssignmentFunc = func(ctx context.Context, a *models.Assignment) error {
+		saved = a
+		return nil
+	}
+
+	assignment, trace, err := engine.AssignExplained(context.Background(), &models.Study{ID: "study1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if saved == nil || saved != assignment {
+		t.Fatalf("Expected the worklist assignment to be saved, got %+v", saved)
+	}
+	if saved.Trace != trace {
+		t.Error("Expected the trace to be saved with the assignment")
+	}
+	if trace.Outcome != "sent to worklist neuro" {
+		t.Errorf("Expected the outcome to name the worklist, got %q", trace.Outcome)
+	}
+}
+
+func TestAssign_StrategyAndRulesFired(t *testing.T) {
+	primary := &models.Shift{ID: 1, PriorityLevel: 10}
+	overflow := &models.Shift{ID: 2, PriorityLevel: 1}
+	rads := []*models.Radiologist{
+		{ID: "rad1", Status: "active", MaxConcurrentStudies: 1},
+		{ID: "rad2", Status: "active"},
+	}
+	roster := map[int64][]string{1: {"rad1"}, 2: {"rad2"}}
+	alert := &models.AssignmentRule{ID: 1, ActionType: "SOFT_ALERT", PriorityOrder: 1}
+
+	tests := []struct {
+		name         string
+		rules        []*models.AssignmentRule
+		rad1Load     int64
+		wantStrategy string
+		wantRule     int64 // 0 for none
+		wantFired    []int64
+		wantAssignee string
+	}{
+		{"primary shift", []*models.AssignmentRule{alert}, 0, models.StrategyShiftPrimary, 0, []int64{1}, "rad1"},
+		{"overflow", nil, 1, models.StrategyOverflow, 0, nil, "rad2"},
+		{"special arrangement", []*models.AssignmentRule{
+			alert,
+			{ID: 2, ActionType: "ASSIGN_TO_RADIOLOGIST", ActionTarget: "rad2", PriorityOrder: 2},
+		}, 0, models.StrategySpecialArrangement, 2, []int64{1, 2}, "rad2"},
+		{"escalated", []*models.AssignmentRule{
+			{ID: 3, ActionType: "ESCALATE", PriorityOrder: 1},
+			{ID: 4, ActionType: "ASSIGN_TO_SHIFT", ActionTarget: "1", PriorityOrder: 2},
+		}, 0, models.StrategyEscalated, 4, []int64{3, 4}, "rad1"},
+		{"worklist", []*models.AssignmentRule{
+			alert,
+			{ID: 5, ActionType: "ASSIGN_TO_WORKLIST", ActionTarget: "neuro", PriorityOrder: 2},
+		}, 0, models.StrategyWorklist, 5, []int64{1, 5}, models.WorklistAssignee},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := setupEngine(t, []*models.Shift{primary, overflow}, rads, roster, tt.rules)
+			load := tt.rad1Load
+			engine.db.(*MockDataStore).GetRadiologistCurrentWorkloadFunc = func(ctx context.Context, id string) (int64, error) {
+				if id == "rad1" {
+					return load, nil
+				}
+				return 0, nil
+			}
+			var saved *models.Assignment
+			engine.db.(*MockDataStore).SaveAssignmentFunc = func(ctx context.Context, a *models.Assignment) error {
+				saved = a
+				return nil
+			}
+
+			assignment, err := engine.Assign(context.Background(), &models.Study{ID: "study1"})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if saved != assignment {
+				t.Errorf("Expected the assignment to be saved, got %+v", saved)
+			}
+			if assignment.Strategy != tt.wantStrategy {
+				t.Errorf("Expected strategy %s, got %s", tt.wantStrategy, assignment.Strategy)
+			}
+			if assignment.RadiologistID != tt.wantAssignee {
+				t.Errorf("Expected %s, got %s", tt.wantAssignee, assignment.RadiologistID)
+			}
+			var gotRule int64
+			if assignment.RuleMatchedID != nil {
+				gotRule = *assignment.RuleMatchedID
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("Expected deciding rule %d, got %d", tt.wantRule, gotRule)
+			}
+			if !reflect.DeepEqual(assignment.RulesFired, tt.wantFired) {
+				t.Errorf("Expected rules fired %v, got %v", tt.wantFired, assignment.RulesFired)
+			}
+		})
+	}
+}
+
+func TestAssign_RuleChain(t *testing.T) {
+	shift := &models.Shift{ID: 1}
+	rads := []*models.Radiologist{
+		{ID: "rad1", Status: "active"},
+		{ID: "rad2", Status: "active"},
+	}
+	toRadiologist := func(id int64, target, chain string, soft bool) *models.AssignmentRule {
+		return &models.AssignmentRule{ID: id, PriorityOrder: int(id), ActionType: "ASSIGN_TO_RADIOLOGIST",
+			ActionTarget: target, Chain: chain, Soft: soft}
+	}
+	escalate := &models.AssignmentRule{ID: 9, PriorityOrder: 9, ActionType: "ESCALATE"}
+
+	tests := []struct {
+		name          string
+		rules         []*models.AssignmentRule
+		wantAssignee  string // Empty when no one can be assigned
+		wantFired     []int64
+		wantEscalated bool
+	}{
+		{"continue applies later rules",
+			[]*models.AssignmentRule{toRadiologist(1, "rad2", "", false), escalate},
+			"rad2", []int64{1, 9}, true},
+		{"terminal stops",
+			[]*models.AssignmentRule{toRadiologist(1, "rad2", models.RuleChainTerminal, false), escalate},
+			"rad2", []int64{1}, false},
+		{"hard filter empties the pool",
+			[]*models.AssignmentRule{toRadiologist(1, "rad_away", "", false)},
+			"", nil, false},
+		{"soft filter is skipped",
+			[]*models.AssignmentRule{toRadiologist(1, "rad_away", "", true), toRadiologist(2, "rad2", "", false)},
+			"rad2", []int64{2}, false},
+		{"fallback restarts from every candidate",
+			[]*models.AssignmentRule{toRadiologist(1, "rad_away", "", false), toRadiologist(2, "rad2", models.RuleChainFallback, false)},
+			"rad2", []int64{1, 2}, false},
+		{"fallback unused while candidates remain",
+			[]*models.AssignmentRule{toRadiologist(1, "rad1", "", false), toRadiologist(2, "rad2", models.RuleChainFallback, false)},
+			"rad1", []int64{1}, false},
+		{"fallback to a worklist",
+			[]*models.AssignmentRule{
+				toRadiologist(1, "rad_away", "", false),
+				{ID: 2, PriorityOrder: 2, ActionType: "ASSIGN_TO_WORKLIST", ActionTarget: "overflow", Chain: models.RuleChainFallback},
+			},
+			models.WorklistAssignee, []int64{1, 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := setupEngine(t, []*models.Shift{shift}, rads, map[int64][]string{1: {"rad1", "rad2"}}, tt.rules)
+
+			assignment, trace, err := engine.AssignExplained(context.Background(), &models.Study{ID: "study1"})
+			if tt.wantAssignee == "" {
+				if err == nil {
+					t.Fatalf("Expected no one to be assigned, got %s", assignment.RadiologistID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if assignment.RadiologistID != tt.wantAssignee {
+				t.Errorf("Expected %s, got %s", tt.wantAssignee, assignment.RadiologistID)
+			}
+			if !reflect.DeepEqual(assignment.RulesFired, tt.wantFired) {
+				t.Errorf("Expected rules fired %v, got %v", tt.wantFired, assignment.RulesFired)
+			}
+			if assignment.Escalated != tt.wantEscalated {
+				t.Errorf("Expected escalated %v, got %v", tt.wantEscalated, assignment.Escalated)
+			}
+			for _, r := range trace.Rules {
+				if r.ID == escalate.ID && !tt.wantEscalated && r.Matched {
+					t.Errorf("Expected the rule after a terminal rule not to be evaluated, got %+v", r)
+				}
+			}
+		})
+	}
+}
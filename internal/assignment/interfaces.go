@@ -3,16 +3,32 @@ package assignment
 import (
 	"context"
 	"radiology-assignment/internal/models"
+	"time"
 )
 
 // DataStore defines the interface for database operations
 type DataStore interface {
-	GetShiftsByWorkType(ctx context.Context, modality, bodyPart string, site string) ([]*models.Shift, error)
+	GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error)
 	GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error)
 	GetRadiologists(ctx context.Context, ids []string) ([]*models.Radiologist, error)
 	GetRadiologistCurrentWorkload(ctx context.Context, radiologistID string) (int64, error)
 	GetRadiologistWorkloads(ctx context.Context, radiologistIDs []string) (map[string]int64, error)
 	SaveAssignment(ctx context.Context, assignment *models.Assignment) error
+
+	// GetAssignmentByStudyID returns the study's most recent assignment.
+	// It and GetStudy return nil, nil when nothing is stored.
+	GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error)
+	// UpdateAssignmentStatus records a lifecycle change; workloads must only
+	// count assignments that are still open.
+	UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error
+	GetStudy(ctx context.Context, id string) (*models.Study, error)
+	SaveStudy(ctx context.Context, study *models.Study) error
+
+	// GetProcedureByCode returns nil, nil when the code is not in the catalog
+	GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error)
+	// RecordUnmappedProcedure queues the study's procedure for catalog review,
+	// counting repeat occurrences of the same code and description
+	RecordUnmappedProcedure(ctx context.Context, study *models.Study) error
 }
 
 // RosterService defines the interface for roster retrieval
@@ -24,3 +40,11 @@ type RosterService interface {
 type RulesService interface {
 	GetActive() []*models.AssignmentRule
 }
+
+// VersionedRulesService is a RulesService that counts its changes, so the
+// engine can compile the rules once per version rather than for every study.
+// GetActiveVersion returns the rules and their version together.
+type VersionedRulesService interface {
+	RulesService
+	GetActiveVersion() ([]*models.AssignmentRule, uint64)
+}
@@ -3,19 +3,26 @@ package assignment
 import (
 	"context"
 	"radiology-assignment/internal/models"
+	"time"
 )
 
 type MockDataStore struct {
-	GetShiftsByWorkTypeFunc           func(ctx context.Context, modality, bodyPart string, site string) ([]*models.Shift, error)
+	GetShiftsByWorkTypeFunc           func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error)
 	GetRadiologistFunc                func(ctx context.Context, id string) (*models.Radiologist, error)
 	GetRadiologistsFunc               func(ctx context.Context, ids []string) ([]*models.Radiologist, error)
 	GetRadiologistCurrentWorkloadFunc func(ctx context.Context, radiologistID string) (int64, error)
 	GetRadiologistWorkloadsFunc       func(ctx context.Context, radiologistIDs []string) (map[string]int64, error)
 	SaveAssignmentFunc                func(ctx context.Context, assignment *models.Assignment) error
+	GetAssignmentByStudyIDFunc        func(ctx context.Context, studyID string) (*models.Assignment, error)
+	UpdateAssignmentStatusFunc        func(ctx context.Context, assignmentID int64, status string, at time.Time) error
+	GetStudyFunc                      func(ctx context.Context, id string) (*models.Study, error)
+	SaveStudyFunc                     func(ctx context.Context, study *models.Study) error
+	GetProcedureByCodeFunc            func(ctx context.Context, code string) (*models.Procedure, error)
+	RecordUnmappedProcedureFunc       func(ctx context.Context, study *models.Study) error
 }
 
-func (m *MockDataStore) GetShiftsByWorkType(ctx context.Context, modality, bodyPart string, site string) ([]*models.Shift, error) {
-	return m.GetShiftsByWorkTypeFunc(ctx, modality, bodyPart, site)
+func (m *MockDataStore) GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+	return m.GetShiftsByWorkTypeFunc(ctx, q)
 }
 
 func (m *MockDataStore) GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error) {
@@ -51,6 +58,50 @@ func (m *MockDataStore) SaveAssignment(ctx context.Context, assignment *models.A
 	return m.SaveAssignmentFunc(ctx, assignment)
 }
 
+// The order lifecycle methods are optional so existing tests need not set them
+
+func (m *MockDataStore) GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error) {
+	if m.GetAssignmentByStudyIDFunc == nil {
+		return nil, nil
+	}
+	return m.GetAssignmentByStudyIDFunc(ctx, studyID)
+}
+
+func (m *MockDataStore) UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error {
+	if m.UpdateAssignmentStatusFunc == nil {
+		return nil
+	}
+	return m.UpdateAssignmentStatusFunc(ctx, assignmentID, status, at)
+}
+
+func (m *MockDataStore) GetStudy(ctx context.Context, id string) (*models.Study, error) {
+	if m.GetStudyFunc == nil {
+		return nil, nil
+	}
+	return m.GetStudyFunc(ctx, id)
+}
+
+func (m *MockDataStore) SaveStudy(ctx context.Context, study *models.Study) error {
+	if m.SaveStudyFunc == nil {
+		return nil
+	}
+	return m.SaveStudyFunc(ctx, study)
+}
+
+func (m *MockDataStore) GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error) {
+	if m.GetProcedureByCodeFunc == nil {
+		return nil, nil
+	}
+	return m.GetProcedureByCodeFunc(ctx, code)
+}
+
+func (m *MockDataStore) RecordUnmappedProcedure(ctx context.Context, study *models.Study) error {
+	if m.RecordUnmappedProcedureFunc == nil {
+		return nil
+	}
+	return m.RecordUnmappedProcedureFunc(ctx, study)
+}
+
 type MockRosterService struct {
 	GetByShiftFunc func(shiftID int64) []*models.RosterEntry
 }
@@ -66,3 +117,18 @@ type MockRulesService struct {
 func (m *MockRulesService) GetActive() []*models.AssignmentRule {
 	return m.GetActiveFunc()
 }
+
+// MockVersionedRulesService serves fixed rules under a version, as the rules
+// cache does; bump Version along with any change to Rules.
+type MockVersionedRulesService struct {
+	Rules   []*models.AssignmentRule
+	Version uint64
+}
+
+func (m *MockVersionedRulesService) GetActive() []*models.AssignmentRule {
+	return m.Rules
+}
+
+func (m *MockVersionedRulesService) GetActiveVersion() ([]*models.AssignmentRule, uint64) {
+	return m.Rules, m.Version
+}
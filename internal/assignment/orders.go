@@ -0,0 +1,180 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"time"
+)
+
+var ErrNoAssignment = errors.New("study has no assignment")
+
+// ErrNoCandidate is returned when no radiologist can take a study, so it
+// needs assigning by hand.
+var ErrNoCandidate = errors.New("no candidate radiologist")
+
+// Process applies an inbound order according to its ORC-1 control code and
+// returns the assignment to publish downstream. It returns nil when nothing
+// new was assigned: the order was cancelled, a status update was recorded, a
+// change did not affect routing, or the order was already assigned (a
+// journal redelivery).
+func (e *Engine) Process(ctx context.Context, study *models.Study) (*models.Assignment, error) {
+	if study == nil {
+		return nil, fmt.Errorf("study cannot be nil")
+	}
+
+	switch {
+	case study.IsCancellation():
+		_, err := e.Cancel(ctx, study.ID)
+		return nil, err
+
+	case study.IsStatusUpdate():
+		_, err := e.UpdateStatus(ctx, study.ID, study.ReportStatus)
+		return nil, err
+
+	case study.OrderControl == models.OrderControlChange:
+		// Normalize first so routing changes are judged on canonical values
+		if err := e.normalize(ctx, study); err != nil {
+			return nil, err
+		}
+		assignment, reassigned, err := e.Modify(ctx, study)
+		if err != nil || !reassigned {
+			return nil, err
+		}
+		return assignment, nil
+
+	default:
+		existing, err := e.db.GetAssignmentByStudyID(ctx, study.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.CurrentStatus() != models.StatusCancelled {
+			return nil, nil
+		}
+		return e.assignAndSave(ctx, study)
+	}
+}
+
+// UpdateStatus moves a study's assignment through its lifecycle. Repeating
+// the current status is a no-op so redelivered messages are harmless.
+func (e *Engine) UpdateStatus(ctx context.Context, studyID, status string) (*models.Assignment, error) {
+	if !models.ValidStatus(status) {
+		return nil, fmt.Errorf("unknown assignment status %q", status)
+	}
+
+	existing, err := e.db.GetAssignmentByStudyID(ctx, studyID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("%w %s", ErrNoAssignment, studyID)
+	}
+	if existing.CurrentStatus() == status {
+		return existing, nil
+	}
+	if !existing.CanTransition(status) {
+		return nil, fmt.Errorf("%w: study %s is %s, cannot become %s", models.ErrInvalidTransition, studyID, existing.CurrentStatus(), status)
+	}
+
+	if err := e.db.UpdateAssignmentStatus(ctx, existing.ID, status, time.Now()); err != nil {
+		return nil, err
+	}
+	return e.db.GetAssignmentByStudyID(ctx, studyID)
+}
+
+// Cancel marks the assignment for a cancelled or discontinued order as
+// cancelled so it no longer counts against the radiologist's capacity. It
+// returns the cancelled assignment, or nil if the study was never assigned.
+func (e *Engine) Cancel(ctx context.Context, studyID string) (*models.Assignment, error) {
+	existing, err := e.db.GetAssignmentByStudyID(ctx, studyID)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	return e.UpdateStatus(ctx, studyID, models.StatusCancelled)
+}
+
+// Escalate puts a study no radiologist can take on the manual worklist, to
+// be assigned by hand. A study that is still assigned keeps its assignment,
+// and nil is returned.
+func (e *Engine) Escalate(ctx context.Context, study *models.Study) (*models.Assignment, error) {
+	existing, err := e.db.GetAssignmentByStudyID(ctx, study.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.CurrentStatus() != models.StatusCancelled {
+		return nil, nil
+	}
+
+	assignment := &models.Assignment{
+		StudyID:       study.ID,
+		RadiologistID: models.WorklistAssignee,
+		Worklist:      models.ManualWorklist,
+		AssignedAt:    time.Now(),
+		Escalated:     true,
+		Strategy:      models.StrategyManual,
+		Status:        models.StatusAssigned,
+	}
+	if err := e.db.SaveAssignment(ctx, assignment); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// Modify applies an order change. A study nobody has started reading is
+// reassigned only when its modality, body part, urgency or site changed;
+// otherwise the existing assignment stands and only the stored order details
+// are updated. The bool result reports whether assignment was re-run. If
+// reassignment fails the existing assignment is restored, so a changed order
+// is never left unassigned.
+func (e *Engine) Modify(ctx context.Context, study *models.Study) (*models.Assignment, bool, error) {
+	prev, err := e.db.GetStudy(ctx, study.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	existing, err := e.db.GetAssignmentByStudyID(ctx, study.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var restore func() error
+	if existing != nil && existing.CurrentStatus() != models.StatusCancelled {
+		keep := existing.CurrentStatus() != models.StatusAssigned || (prev != nil && !study.RoutingChanged(prev))
+		if keep {
+			if err := e.db.SaveStudy(ctx, study); err != nil {
+				return nil, false, err
+			}
+			return existing, false, nil
+		}
+		// Cancelled before reassigning so the study no longer counts against
+		// its radiologist's capacity
+		status, at := existing.CurrentStatus(), existing.StatusUpdatedAt
+		if _, err := e.UpdateStatus(ctx, study.ID, models.StatusCancelled); err != nil {
+			return nil, false, err
+		}
+		restore = func() error { return e.db.UpdateAssignmentStatus(ctx, existing.ID, status, at) }
+	}
+
+	assignment, err := e.assignAndSave(ctx, study)
+	if err != nil {
+		if restore != nil {
+			if rerr := restore(); rerr != nil {
+				return nil, false, fmt.Errorf("%w (restoring assignment %d: %v)", err, existing.ID, rerr)
+			}
+		}
+		return nil, false, err
+	}
+	return assignment, true, nil
+}
+
+// assignAndSave runs the assignment pipeline and records the normalized order
+// it was made for, so a later change can be compared against it.
+func (e *Engine) assignAndSave(ctx context.Context, study *models.Study) (*models.Assignment, error) {
+	if err := e.normalize(ctx, study); err != nil {
+		return nil, err
+	}
+	if err := e.db.SaveStudy(ctx, study); err != nil {
+		return nil, err
+	}
+	return e.Assign(ctx, study)
+}
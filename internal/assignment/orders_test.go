@@ -0,0 +1,309 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"radiology-assignment/internal/models"
+	"testing"
+	"time"
+)
+
+// orderStore keeps assignments and studies for the mock DataStore
+type orderStore struct {
+	assignments []*models.Assignment
+	studies     map[string]*models.Study
+}
+
+// latest returns the most recent assignment for a study
+func (o *orderStore) latest(studyID string) *models.Assignment {
+	for i := len(o.assignments) - 1; i >= 0; i-- {
+		if o.assignments[i].StudyID == studyID {
+			return o.assignments[i]
+		}
+	}
+	return nil
+}
+
+func (o *orderStore) open() int {
+	n := 0
+	for _, a := range o.assignments {
+		if a.IsOpen() {
+			n++
+		}
+	}
+	return n
+}
+
+// setupOrderEngine returns an engine backed by an orderStore, with rad1
+// rostered on a single MRI shift.
+func setupOrderEngine(t *testing.T) (*Engine, *orderStore) {
+	shift := &models.Shift{ID: 1, Name: "MRI", WorkType: "MRI"}
+	rad := &models.Radiologist{ID: "rad1", Status: "active", MaxConcurrentStudies: 5}
+	engine := setupEngine(t, []*models.Shift{shift}, []*models.Radiologist{rad}, map[int64][]string{1: {"rad1"}}, nil)
+
+	store := &orderStore{studies: make(map[string]*models.Study)}
+
+	mockDB := engine.db.(*MockDataStore)
+	mockDB.SaveAssignmentFunc = func(ctx context.Context, a *models.Assignment) error {
+		a.ID = int64(len(store.assignments) + 1)
+		store.assignments = append(store.assignments, a)
+		return nil
+	}
+	mockDB.GetAssignmentByStudyIDFunc = func(ctx context.Context, studyID string) (*models.Assignment, error) {
+		return store.latest(studyID), nil
+	}
+	mockDB.UpdateAssignmentStatusFunc = func(ctx context.Context, id int64, status string, at time.Time) error {
+		for _, a := range store.assignments {
+			if a.ID == id {
+				a.Status = status
+				a.StatusUpdatedAt = at
+				return nil
+			}
+		}
+		return errors.New("not found")
+	}
+	mockDB.GetStudyFunc = func(ctx context.Context, id string) (*models.Study, error) {
+		return store.studies[id], nil
+	}
+	mockDB.SaveStudyFunc = func(ctx context.Context, s *models.Study) error {
+		store.studies[s.ID] = s
+		return nil
+	}
+	return engine, store
+}
+
+func TestProcess_NewOrderIsIdempotent(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+	study := &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI", Urgency: "ROUTINE"}
+
+	first, err := engine.Process(context.Background(), study)
+	if err != nil || first == nil {
+		t.Fatalf("Expected assignment, got %v, %v", first, err)
+	}
+
+	// A redelivered NW must not produce a second assignment
+	second, err := engine.Process(context.Background(), study)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if second != nil {
+		t.Errorf("Expected no new assignment on redelivery, got %+v", second)
+	}
+	if len(store.assignments) != 1 {
+		t.Errorf("Expected 1 assignment, got %d", len(store.assignments))
+	}
+}
+
+func TestProcess_Cancel(t *testing.T) {
+	for _, code := range []string{"CA", "DC"} {
+		t.Run(code, func(t *testing.T) {
+			engine, store := setupOrderEngine(t)
+			ctx := context.Background()
+
+			engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI"})
+			if store.open() != 1 {
+				t.Fatalf("Expected study to be assigned first")
+			}
+
+			a, err := engine.Process(ctx, &models.Study{ID: "S1", OrderControl: code})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if a != nil {
+				t.Errorf("Expected nothing to publish for a cancel, got %+v", a)
+			}
+			if store.open() != 0 {
+				t.Errorf("Expected no open assignments, got %d", store.open())
+			}
+			if got := store.latest("S1").Status; got != models.StatusCancelled {
+				t.Errorf("Expected cancelled, got %s", got)
+			}
+		})
+	}
+}
+
+func TestCancel_UnknownStudy(t *testing.T) {
+	engine, _ := setupOrderEngine(t)
+	removed, err := engine.Cancel(context.Background(), "missing")
+	if err != nil || removed != nil {
+		t.Errorf("Expected nil, nil for an unassigned study, got %v, %v", removed, err)
+	}
+}
+
+func TestProcess_Change(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(s *models.Study)
+		wantReassigned bool
+	}{
+		{"Indication only", func(s *models.Study) { s.Indication = "Updated history" }, false},
+		{"Urgency", func(s *models.Study) { s.Urgency = "STAT" }, true},
+		{"Body part", func(s *models.Study) { s.BodyPart = "KNEE" }, true},
+		{"Site", func(s *models.Study) { s.Site = "SiteB" }, true},
+		{"Modality", func(s *models.Study) { s.Modality = "MRI 3T" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, store := setupOrderEngine(t)
+			ctx := context.Background()
+
+			original := &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI", BodyPart: "HEAD", Urgency: "ROUTINE", Site: "SiteA"}
+			engine.Process(ctx, original)
+
+			changed := *original
+			changed.OrderControl = "XO"
+			tt.change(&changed)
+
+			a, err := engine.Process(ctx, &changed)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if (a != nil) != tt.wantReassigned {
+				t.Errorf("Expected reassigned=%v, got assignment %+v", tt.wantReassigned, a)
+			}
+			if store.open() != 1 {
+				t.Errorf("Expected exactly 1 open assignment, got %d", store.open())
+			}
+			if store.studies["S1"] != &changed {
+				t.Errorf("Expected stored study to be updated")
+			}
+		})
+	}
+}
+
+func TestProcess_ChangeThatCannotBeReassignedKeepsAssignment(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+	ctx := context.Background()
+
+	engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI", Urgency: "ROUTINE"})
+	engine.db.(*MockDataStore).GetShiftsByWorkTypeFunc = func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+		return nil, nil
+	}
+
+	if _, err := engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "XO", Modality: "MRI", Urgency: "STAT"}); err == nil {
+		t.Fatal("Expected an error when no shift covers the changed order")
+	}
+	a := store.latest("S1")
+	if len(store.assignments) != 1 || a.CurrentStatus() != models.StatusAssigned {
+		t.Errorf("Expected the original assignment to stand, got %+v", store.assignments)
+	}
+}
+
+func TestEscalate_SendsUnassignableStudyToManualWorklist(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+	ctx := context.Background()
+
+	engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI"})
+	engine.db.(*MockDataStore).GetShiftsByWorkTypeFunc = func(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+		return nil, nil
+	}
+
+	study := &models.Study{ID: "S2", OrderControl: "NW", Modality: "MRI"}
+	if _, err := engine.Process(ctx, study); !errors.Is(err, ErrNoCandidate) {
+		t.Fatalf("Expected ErrNoCandidate, got %v", err)
+	}
+	a, err := engine.Escalate(ctx, study)
+	if err != nil || a == nil {
+		t.Fatalf("Expected manual worklist assignment, got %v, %v", a, err)
+	}
+	if a.RadiologistID != models.WorklistAssignee || a.Worklist != models.ManualWorklist || a.Strategy != models.StrategyManual || !a.Escalated {
+		t.Errorf("Expected an escalated manual worklist assignment, got %+v", a)
+	}
+	if store.latest("S2") != a {
+		t.Errorf("Expected the manual worklist assignment to be saved")
+	}
+
+	// A study that is still assigned keeps its radiologist
+	a, err = engine.Escalate(ctx, &models.Study{ID: "S1", Modality: "MRI"})
+	if err != nil || a != nil {
+		t.Errorf("Expected no escalation for an assigned study, got %+v, %v", a, err)
+	}
+	if got := store.latest("S1").RadiologistID; got != "rad1" {
+		t.Errorf("Expected S1 to stay with rad1, got %s", got)
+	}
+}
+
+func TestProcess_ChangeForUnknownStudyAssigns(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+
+	a, err := engine.Process(context.Background(), &models.Study{ID: "S9", OrderControl: "XO", Modality: "MRI"})
+	if err != nil || a == nil {
+		t.Fatalf("Expected assignment, got %v, %v", a, err)
+	}
+	if store.latest("S9") == nil {
+		t.Errorf("Expected assignment to be saved")
+	}
+}
+
+func TestProcess_ChangeAfterReadingStartedKeepsRadiologist(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+	ctx := context.Background()
+
+	engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI", Urgency: "ROUTINE"})
+	engine.UpdateStatus(ctx, "S1", models.StatusInProgress)
+
+	a, err := engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "XO", Modality: "MRI", Urgency: "STAT"})
+	if err != nil || a != nil {
+		t.Errorf("Expected in-progress study to keep its assignment, got %v, %v", a, err)
+	}
+	if len(store.assignments) != 1 {
+		t.Errorf("Expected no new assignment, got %d", len(store.assignments))
+	}
+}
+
+func TestUpdateStatus_Transitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []string
+		wantErr bool
+		want    string
+	}{
+		{"Full lifecycle", []string{"in_progress", "reported", "finalized"}, false, "finalized"},
+		{"Straight to final", []string{"finalized"}, false, "finalized"},
+		{"Repeated status", []string{"in_progress", "in_progress"}, false, "in_progress"},
+		{"Cancel while reading", []string{"in_progress", "cancelled"}, false, "cancelled"},
+		{"Backwards", []string{"reported", "in_progress"}, true, "reported"},
+		{"Reopen cancelled", []string{"cancelled", "assigned"}, true, "cancelled"},
+		{"Unknown status", []string{"archived"}, true, "assigned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, store := setupOrderEngine(t)
+			ctx := context.Background()
+			engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI"})
+
+			var err error
+			for _, status := range tt.steps {
+				if _, err = engine.UpdateStatus(ctx, "S1", status); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got := store.latest("S1").CurrentStatus(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProcess_StatusUpdate(t *testing.T) {
+	engine, store := setupOrderEngine(t)
+	ctx := context.Background()
+	engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "NW", Modality: "MRI"})
+
+	a, err := engine.Process(ctx, &models.Study{ID: "S1", OrderControl: "RE", ReportStatus: models.StatusFinalized})
+	if err != nil || a != nil {
+		t.Fatalf("Expected status update to publish nothing, got %v, %v", a, err)
+	}
+	if store.open() != 0 {
+		t.Errorf("Expected finalized study to leave the workload, got %d open", store.open())
+	}
+
+	if _, err := engine.Process(ctx, &models.Study{ID: "S2", OrderControl: "SC", ReportStatus: models.StatusInProgress}); !errors.Is(err, ErrNoAssignment) {
+		t.Errorf("Expected ErrNoAssignment for unknown study, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"radiology-assignment/internal/models"
 	"testing"
+	"time"
 )
 
 // BenchStore implements DataStore with the inefficient O(M) workload lookup
@@ -14,7 +15,7 @@ type BenchStore struct {
 	rads        map[string]*models.Radiologist
 }
 
-func (s *BenchStore) GetShiftsByWorkType(ctx context.Context, modality, bodyPart, site string) ([]*models.Shift, error) {
+func (s *BenchStore) GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
 	return s.shifts, nil
 }
 
@@ -68,6 +69,30 @@ func (s *BenchStore) SaveAssignment(ctx context.Context, assignment *models.Assi
 	return nil
 }
 
+func (s *BenchStore) GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error) {
+	return nil, nil
+}
+
+func (s *BenchStore) UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error {
+	return nil
+}
+
+func (s *BenchStore) GetStudy(ctx context.Context, id string) (*models.Study, error) {
+	return nil, nil
+}
+
+func (s *BenchStore) SaveStudy(ctx context.Context, study *models.Study) error {
+	return nil
+}
+
+func (s *BenchStore) GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error) {
+	return nil, nil
+}
+
+func (s *BenchStore) RecordUnmappedProcedure(ctx context.Context, study *models.Study) error {
+	return nil
+}
+
 // Ensure BenchStore implements DataStore
 var _ DataStore = &BenchStore{}
 
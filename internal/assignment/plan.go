@@ -0,0 +1,144 @@
+package assignment
+
+import (
+	"log"
+	"radiology-assignment/internal/models"
+	"sort"
+)
+
+// indexedFields are the study fields the rule plan is indexed on, most
+// discriminating first when a rule restricts several of them.
+var indexedFields = []string{"site", "procedure_code", "urgency"}
+
+type compiledRule struct {
+	rule       *models.AssignmentRule
+	conditions *models.ConditionGroup
+	err        error // Why the filters did not parse; such a rule never matches
+}
+
+// rulePlan is the active rules compiled for evaluation. It is never modified
+// after compilePlan returns, so one plan can serve any number of studies
+// while the next is built.
+type rulePlan struct {
+	version uint64
+	rules   []compiledRule // In evaluation order
+
+	// Positions in rules. A rule whose top-level conditions limit one of the
+	// indexed fields to a set of values is listed under each of those values
+	// only; every other rule is in always. All lists are in ascending order.
+	always []int
+	index  map[string]map[string][]int
+}
+
+// compilePlan parses every rule once and indexes it. rules is not modified.
+func compilePlan(rules []*models.AssignmentRule, version uint64) *rulePlan {
+	ordered := make([]*models.AssignmentRule, len(rules))
+	copy(ordered, rules)
+	// Lower PriorityOrder first, ties in ID order as the rules cache keeps them
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].PriorityOrder != ordered[j].PriorityOrder {
+			return ordered[i].PriorityOrder < ordered[j].PriorityOrder
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	p := &rulePlan{
+		version: version,
+		rules:   make([]compiledRule, len(ordered)),
+		index:   make(map[string]map[string][]int, len(indexedFields)),
+	}
+	for _, f := range indexedFields {
+		p.index[f] = make(map[string][]int)
+	}
+
+	for i, rule := range ordered {
+		conditions, err := models.ParseConditions(rule.ConditionFilters)
+		p.rules[i] = compiledRule{rule: rule, conditions: conditions, err: err}
+		if err != nil {
+			// Rules are validated when saved, so this is one stored before that
+			log.Printf("Rule %d never matches: %v", rule.ID, err)
+			continue
+		}
+
+		field, values := discriminator(conditions)
+		if field == "" {
+			p.always = append(p.always, i)
+			continue
+		}
+		seen := make(map[string]bool, len(values))
+		for _, v := range values {
+			if !seen[v] {
+				seen[v] = true
+				p.index[field][v] = append(p.index[field][v], i)
+			}
+		}
+	}
+	return p
+}
+
+// discriminator picks the indexed field the rule restricts to the fewest
+// values. Only conditions at the top level count, as anything under a group
+// can be outweighed by its siblings.
+func discriminator(g *models.ConditionGroup) (field string, values []string) {
+	for _, f := range indexedFields {
+		for i := range g.Conditions {
+			c := &g.Conditions[i]
+			if c.Field != f {
+				continue
+			}
+			if vals, ok := c.Requires(); ok && (field == "" || len(vals) < len(values)) {
+				field, values = f, vals
+			}
+		}
+	}
+	return field, values
+}
+
+// candidates returns the positions of the rules that can match the study, in
+// evaluation order. The others are known to fail without evaluating them.
+func (p *rulePlan) candidates(study *models.Study) []int {
+	var lists [][]int
+	if len(p.always) > 0 {
+		lists = append(lists, p.always)
+	}
+	for _, f := range indexedFields {
+		var v string
+		switch f {
+		case "site":
+			v = study.Site
+		case "procedure_code":
+			v = study.ProcedureCode
+		case "urgency":
+			v = study.Urgency
+		}
+		if l := p.index[f][v]; len(l) > 0 {
+			lists = append(lists, l)
+		}
+	}
+	switch len(lists) {
+	case 0:
+		return nil
+	case 1:
+		return lists[0]
+	}
+
+	// A rule is in at most one list, so merging needs no deduplication
+	n := 0
+	for _, l := range lists {
+		n += len(l)
+	}
+	merged := make([]int, 0, n)
+	for len(lists) > 0 {
+		min := 0
+		for i := range lists {
+			if lists[i][0] < lists[min][0] {
+				min = i
+			}
+		}
+		merged = append(merged, lists[min][0])
+		if lists[min] = lists[min][1:]; len(lists[min]) == 0 {
+			lists = append(lists[:min], lists[min+1:]...)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,168 @@
+package assignment
+
+import (
+	"context"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestRulePlan_IndexAgreesWithFullScan(t *testing.T) {
+	sites := []string{"Main", "Remote", "Partner"}
+	codes := []string{"CTHEAD", "MRBRAIN", "XRCHEST"}
+	urgencies := []string{"STAT", "ROUTINE"}
+
+	var rules []*models.AssignmentRule
+	add := func(filters map[string]interface{}) {
+		id := int64(len(rules) + 1)
+		// Priorities repeat and run backwards so the plan has to order them
+		rules = append(rules, &models.AssignmentRule{ID: id, PriorityOrder: 100 - int(id)%7, ConditionFilters: filters})
+	}
+	for _, site := range sites {
+		add(map[string]interface{}{"site": site})
+		add(map[string]interface{}{"site": site, "urgency": "STAT"})
+		for _, code := range codes {
+			add(map[string]interface{}{"site": map[string]interface{}{"in": []interface{}{site, "Main"}}, "procedure_code": code})
+		}
+	}
+	for _, u := range urgencies {
+		add(map[string]interface{}{"urgency": u})
+		add(map[string]interface{}{"urgency": map[string]interface{}{"not_in": []interface{}{u}}})
+	}
+	add(nil)
+	add(map[string]interface{}{"modality": "CT"})
+	add(map[string]interface{}{"any": []interface{}{
+		map[string]interface{}{"site": "Remote"},
+		map[string]interface{}{"urgency": "STAT"},
+	}})
+	add(map[string]interface{}{"procedure_code": map[string]interface{}{"prefix": "MR"}})
+	add(map[string]interface{}{"site": 3}) // Invalid, never matches
+
+	plan := compilePlan(rules, 1)
+	for _, site := range append(sites, "Elsewhere") {
+		for _, code := range append(codes, "") {
+			for _, u := range urgencies {
+				study := &models.Study{Site: site, ProcedureCode: code, Urgency: u, Modality: "CT"}
+
+				var want, got []int64
+				for _, c := range plan.rules {
+					if evalConditions(c.conditions, c.err, study, nil) {
+						want = append(want, c.rule.ID)
+					}
+				}
+				for _, pos := range plan.candidates(study) {
+					c := plan.rules[pos]
+					if evalConditions(c.conditions, c.err, study, nil) {
+						got = append(got, c.rule.ID)
+					}
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s/%s/%s: expected rules %v, got %v", site, code, u, want, got)
+				}
+			}
+		}
+	}
+
+	if len(plan.always) >= len(rules)/2 {
+		t.Errorf("Expected most rules to be indexed, %d of %d are not", len(plan.always), len(rules))
+	}
+	for i := 1; i < len(plan.rules); i++ {
+		if plan.rules[i-1].rule.PriorityOrder > plan.rules[i].rule.PriorityOrder {
+			t.Fatalf("Expected the plan in priority order, got %d before %d",
+				plan.rules[i-1].rule.PriorityOrder, plan.rules[i].rule.PriorityOrder)
+		}
+	}
+}
+
+func TestEvaluateRules_LeavesSharedRulesAlone(t *testing.T) {
+	shift := &models.Shift{ID: 1}
+	rads := []*models.Radiologist{{ID: "rad1", Status: "active"}, {ID: "rad2", Status: "active"}}
+	// Out of priority order, as another goroutine might be reading them
+	rules := []*models.AssignmentRule{
+		{ID: 1, Name: "Second", PriorityOrder: 2, ActionType: "ASSIGN_TO_WORKLIST", ActionTarget: "second"},
+		{ID: 2, Name: "First", PriorityOrder: 1, ActionType: "ASSIGN_TO_WORKLIST", ActionTarget: "first"},
+	}
+	engine := setupEngine(t, []*models.Shift{shift}, rads, map[int64][]string{1: {"rad1", "rad2"}}, rules)
+
+	assignment, err := engine.Assign(context.Background(), &models.Study{ID: "study1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if assignment.Worklist != "first" {
+		t.Errorf("Expected the higher priority rule to decide, got worklist %q", assignment.Worklist)
+	}
+	if rules[0].Name != "Second" || rules[1].Name != "First" {
+		t.Errorf("Expected the service's slice to keep its order, got %s, %s", rules[0].Name, rules[1].Name)
+	}
+}
+
+func TestEngine_RecompilesRulesOnNewVersion(t *testing.T) {
+	shift := &models.Shift{ID: 1}
+	rads := []*models.Radiologist{{ID: "rad1", Status: "active"}, {ID: "rad2", Status: "active"}}
+	engine := setupEngine(t, []*models.Shift{shift}, rads, map[int64][]string{1: {"rad1", "rad2"}}, nil)
+	service := &MockVersionedRulesService{Version: 1, Rules: []*models.AssignmentRule{
+		{ID: 1, ActionType: "ASSIGN_TO_RADIOLOGIST", ActionTarget: "rad2"},
+	}}
+	engine.rules = service
+
+	assignTo := func() string {
+		t.Helper()
+		a, err := engine.Assign(context.Background(), &models.Study{ID: "study1"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return a.RadiologistID
+	}
+	if got := assignTo(); got != "rad2" {
+		t.Fatalf("Expected rad2, got %s", got)
+	}
+	plan := engine.plan.Load()
+
+	// Same version: the compiled plan is reused
+	assignTo()
+	if engine.plan.Load() != plan {
+		t.Error("Expected the plan to be reused for an unchanged version")
+	}
+
+	service.Rules = []*models.AssignmentRule{{ID: 2, ActionType: "ASSIGN_TO_RADIOLOGIST", ActionTarget: "rad1"}}
+	service.Version = 2
+	if got := assignTo(); got != "rad1" {
+		t.Errorf("Expected the new rules to send the study to rad1, got %s", got)
+	}
+}
+
+func BenchmarkRulePlan_Compile1000Rules(b *testing.B) {
+	rules := benchmarkRules(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compilePlan(rules, uint64(i))
+	}
+}
+
+// benchmarkRules builds n rules spread over sites, procedures and urgencies,
+// with a few in every plan's always list, none of which match the benchmark
+// study's routing except the last.
+func benchmarkRules(n int) []*models.AssignmentRule {
+	rules := make([]*models.AssignmentRule, n)
+	for i := 0; i < n; i++ {
+		var filters map[string]interface{}
+		switch i % 4 {
+		case 0:
+			filters = map[string]interface{}{"site": fmt.Sprintf("SITE%d", i%50), "urgency": "STAT"}
+		case 1:
+			filters = map[string]interface{}{"procedure_code": map[string]interface{}{"in": []interface{}{fmt.Sprintf("PROC%d", i), fmt.Sprintf("PROC%d", i+1)}}}
+		case 2:
+			filters = map[string]interface{}{"urgency": "ASAP", "patient_age": map[string]interface{}{"gte": 18, "lt": 65}}
+		default:
+			filters = map[string]interface{}{"any": []interface{}{
+				map[string]interface{}{"indication": map[string]interface{}{"regex": fmt.Sprintf("(?i)code %d", i)}},
+				map[string]interface{}{"min_age_minutes": 1000},
+			}}
+		}
+		rules[i] = &models.AssignmentRule{ID: int64(i + 1), PriorityOrder: i, ConditionFilters: filters, ActionType: "ESCALATE"}
+	}
+	rules[n-1].ConditionFilters = map[string]interface{}{"site": "MAIN", "modality": "MRI"}
+	rules[n-1].ActionType = "FILTER_COMPETENCY"
+	return rules
+}
@@ -0,0 +1,68 @@
+package assignment
+
+import (
+	"radiology-assignment/internal/models"
+)
+
+// The trace helpers below do nothing when tr is nil, so the pipeline can call
+// them unconditionally and only pays for tracing in explain mode.
+
+func traceShifts(tr *models.AssignmentTrace, shifts []*models.Shift) {
+	if tr == nil {
+		return
+	}
+	for _, sh := range shifts {
+		tr.Shifts = append(tr.Shifts, models.TraceShift{ID: sh.ID, Name: sh.Name, PriorityLevel: sh.PriorityLevel})
+	}
+}
+
+// traceResolved records the candidates resolved from the roster and every
+// rostered radiologist excluded on the way.
+func traceResolved(tr *models.AssignmentTrace, candidates []*candidate, excluded []Exclusion) {
+	if tr == nil {
+		return
+	}
+	for _, c := range candidates {
+		tr.Candidates = append(tr.Candidates, models.TraceCandidate{RadiologistID: c.Radiologist.ID, ShiftID: c.ShiftID, CurrentLoad: c.CurrentLoad})
+	}
+	for _, x := range excluded {
+		tr.Removed = append(tr.Removed, models.TraceRemoval{Step: x.Step, RadiologistID: x.RadiologistID, ShiftID: x.ShiftID, Reason: x.Reason})
+	}
+}
+
+// traceFiltered records the candidates a rule's action removed.
+func traceFiltered(tr *models.AssignmentTrace, rule *models.AssignmentRule, before, after []*candidate, reason string) {
+	if tr == nil {
+		return
+	}
+	kept := make(map[*candidate]bool, len(after))
+	for _, c := range after {
+		kept[c] = true
+	}
+	for _, c := range before {
+		if !kept[c] {
+			tr.Removed = append(tr.Removed, models.TraceRemoval{
+				Step:          rule.ActionType,
+				RuleID:        rule.ID,
+				RadiologistID: c.Radiologist.ID,
+				ShiftID:       c.ShiftID,
+				Reason:        reason,
+			})
+		}
+	}
+}
+
+func traceScores(tr *models.AssignmentTrace, candidates []*candidate, selected *candidate) {
+	if tr == nil {
+		return
+	}
+	for _, c := range candidates {
+		tr.Scores = append(tr.Scores, models.TraceScore{
+			RadiologistID:        c.Radiologist.ID,
+			ShiftID:              c.ShiftID,
+			CurrentLoad:          c.CurrentLoad,
+			MaxConcurrentStudies: c.Radiologist.MaxConcurrentStudies,
+			Selected:             c == selected,
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package cache
+
+import (
+	"context"
+	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*db.MemoryStore, *Store) {
+	t.Helper()
+	ctx := context.Background()
+	mem := db.NewMemoryStore()
+	for _, id := range []string{"rad1", "rad2"} {
+		if err := mem.SaveRadiologist(ctx, &models.Radiologist{ID: id, Status: "active"}); err != nil {
+			t.Fatalf("SaveRadiologist: %v", err)
+		}
+	}
+	for _, name := range []string{"Day CT", "Night CT"} {
+		if err := mem.CreateShift(ctx, &models.Shift{Name: name, WorkType: "CT"}); err != nil {
+			t.Fatalf("CreateShift: %v", err)
+		}
+	}
+	if err := mem.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 1, RadiologistID: "rad1"}); err != nil {
+		t.Fatalf("CreateRosterEntry: %v", err)
+	}
+	cs, err := NewStore(ctx, mem)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return mem, cs
+}
+
+func rosterIDs(entries []*models.RosterEntry) []string {
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.RadiologistID)
+	}
+	return ids
+}
+
+func TestRosterCache_LoadsAndIndexes(t *testing.T) {
+	_, cs := newTestStore(t)
+
+	if got := rosterIDs(cs.Roster.GetByShift(1)); len(got) != 1 || got[0] != "rad1" {
+		t.Errorf("Expected rad1 on shift 1, got %v", got)
+	}
+	if got := cs.Roster.GetByShift(2); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty roster for shift 2, got %v", got)
+	}
+	if got := cs.Roster.GetByRadiologist("rad1"); len(got) != 1 || got[0].ShiftID != 1 {
+		t.Errorf("Expected rad1 rostered on shift 1, got %v", got)
+	}
+	if cs.Roster.LastSync().IsZero() {
+		t.Error("Expected LastSync to be set after loading")
+	}
+}
+
+func TestStore_PushesRosterChanges(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+
+	before := cs.Roster.GetByShift(1)
+	entry := &models.RosterEntry{ShiftID: 1, RadiologistID: "rad2"}
+	if err := cs.CreateRosterEntry(ctx, entry); err != nil {
+		t.Fatalf("CreateRosterEntry: %v", err)
+	}
+	if got := rosterIDs(cs.Roster.GetByShift(1)); len(got) != 2 {
+		t.Errorf("Expected 2 entries on shift 1 without a refresh, got %v", got)
+	}
+	if len(before) != 1 {
+		t.Errorf("Expected a slice handed out earlier to stay unchanged, got %v", rosterIDs(before))
+	}
+
+	if err := cs.DeleteRosterEntry(ctx, entry.ID); err != nil {
+		t.Fatalf("DeleteRosterEntry: %v", err)
+	}
+	if got := cs.Roster.GetByRadiologist("rad2"); len(got) != 0 {
+		t.Errorf("Expected rad2 to be off the roster, got %v", got)
+	}
+
+	if err := cs.DeleteRadiologist(ctx, "rad1"); err != nil {
+		t.Fatalf("DeleteRadiologist: %v", err)
+	}
+	if got := cs.Roster.GetByShift(1); len(got) != 0 {
+		t.Errorf("Expected shift 1 to be empty after deleting rad1, got %v", rosterIDs(got))
+	}
+
+	if err := cs.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 2, RadiologistID: "rad2"}); err != nil {
+		t.Fatalf("CreateRosterEntry: %v", err)
+	}
+	if err := cs.DeleteShift(ctx, 2); err != nil {
+		t.Fatalf("DeleteShift: %v", err)
+	}
+	if got := cs.Roster.GetByRadiologist("rad2"); len(got) != 0 {
+		t.Errorf("Expected no entries for rad2 after deleting shift 2, got %v", got)
+	}
+}
+
+func TestStore_FailedWriteLeavesCache(t *testing.T) {
+	_, cs := newTestStore(t)
+
+	err := cs.CreateRosterEntry(context.Background(), &models.RosterEntry{ShiftID: 99, RadiologistID: "rad1"})
+	if err == nil {
+		t.Fatal("Expected an error for an unknown shift")
+	}
+	if got := cs.Roster.GetByShift(99); len(got) != 0 {
+		t.Errorf("Expected nothing cached for shift 99, got %v", got)
+	}
+}
+
+func TestRosterCache_RefreshPicksUpOutsideChanges(t *testing.T) {
+	mem, cs := newTestStore(t)
+	ctx := context.Background()
+
+	// Written around the wrapper, as another process would
+	if err := mem.CreateRosterEntry(ctx, &models.RosterEntry{ShiftID: 2, RadiologistID: "rad2"}); err != nil {
+		t.Fatalf("CreateRosterEntry: %v", err)
+	}
+	if got := cs.Roster.GetByShift(2); len(got) != 0 {
+		t.Fatalf("Expected the change to be invisible before a refresh, got %v", rosterIDs(got))
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cs.Roster.Run(runCtx, 10*time.Millisecond)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for len(cs.Roster.GetByShift(2)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if got := rosterIDs(cs.Roster.GetByShift(2)); len(got) != 1 || got[0] != "rad2" {
+		t.Errorf("Expected rad2 on shift 2 after a refresh, got %v", got)
+	}
+}
+
+func TestStore_PushesRuleChanges(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+
+	rules := []*models.AssignmentRule{
+		{Name: "Second", PriorityOrder: 2, Enabled: true},
+		{Name: "First", PriorityOrder: 1, Enabled: true},
+		{Name: "Off", PriorityOrder: 0, Enabled: false},
+	}
+	for _, r := range rules {
+		if err := cs.CreateRule(ctx, r); err != nil {
+			t.Fatalf("CreateRule: %v", err)
+		}
+	}
+
+	names := func() []string {
+		var out []string
+		for _, r := range cs.Rules.GetActive() {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+	if got := names(); len(got) != 2 || got[0] != "First" || got[1] != "Second" {
+		t.Errorf("Expected [First Second], got %v", got)
+	}
+
+	rules[2].Enabled = true
+	if err := cs.UpdateRule(ctx, rules[2]); err != nil {
+		t.Fatalf("UpdateRule: %v", err)
+	}
+	rules[0].Enabled = false
+	if err := cs.UpdateRule(ctx, rules[0]); err != nil {
+		t.Fatalf("UpdateRule: %v", err)
+	}
+	if got := names(); len(got) != 2 || got[0] != "Off" || got[1] != "First" {
+		t.Errorf("Expected [Off First], got %v", got)
+	}
+
+	if err := cs.DeleteRule(ctx, rules[1].ID); err != nil {
+		t.Fatalf("DeleteRule: %v", err)
+	}
+	if got := names(); len(got) != 1 || got[0] != "Off" {
+		t.Errorf("Expected [Off], got %v", got)
+	}
+
+	// The pushed state should agree with a fresh load
+	if err := cs.Rules.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := names(); len(got) != 1 || got[0] != "Off" {
+		t.Errorf("Expected [Off] after refresh, got %v", got)
+	}
+}
+
+func TestStore_PushesShiftChanges(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+	ctQuery := models.ShiftQuery{Modality: "CT", BodyPart: "Head"}
+
+	if got := cs.Shifts.Match(ctQuery); len(got) != 2 {
+		t.Fatalf("Expected both CT shifts to be loaded, got %d", len(got))
+	}
+
+	neuro := &models.Shift{Name: "Neuro", Modalities: []string{"CT"}, BodyParts: []string{"Head"}, PriorityLevel: 5}
+	if err := cs.CreateShift(ctx, neuro); err != nil {
+		t.Fatalf("CreateShift: %v", err)
+	}
+	got, _ := cs.GetShiftsByWorkType(ctx, ctQuery)
+	if len(got) != 3 || got[0].ID != neuro.ID {
+		t.Fatalf("Expected the new shift first, got %+v", got)
+	}
+
+	neuro.BodyParts = []string{"Spine"}
+	if err := cs.UpdateShift(ctx, neuro); err != nil {
+		t.Fatalf("UpdateShift: %v", err)
+	}
+	if got, _ := cs.GetShiftsByWorkType(ctx, ctQuery); len(got) != 2 {
+		t.Errorf("Expected the edited shift to stop covering heads, got %d shifts", len(got))
+	}
+
+	if err := cs.DeleteShift(ctx, 1); err != nil {
+		t.Fatalf("DeleteShift: %v", err)
+	}
+	if got := cs.Shifts.Match(ctQuery); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("Expected only shift 2 left, got %+v", got)
+	}
+	if got := cs.Roster.GetByShift(1); len(got) != 0 {
+		t.Errorf("Expected the deleted shift's roster to go too, got %v", rosterIDs(got))
+	}
+}
+
+// The engine only reuses compiled rules from a versioned service
+var _ assignment.VersionedRulesService = (*RulesCache)(nil)
+
+func TestRulesCache_VersionMovesWithRules(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+
+	rules, v1 := cs.Rules.GetActiveVersion()
+	if len(rules) != 0 {
+		t.Fatalf("Expected no rules, got %d", len(rules))
+	}
+	if _, again := cs.Rules.GetActiveVersion(); again != v1 {
+		t.Errorf("Expected the version to hold without changes, got %d then %d", v1, again)
+	}
+
+	rule := &models.AssignmentRule{Name: "STAT", Enabled: true}
+	if err := cs.CreateRule(ctx, rule); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	rules, v2 := cs.Rules.GetActiveVersion()
+	if v2 <= v1 || len(rules) != 1 {
+		t.Errorf("Expected a new version with the rule, got version %d after %d with %d rules", v2, v1, len(rules))
+	}
+
+	if err := cs.DeleteRule(ctx, rule.ID); err != nil {
+		t.Fatalf("DeleteRule: %v", err)
+	}
+	if _, v3 := cs.Rules.GetActiveVersion(); v3 <= v2 {
+		t.Errorf("Expected the version to move on delete, got %d after %d", v3, v2)
+	}
+}
+
+func TestRulesCache_RefreshWithoutChangesKeepsVersion(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+	if err := cs.CreateRule(ctx, &models.AssignmentRule{Name: "STAT", Enabled: true}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if err := cs.Rules.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	_, v1 := cs.Rules.GetActiveVersion()
+
+	if err := cs.Rules.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, v2 := cs.Rules.GetActiveVersion(); v2 != v1 {
+		t.Errorf("Expected an unchanged refresh to keep version %d, got %d", v1, v2)
+	}
+}
+
+// racingRules calls during after reading the rules and before returning them,
+// as an event applied while a refresh is in flight would.
+type racingRules struct {
+	db.RuleRepository
+	during func()
+}
+
+func (r *racingRules) ListActiveRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	rules, err := r.RuleRepository.ListActiveRules(ctx)
+	if r.during != nil {
+		r.during()
+	}
+	return rules, err
+}
+
+func TestRulesCache_RefreshDoesNotOverwriteNewerApply(t *testing.T) {
+	ctx := context.Background()
+	repo := &racingRules{RuleRepository: db.NewMemoryStore()}
+	rc, err := NewRulesCache(ctx, repo)
+	if err != nil {
+		t.Fatalf("NewRulesCache: %v", err)
+	}
+
+	rule := &models.AssignmentRule{ID: 1, Name: "STAT", Enabled: true}
+	repo.during = func() { rc.Apply(RulesEvent{Type: RuleSaved, Rule: rule}) }
+	if err := rc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := rc.GetActive(); len(got) != 1 || got[0] != rule {
+		t.Errorf("Expected the applied rule to survive the refresh, got %v", got)
+	}
+}
+
+// racingRoster and racingShifts call during after reading from the store, as
+// racingRules does.
+type racingRoster struct {
+	db.RosterRepository
+	during func()
+}
+
+func (r *racingRoster) ListRoster(ctx context.Context) ([]*models.RosterEntry, error) {
+	entries, err := r.RosterRepository.ListRoster(ctx)
+	if r.during != nil {
+		r.during()
+	}
+	return entries, err
+}
+
+type racingShifts struct {
+	db.ShiftRepository
+	during func()
+}
+
+func (r *racingShifts) ListShifts(ctx context.Context) ([]*models.Shift, error) {
+	shifts, err := r.ShiftRepository.ListShifts(ctx)
+	if r.during != nil {
+		r.during()
+	}
+	return shifts, err
+}
+
+func TestRosterCache_RefreshDoesNotOverwriteNewerApply(t *testing.T) {
+	ctx := context.Background()
+	repo := &racingRoster{RosterRepository: db.NewMemoryStore()}
+	rc, err := NewRosterCache(ctx, repo)
+	if err != nil {
+		t.Fatalf("NewRosterCache: %v", err)
+	}
+
+	entry := &models.RosterEntry{ID: 1, ShiftID: 1, RadiologistID: "rad1"}
+	repo.during = func() { rc.Apply(RosterEvent{Type: RosterEntryAdded, Entry: entry}) }
+	if err := rc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := rc.GetByShift(1); len(got) != 1 || got[0] != entry {
+		t.Errorf("Expected the applied entry to survive the refresh, got %v", rosterIDs(got))
+	}
+}
+
+func TestShiftCache_RefreshDoesNotOverwriteNewerApply(t *testing.T) {
+	ctx := context.Background()
+	repo := &racingShifts{ShiftRepository: db.NewMemoryStore()}
+	sc, err := NewShiftCache(ctx, repo)
+	if err != nil {
+		t.Fatalf("NewShiftCache: %v", err)
+	}
+
+	shift := &models.Shift{ID: 1, Name: "Day CT", Modalities: []string{"CT"}}
+	repo.during = func() { sc.Apply(ShiftEvent{Type: ShiftSaved, Shift: shift}) }
+	if err := sc.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := sc.Match(models.ShiftQuery{Modality: "CT"}); len(got) != 1 || got[0] != shift {
+		t.Errorf("Expected the applied shift to survive the refresh, got %+v", got)
+	}
+}
+
+func TestCaches_RefreshWithoutChangesKeepsContents(t *testing.T) {
+	_, cs := newTestStore(t)
+	ctx := context.Background()
+	entries, index := cs.Roster.GetByShift(1), cs.Shifts.index
+
+	if err := cs.Roster.Refresh(ctx); err != nil {
+		t.Fatalf("Roster Refresh: %v", err)
+	}
+	if err := cs.Shifts.Refresh(ctx); err != nil {
+		t.Fatalf("Shifts Refresh: %v", err)
+	}
+	if got := cs.Roster.GetByShift(1); len(got) != 1 || got[0] != entries[0] {
+		t.Errorf("Expected an unchanged refresh to keep the cached roster")
+	}
+	if cs.Shifts.index != index {
+		t.Errorf("Expected an unchanged refresh to keep the shift index")
+	}
+}
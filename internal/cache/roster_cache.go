@@ -0,0 +1,218 @@
+// Package cache keeps the roster and the active rules in memory so the
+// assignment engine does not go to the store for every study. Caches reload
+// from the store on a timer and apply pushed change events in between.
+package cache
+
+import (
+	"context"
+	"log"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval keeps a cache that misses a change event within the
+// 30 second roster propagation limit (NFR-5.4.2).
+const DefaultRefreshInterval = 30 * time.Second
+
+type RosterEventType string
+
+const (
+	RosterEntryAdded   RosterEventType = "entry_added"
+	RosterEntryRemoved RosterEventType = "entry_removed"
+	ShiftRemoved       RosterEventType = "shift_removed"
+	RadiologistRemoved RosterEventType = "radiologist_removed"
+)
+
+// RosterEvent describes a committed roster change. Entry is set for
+// RosterEntryAdded, EntryID for RosterEntryRemoved, ShiftID for ShiftRemoved
+// and RadiologistID for RadiologistRemoved.
+type RosterEvent struct {
+	Type          RosterEventType
+	Entry         *models.RosterEntry
+	EntryID       int64
+	ShiftID       int64
+	RadiologistID string
+}
+
+// RosterCache indexes roster entries by shift and by radiologist. Slices
+// handed out by the getters are never modified afterwards; changes build new
+// slices, so callers can range over them without holding a lock.
+type RosterCache struct {
+	repo db.RosterRepository
+
+	mu            sync.RWMutex
+	byShift       map[int64][]*models.RosterEntry
+	byRadiologist map[string][]*models.RosterEntry
+	applied       uint64 // Events applied so far, so a refresh can tell it raced one
+	lastSync      time.Time
+}
+
+// NewRosterCache loads the roster from repo before returning.
+func NewRosterCache(ctx context.Context, repo db.RosterRepository) (*RosterCache, error) {
+	rc := &RosterCache{repo: repo}
+	if err := rc.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Refresh replaces the cached roster with the store's. Like RulesCache, a
+// refresh that overlapped an Apply is dropped, as what it read may predate
+// the change.
+func (rc *RosterCache) Refresh(ctx context.Context) error {
+	rc.mu.RLock()
+	applied := rc.applied
+	rc.mu.RUnlock()
+
+	entries, err := rc.repo.ListRoster(ctx)
+	if err != nil {
+		return err
+	}
+
+	byShift := make(map[int64][]*models.RosterEntry)
+	byRadiologist := make(map[string][]*models.RosterEntry)
+	for _, e := range entries {
+		byShift[e.ShiftID] = append(byShift[e.ShiftID], e)
+		byRadiologist[e.RadiologistID] = append(byRadiologist[e.RadiologistID], e)
+	}
+
+	rc.mu.Lock()
+	if rc.applied != applied {
+		rc.mu.Unlock()
+		return nil
+	}
+	rc.lastSync = time.Now()
+	changed := !reflect.DeepEqual(rc.byShift, byShift)
+	if changed {
+		rc.byShift = byShift
+		rc.byRadiologist = byRadiologist
+	}
+	rc.mu.Unlock()
+
+	if changed {
+		log.Printf("Roster cache refreshed with %d entries", len(entries))
+	}
+	return nil
+}
+
+// LastSync reports when the cache last reloaded from the store.
+func (rc *RosterCache) LastSync() time.Time {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.lastSync
+}
+
+func (rc *RosterCache) GetByShift(shiftID int64) []*models.RosterEntry {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	if entries, ok := rc.byShift[shiftID]; ok {
+		return entries
+	}
+	return []*models.RosterEntry{}
+}
+
+func (rc *RosterCache) GetByRadiologist(radiologistID string) []*models.RosterEntry {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	if entries, ok := rc.byRadiologist[radiologistID]; ok {
+		return entries
+	}
+	return []*models.RosterEntry{}
+}
+
+// Apply brings the cache up to date with a change that has already been
+// committed to the store.
+func (rc *RosterCache) Apply(ev RosterEvent) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	switch ev.Type {
+	case RosterEntryAdded:
+		e := ev.Entry
+		// A refresh racing the event may already have loaded the entry
+		rc.removeWhere(func(old *models.RosterEntry) bool { return old.ID == e.ID })
+		rc.byShift[e.ShiftID] = appendNew(rc.byShift[e.ShiftID], e)
+		rc.byRadiologist[e.RadiologistID] = appendNew(rc.byRadiologist[e.RadiologistID], e)
+	case RosterEntryRemoved:
+		rc.removeWhere(func(e *models.RosterEntry) bool { return e.ID == ev.EntryID })
+	case ShiftRemoved:
+		rc.removeWhere(func(e *models.RosterEntry) bool { return e.ShiftID == ev.ShiftID })
+	case RadiologistRemoved:
+		rc.removeWhere(func(e *models.RosterEntry) bool { return e.RadiologistID == ev.RadiologistID })
+	default:
+		log.Printf("Ignoring unknown roster event %q", ev.Type)
+		return
+	}
+	rc.applied++
+}
+
+// removeWhere drops matching entries from both indexes. Callers hold mu.
+func (rc *RosterCache) removeWhere(match func(*models.RosterEntry) bool) {
+	for shiftID, entries := range rc.byShift {
+		if kept, changed := filter(entries, match); changed {
+			if len(kept) == 0 {
+				delete(rc.byShift, shiftID)
+			} else {
+				rc.byShift[shiftID] = kept
+			}
+		}
+	}
+	for radID, entries := range rc.byRadiologist {
+		if kept, changed := filter(entries, match); changed {
+			if len(kept) == 0 {
+				delete(rc.byRadiologist, radID)
+			} else {
+				rc.byRadiologist[radID] = kept
+			}
+		}
+	}
+}
+
+// Run refreshes the cache every interval until ctx is cancelled.
+func (rc *RosterCache) Run(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, "roster", rc.Refresh)
+}
+
+// filter returns the entries that do not match in a new slice, or entries
+// itself when nothing matched.
+func filter(entries []*models.RosterEntry, match func(*models.RosterEntry) bool) ([]*models.RosterEntry, bool) {
+	var kept []*models.RosterEntry
+	changed := false
+	for _, e := range entries {
+		if match(e) {
+			changed = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if !changed {
+		return entries, false
+	}
+	return kept, true
+}
+
+// appendNew appends e without writing into the backing array of s, which
+// readers may still hold.
+func appendNew(s []*models.RosterEntry, e *models.RosterEntry) []*models.RosterEntry {
+	return append(s[:len(s):len(s)], e)
+}
+
+func runEvery(ctx context.Context, interval time.Duration, name string, refresh func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh %s cache: %v", name, err)
+			}
+		}
+	}
+}
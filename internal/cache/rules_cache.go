@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RulesEventType string
+
+const (
+	RuleSaved   RulesEventType = "rule_saved"
+	RuleRemoved RulesEventType = "rule_removed"
+)
+
+// RulesEvent describes a committed rule change. Rule is set for RuleSaved
+// and RuleID for RuleRemoved.
+type RulesEvent struct {
+	Type   RulesEventType
+	Rule   *models.AssignmentRule
+	RuleID int64
+}
+
+// RulesCache holds the enabled rules in evaluation order. Like RosterCache,
+// the slice returned by GetActive is replaced rather than modified.
+type RulesCache struct {
+	repo db.RuleRepository
+
+	mu       sync.RWMutex
+	rules    []*models.AssignmentRule
+	version  uint64 // Bumped whenever the rules change
+	applied  uint64 // Events applied so far, so a refresh can tell it raced one
+	lastSync time.Time
+}
+
+// NewRulesCache loads the active rules from repo before returning.
+func NewRulesCache(ctx context.Context, repo db.RuleRepository) (*RulesCache, error) {
+	rc := &RulesCache{repo: repo}
+	if err := rc.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Refresh replaces the cached rules with the store's active rules. The
+// version only moves when they differ from the cached ones, and a refresh that
+// overlapped an Apply is dropped, as what it read may predate the change.
+func (rc *RulesCache) Refresh(ctx context.Context) error {
+	rc.mu.RLock()
+	applied := rc.applied
+	rc.mu.RUnlock()
+
+	rules, err := rc.repo.ListActiveRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rc.mu.Lock()
+	if rc.applied != applied {
+		rc.mu.Unlock()
+		return nil
+	}
+	rc.lastSync = time.Now()
+	changed := !sameRules(rc.rules, rules)
+	if changed {
+		rc.rules = rules
+		rc.version++
+	}
+	rc.mu.Unlock()
+
+	if changed {
+		log.Printf("Rules cache refreshed with %d rules", len(rules))
+	}
+	return nil
+}
+
+// sameRules reports whether a and b hold equal rules in the same order.
+func sameRules(a, b []*models.AssignmentRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// LastSync reports when the cache last reloaded from the store.
+func (rc *RulesCache) LastSync() time.Time {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.lastSync
+}
+
+func (rc *RulesCache) GetActive() []*models.AssignmentRule {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.rules
+}
+
+// GetActiveVersion returns the rules with a version that changes whenever
+// they do, so callers can keep anything derived from them until it moves.
+func (rc *RulesCache) GetActiveVersion() ([]*models.AssignmentRule, uint64) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.rules, rc.version
+}
+
+// Apply brings the cache up to date with a change that has already been
+// committed to the store. A saved rule that is disabled drops out.
+func (rc *RulesCache) Apply(ev RulesEvent) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var id int64
+	switch ev.Type {
+	case RuleSaved:
+		id = ev.Rule.ID
+	case RuleRemoved:
+		id = ev.RuleID
+	default:
+		log.Printf("Ignoring unknown rules event %q", ev.Type)
+		return
+	}
+
+	rules := make([]*models.AssignmentRule, 0, len(rc.rules)+1)
+	for _, r := range rc.rules {
+		if r.ID != id {
+			rules = append(rules, r)
+		}
+	}
+	if ev.Type == RuleSaved && ev.Rule.Enabled {
+		rules = append(rules, ev.Rule)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].PriorityOrder != rules[j].PriorityOrder {
+			return rules[i].PriorityOrder < rules[j].PriorityOrder
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	rc.rules = rules
+	rc.version++
+	rc.applied++
+}
+
+// Run refreshes the cache every interval until ctx is cancelled.
+func (rc *RulesCache) Run(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, "rules", rc.Refresh)
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type ShiftEventType string
+
+const (
+	ShiftSaved   ShiftEventType = "shift_saved"
+	ShiftDeleted ShiftEventType = "shift_deleted"
+)
+
+// ShiftEvent describes a committed shift change. Shift is set for ShiftSaved
+// and ShiftID for ShiftDeleted.
+type ShiftEvent struct {
+	Type    ShiftEventType
+	Shift   *models.Shift
+	ShiftID int64
+}
+
+// ShiftCache keeps every shift in a db.ShiftIndex, so matching a study costs
+// a few bitset operations however many shifts are defined.
+type ShiftCache struct {
+	repo db.ShiftRepository
+
+	mu       sync.RWMutex
+	shifts   []*models.Shift // In ID order
+	index    *db.ShiftIndex
+	applied  uint64 // Events applied so far, so a refresh can tell it raced one
+	lastSync time.Time
+}
+
+// NewShiftCache loads the shifts from repo before returning.
+func NewShiftCache(ctx context.Context, repo db.ShiftRepository) (*ShiftCache, error) {
+	sc := &ShiftCache{repo: repo}
+	if err := sc.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// Refresh replaces the cached shifts with the store's. Like RulesCache, a
+// refresh that overlapped an Apply is dropped, as what it read may predate
+// the change.
+func (sc *ShiftCache) Refresh(ctx context.Context) error {
+	sc.mu.RLock()
+	applied := sc.applied
+	sc.mu.RUnlock()
+
+	shifts, err := sc.repo.ListShifts(ctx)
+	if err != nil {
+		return err
+	}
+
+	sc.mu.Lock()
+	if sc.applied != applied {
+		sc.mu.Unlock()
+		return nil
+	}
+	sc.lastSync = time.Now()
+	changed := sc.index == nil || !reflect.DeepEqual(sc.shifts, shifts)
+	if changed {
+		sc.shifts = shifts
+		sc.index = db.NewShiftIndex(shifts)
+	}
+	sc.mu.Unlock()
+
+	if changed {
+		log.Printf("Shift cache refreshed with %d shifts", len(shifts))
+	}
+	return nil
+}
+
+// LastSync reports when the cache last reloaded from the store.
+func (sc *ShiftCache) LastSync() time.Time {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.lastSync
+}
+
+// Match returns the shifts covering q, highest priority first. The shifts are
+// shared and must not be modified.
+func (sc *ShiftCache) Match(q models.ShiftQuery) []*models.Shift {
+	sc.mu.RLock()
+	index := sc.index
+	sc.mu.RUnlock()
+	return index.Match(q)
+}
+
+// Apply brings the cache up to date with a change that has already been
+// committed to the store.
+func (sc *ShiftCache) Apply(ev ShiftEvent) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var id int64
+	switch ev.Type {
+	case ShiftSaved:
+		id = ev.Shift.ID
+	case ShiftDeleted:
+		id = ev.ShiftID
+	default:
+		log.Printf("Ignoring unknown shift event %q", ev.Type)
+		return
+	}
+
+	shifts := make([]*models.Shift, 0, len(sc.shifts)+1)
+	inserted := false
+	for _, sh := range sc.shifts {
+		if ev.Type == ShiftSaved && !inserted && sh.ID >= id {
+			shifts = append(shifts, ev.Shift)
+			inserted = true
+		}
+		if sh.ID != id {
+			shifts = append(shifts, sh)
+		}
+	}
+	if ev.Type == ShiftSaved && !inserted {
+		shifts = append(shifts, ev.Shift)
+	}
+	sc.shifts = shifts
+	sc.index = db.NewShiftIndex(shifts)
+	sc.applied++
+}
+
+// Run refreshes the cache every interval until ctx is cancelled.
+func (sc *ShiftCache) Run(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, "shift", sc.Refresh)
+}
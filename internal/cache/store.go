@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"radiology-assignment/internal/db"
+	"radiology-assignment/internal/models"
+	"time"
+)
+
+// Store wraps a db.Store and pushes every committed shift, roster and rule
+// change into the caches, so edits reach the engine immediately instead of
+// on the next refresh. Shift matching is answered from the shift cache.
+type Store struct {
+	db.Store
+	Shifts *ShiftCache
+	Roster *RosterCache
+	Rules  *RulesCache
+}
+
+// NewStore loads the caches from s.
+func NewStore(ctx context.Context, s db.Store) (*Store, error) {
+	shifts, err := NewShiftCache(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	roster, err := NewRosterCache(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := NewRulesCache(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Store: s, Shifts: shifts, Roster: roster, Rules: rules}, nil
+}
+
+// Run refreshes every cache each interval until ctx is cancelled, picking up
+// changes made by other processes.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	go s.Shifts.Run(ctx, interval)
+	go s.Roster.Run(ctx, interval)
+	s.Rules.Run(ctx, interval)
+}
+
+// GetShiftsByWorkType matches against the shift cache. Callers get copies,
+// as they would from the store.
+func (s *Store) GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+	matched := s.Shifts.Match(q)
+	result := make([]*models.Shift, len(matched))
+	for i, sh := range matched {
+		c := *sh
+		result[i] = &c
+	}
+	return result, nil
+}
+
+func (s *Store) CreateShift(ctx context.Context, sh *models.Shift) error {
+	if err := s.Store.CreateShift(ctx, sh); err != nil {
+		return err
+	}
+	shift := *sh
+	s.Shifts.Apply(ShiftEvent{Type: ShiftSaved, Shift: &shift})
+	return nil
+}
+
+func (s *Store) UpdateShift(ctx context.Context, sh *models.Shift) error {
+	if err := s.Store.UpdateShift(ctx, sh); err != nil {
+		return err
+	}
+	shift := *sh
+	s.Shifts.Apply(ShiftEvent{Type: ShiftSaved, Shift: &shift})
+	return nil
+}
+
+func (s *Store) CreateRosterEntry(ctx context.Context, e *models.RosterEntry) error {
+	if err := s.Store.CreateRosterEntry(ctx, e); err != nil {
+		return err
+	}
+	entry := *e
+	s.Roster.Apply(RosterEvent{Type: RosterEntryAdded, Entry: &entry})
+	return nil
+}
+
+func (s *Store) DeleteRosterEntry(ctx context.Context, id int64) error {
+	if err := s.Store.DeleteRosterEntry(ctx, id); err != nil {
+		return err
+	}
+	s.Roster.Apply(RosterEvent{Type: RosterEntryRemoved, EntryID: id})
+	return nil
+}
+
+func (s *Store) DeleteShift(ctx context.Context, id int64) error {
+	if err := s.Store.DeleteShift(ctx, id); err != nil {
+		return err
+	}
+	s.Shifts.Apply(ShiftEvent{Type: ShiftDeleted, ShiftID: id})
+	s.Roster.Apply(RosterEvent{Type: ShiftRemoved, ShiftID: id})
+	return nil
+}
+
+func (s *Store) DeleteRadiologist(ctx context.Context, id string) error {
+	if err := s.Store.DeleteRadiologist(ctx, id); err != nil {
+		return err
+	}
+	s.Roster.Apply(RosterEvent{Type: RadiologistRemoved, RadiologistID: id})
+	return nil
+}
+
+func (s *Store) CreateRule(ctx context.Context, r *models.AssignmentRule) error {
+	if err := s.Store.CreateRule(ctx, r); err != nil {
+		return err
+	}
+	rule := *r
+	s.Rules.Apply(RulesEvent{Type: RuleSaved, Rule: &rule})
+	return nil
+}
+
+func (s *Store) UpdateRule(ctx context.Context, r *models.AssignmentRule) error {
+	if err := s.Store.UpdateRule(ctx, r); err != nil {
+		return err
+	}
+	rule := *r
+	s.Rules.Apply(RulesEvent{Type: RuleSaved, Rule: &rule})
+	return nil
+}
+
+func (s *Store) DeleteRule(ctx context.Context, id int64) error {
+	if err := s.Store.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+	s.Rules.Apply(RulesEvent{Type: RuleRemoved, RuleID: id})
+	return nil
+}
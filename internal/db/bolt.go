@@ -0,0 +1,818 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"radiology-assignment/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketShifts       = []byte("shifts")
+	bucketRoster       = []byte("roster")
+	bucketRules        = []byte("rules")
+	bucketAssignments  = []byte("assignments")
+	bucketStudyIndex   = []byte("assignments_by_study") // Study ID -> latest assignment key
+	bucketWorkload     = []byte("workload")             // Radiologist ID -> open assignment count
+	bucketStudies      = []byte("studies")
+	bucketRadiologists = []byte("radiologists")
+	bucketProcedures   = []byte("procedures") // Keyed by upper-cased code
+	bucketUnmapped     = []byte("unmapped_procedures")
+	bucketSites        = []byte("sites")
+	bucketModalities   = []byte("modalities")
+	bucketBodyParts    = []byte("body_parts")
+	bucketCredentials  = []byte("credentials")
+
+	allBuckets = [][]byte{
+		bucketShifts, bucketRoster, bucketRules, bucketAssignments, bucketStudyIndex, bucketWorkload,
+		bucketStudies, bucketRadiologists, bucketProcedures, bucketUnmapped,
+		bucketSites, bucketModalities, bucketBodyParts, bucketCredentials,
+	}
+)
+
+// BoltStore keeps everything in a single bbolt file, for sites that do not
+// run Postgres and for tests. Records are stored as JSON; IDs come from each
+// bucket's sequence so they are never reused after a delete.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var _ Store = (*BoltStore)(nil)
+
+// OpenBolt opens or creates the database file at path.
+func OpenBolt(path string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("db: create %s: %w", dir, err)
+		}
+	}
+	// The timeout stops a second process from blocking forever on the file lock
+	bdb, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("db: open %s: %w", path, err)
+	}
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		bdb.Close()
+		return nil, fmt.Errorf("db: create buckets: %w", err)
+	}
+	return &BoltStore{db: bdb}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// itob encodes an ID so keys sort numerically
+func itob(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("db: encode %q: %w", key, err)
+	}
+	return b.Put(key, data)
+}
+
+// get decodes the record at key, returning nil when there is none.
+func get[T any](b *bolt.Bucket, key []byte) (*T, error) {
+	data := b.Get(key)
+	if data == nil {
+		return nil, nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("db: decode %q: %w", key, err)
+	}
+	return &v, nil
+}
+
+// list decodes every record in the bucket in key order.
+func list[T any](b *bolt.Bucket) ([]*T, error) {
+	var result []*T
+	err := b.ForEach(func(k, data []byte) error {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("db: decode %q: %w", k, err)
+		}
+		result = append(result, &v)
+		return nil
+	})
+	return result, err
+}
+
+func nextID(b *bolt.Bucket) (int64, error) {
+	id, err := b.NextSequence()
+	return int64(id), err
+}
+
+// GetShiftsByWorkType indexes the stored shifts for each call; the engine
+// reads through cache.Store, which keeps the index between calls.
+func (s *BoltStore) GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+	all, err := s.ListShifts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewShiftIndex(all).Match(q), nil
+}
+
+// Shifts
+
+func (s *BoltStore) ListShifts(ctx context.Context) ([]*models.Shift, error) {
+	var result []*models.Shift
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.Shift](tx.Bucket(bucketShifts))
+		return err
+	})
+	return result, err
+}
+
+func (s *BoltStore) GetShift(ctx context.Context, id int64) (*models.Shift, error) {
+	var sh *models.Shift
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		sh, err = get[models.Shift](tx.Bucket(bucketShifts), itob(id))
+		return err
+	})
+	if err == nil && sh == nil {
+		return nil, fmt.Errorf("%w: shift %d", ErrNotFound, id)
+	}
+	return sh, err
+}
+
+func (s *BoltStore) CreateShift(ctx context.Context, sh *models.Shift) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketShifts)
+		if err := checkShiftName(b, sh.Name, 0); err != nil {
+			return err
+		}
+		id, err := nextID(b)
+		if err != nil {
+			return err
+		}
+		sh.ID = id
+		sh.CreatedAt = time.Now()
+		sh.UpdatedAt = sh.CreatedAt
+		return put(b, itob(id), sh)
+	})
+}
+
+func (s *BoltStore) UpdateShift(ctx context.Context, sh *models.Shift) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketShifts)
+		existing, err := get[models.Shift](b, itob(sh.ID))
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: shift %d", ErrNotFound, sh.ID)
+		}
+		if err := checkShiftName(b, sh.Name, sh.ID); err != nil {
+			return err
+		}
+		sh.CreatedAt = existing.CreatedAt
+		sh.UpdatedAt = time.Now()
+		return put(b, itob(sh.ID), sh)
+	})
+}
+
+// checkShiftName returns ErrDuplicate if a shift other than id is called
+// name, as the Postgres schema keeps shift names unique.
+func checkShiftName(b *bolt.Bucket, name string, id int64) error {
+	shifts, err := list[models.Shift](b)
+	if err != nil {
+		return err
+	}
+	for _, sh := range shifts {
+		if sh.Name == name && sh.ID != id {
+			return fmt.Errorf("%w: shift %q", ErrDuplicate, name)
+		}
+	}
+	return nil
+}
+
+// DeleteShift removes the shift and its roster entries.
+func (s *BoltStore) DeleteShift(ctx context.Context, id int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketShifts)
+		if b.Get(itob(id)) == nil {
+			return fmt.Errorf("%w: shift %d", ErrNotFound, id)
+		}
+		if err := b.Delete(itob(id)); err != nil {
+			return err
+		}
+		return deleteRosterWhere(tx, func(e *models.RosterEntry) bool { return e.ShiftID == id })
+	})
+}
+
+// Roster
+
+func deleteRosterWhere(tx *bolt.Tx, match func(*models.RosterEntry) bool) error {
+	b := tx.Bucket(bucketRoster)
+	entries, err := list[models.RosterEntry](b)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if match(e) {
+			if err := b.Delete(itob(e.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) ListRoster(ctx context.Context) ([]*models.RosterEntry, error) {
+	var result []*models.RosterEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.RosterEntry](tx.Bucket(bucketRoster))
+		return err
+	})
+	return result, err
+}
+
+func (s *BoltStore) ListRosterByShift(ctx context.Context, shiftID int64) ([]*models.RosterEntry, error) {
+	all, err := s.ListRoster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []*models.RosterEntry
+	for _, e := range all {
+		if e.ShiftID == shiftID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// CreateRosterEntry inserts e and sets its ID and timestamps. An empty status
+// is stored as active.
+func (s *BoltStore) CreateRosterEntry(ctx context.Context, e *models.RosterEntry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketShifts).Get(itob(e.ShiftID)) == nil {
+			return fmt.Errorf("%w: shift %d", ErrNotFound, e.ShiftID)
+		}
+		if tx.Bucket(bucketRadiologists).Get([]byte(e.RadiologistID)) == nil {
+			return fmt.Errorf("%w: radiologist %s", ErrNotFound, e.RadiologistID)
+		}
+		b := tx.Bucket(bucketRoster)
+		id, err := nextID(b)
+		if err != nil {
+			return err
+		}
+		e.ID = id
+		if e.Status == "" {
+			e.Status = "active"
+		}
+		if e.StartDate.IsZero() {
+			e.StartDate = time.Now()
+		}
+		e.CreatedAt = time.Now()
+		e.UpdatedAt = e.CreatedAt
+		return put(b, itob(id), e)
+	})
+}
+
+func (s *BoltStore) DeleteRosterEntry(ctx context.Context, id int64) error {
+	return s.deleteKey(bucketRoster, itob(id), fmt.Sprintf("roster entry %d", id))
+}
+
+// deleteKey removes one record, reporting ErrNotFound when it is missing.
+func (s *BoltStore) deleteKey(bucket, key []byte, what string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b.Get(key) == nil {
+			return fmt.Errorf("%w: %s", ErrNotFound, what)
+		}
+		return b.Delete(key)
+	})
+}
+
+// Rules
+
+// ListRules returns every rule in evaluation order.
+func (s *BoltStore) ListRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	var result []*models.AssignmentRule
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.AssignmentRule](tx.Bucket(bucketRules))
+		return err
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].PriorityOrder < result[j].PriorityOrder
+	})
+	return result, err
+}
+
+// ListActiveRules returns the enabled rules in evaluation order.
+func (s *BoltStore) ListActiveRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	all, err := s.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []*models.AssignmentRule
+	for _, r := range all {
+		if r.Enabled {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (s *BoltStore) GetRule(ctx context.Context, id int64) (*models.AssignmentRule, error) {
+	var r *models.AssignmentRule
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get[models.AssignmentRule](tx.Bucket(bucketRules), itob(id))
+		return err
+	})
+	if err == nil && r == nil {
+		return nil, fmt.Errorf("%w: rule %d", ErrNotFound, id)
+	}
+	return r, err
+}
+
+func (s *BoltStore) CreateRule(ctx context.Context, r *models.AssignmentRule) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRules)
+		id, err := nextID(b)
+		if err != nil {
+			return err
+		}
+		r.ID = id
+		r.CreatedAt = time.Now()
+		r.UpdatedAt = r.CreatedAt
+		return put(b, itob(id), r)
+	})
+}
+
+func (s *BoltStore) UpdateRule(ctx context.Context, r *models.AssignmentRule) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRules)
+		existing, err := get[models.AssignmentRule](b, itob(r.ID))
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: rule %d", ErrNotFound, r.ID)
+		}
+		r.CreatedAt = existing.CreatedAt
+		r.UpdatedAt = time.Now()
+		return put(b, itob(r.ID), r)
+	})
+}
+
+func (s *BoltStore) DeleteRule(ctx context.Context, id int64) error {
+	return s.deleteKey(bucketRules, itob(id), fmt.Sprintf("rule %d", id))
+}
+
+// Radiologists
+
+func (s *BoltStore) GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error) {
+	var r *models.Radiologist
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get[models.Radiologist](tx.Bucket(bucketRadiologists), []byte(id))
+		return err
+	})
+	if err == nil && r == nil {
+		return nil, fmt.Errorf("%w: radiologist %s", ErrNotFound, id)
+	}
+	return r, err
+}
+
+func (s *BoltStore) GetRadiologists(ctx context.Context, ids []string) ([]*models.Radiologist, error) {
+	var result []*models.Radiologist
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRadiologists)
+		for _, id := range ids {
+			r, err := get[models.Radiologist](b, []byte(id))
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				result = append(result, r)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (s *BoltStore) ListRadiologists(ctx context.Context) ([]*models.Radiologist, error) {
+	var result []*models.Radiologist
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.Radiologist](tx.Bucket(bucketRadiologists))
+		return err
+	})
+	return result, err
+}
+
+// SaveRadiologist inserts r or updates the radiologist with the same ID.
+func (s *BoltStore) SaveRadiologist(ctx context.Context, r *models.Radiologist) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRadiologists)
+		existing, err := get[models.Radiologist](b, []byte(r.ID))
+		if err != nil {
+			return err
+		}
+		r.UpdatedAt = time.Now()
+		r.CreatedAt = r.UpdatedAt
+		if existing != nil {
+			r.CreatedAt = existing.CreatedAt
+		}
+		return put(b, []byte(r.ID), r)
+	})
+}
+
+// DeleteRadiologist removes the radiologist and their roster entries.
+func (s *BoltStore) DeleteRadiologist(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRadiologists)
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: radiologist %s", ErrNotFound, id)
+		}
+		if err := b.Delete([]byte(id)); err != nil {
+			return err
+		}
+		return deleteRosterWhere(tx, func(e *models.RosterEntry) bool { return e.RadiologistID == id })
+	})
+}
+
+// Workload
+
+func readCount(b *bolt.Bucket, key string) int64 {
+	if v := b.Get([]byte(key)); len(v) == 8 {
+		return int64(binary.BigEndian.Uint64(v))
+	}
+	return 0
+}
+
+// adjustWorkload keeps the open assignment counter in step with status
+// changes, never going below zero.
+func adjustWorkload(tx *bolt.Tx, radiologistID string, delta int64) error {
+	b := tx.Bucket(bucketWorkload)
+	count := readCount(b, radiologistID) + delta
+	if count < 0 {
+		count = 0
+	}
+	return b.Put([]byte(radiologistID), itob(count))
+}
+
+func (s *BoltStore) GetRadiologistCurrentWorkload(ctx context.Context, radiologistID string) (int64, error) {
+	var count int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = readCount(tx.Bucket(bucketWorkload), radiologistID)
+		return nil
+	})
+	return count, err
+}
+
+func (s *BoltStore) GetRadiologistWorkloads(ctx context.Context, radiologistIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(radiologistIDs))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketWorkload)
+		for _, id := range radiologistIDs {
+			counts[id] = readCount(b, id)
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// Assignments
+
+// SaveAssignment inserts a and sets its ID and CreatedAt.
+func (s *BoltStore) SaveAssignment(ctx context.Context, a *models.Assignment) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketAssignments)
+		id, err := nextID(b)
+		if err != nil {
+			return err
+		}
+		a.ID = id
+		a.CreatedAt = time.Now()
+		if a.AssignedAt.IsZero() {
+			a.AssignedAt = a.CreatedAt
+		}
+		if a.Status == "" {
+			a.Status = models.StatusAssigned
+		}
+		if err := put(b, itob(id), a); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketStudyIndex).Put([]byte(a.StudyID), itob(id)); err != nil {
+			return err
+		}
+		if a.IsOpen() {
+			return adjustWorkload(tx, a.RadiologistID, 1)
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error) {
+	var a *models.Assignment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(bucketStudyIndex).Get([]byte(studyID))
+		if key == nil {
+			return nil
+		}
+		var err error
+		a, err = get[models.Assignment](tx.Bucket(bucketAssignments), key)
+		return err
+	})
+	return a, err
+}
+
+func (s *BoltStore) UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketAssignments)
+		a, err := get[models.Assignment](b, itob(assignmentID))
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return fmt.Errorf("%w: assignment %d", ErrNotFound, assignmentID)
+		}
+		wasOpen := a.IsOpen()
+		a.Status = status
+		a.StatusUpdatedAt = at
+		if err := put(b, itob(assignmentID), a); err != nil {
+			return err
+		}
+		switch {
+		case wasOpen && !a.IsOpen():
+			return adjustWorkload(tx, a.RadiologistID, -1)
+		case !wasOpen && a.IsOpen():
+			return adjustWorkload(tx, a.RadiologistID, 1)
+		}
+		return nil
+	})
+}
+
+// ListAssignments returns the most recent assignments first, at most limit
+// of them.
+func (s *BoltStore) ListAssignments(ctx context.Context, limit int) ([]*models.Assignment, error) {
+	var result []*models.Assignment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketAssignments).Cursor()
+		for k, data := c.Last(); k != nil && len(result) < limit; k, data = c.Prev() {
+			var a models.Assignment
+			if err := json.Unmarshal(data, &a); err != nil {
+				return fmt.Errorf("db: decode assignment: %w", err)
+			}
+			result = append(result, &a)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// CountAssignments reads the open total from the workload counters rather
+// than decoding every assignment.
+func (s *BoltStore) CountAssignments(ctx context.Context) (int64, int64, error) {
+	var total, open int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		total = int64(tx.Bucket(bucketAssignments).Stats().KeyN)
+		return tx.Bucket(bucketWorkload).ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				open += int64(binary.BigEndian.Uint64(v))
+			}
+			return nil
+		})
+	})
+	return total, open, err
+}
+
+// Studies
+
+func (s *BoltStore) GetStudy(ctx context.Context, id string) (*models.Study, error) {
+	var study *models.Study
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		study, err = get[models.Study](tx.Bucket(bucketStudies), []byte(id))
+		return err
+	})
+	return study, err
+}
+
+func (s *BoltStore) SaveStudy(ctx context.Context, study *models.Study) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(bucketStudies), []byte(study.ID), study)
+	})
+}
+
+// Procedures
+
+func procedureKey(code string) []byte {
+	return []byte(strings.ToUpper(code))
+}
+
+func (s *BoltStore) ListProcedures(ctx context.Context) ([]*models.Procedure, error) {
+	var result []*models.Procedure
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.Procedure](tx.Bucket(bucketProcedures))
+		return err
+	})
+	return result, err
+}
+
+// GetProcedureByCode matches the code case-insensitively and returns nil, nil
+// when it is not in the catalog.
+func (s *BoltStore) GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error) {
+	var p *models.Procedure
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		p, err = get[models.Procedure](tx.Bucket(bucketProcedures), procedureKey(code))
+		return err
+	})
+	return p, err
+}
+
+// SaveProcedure inserts p or updates the catalog entry with the same code,
+// and sets its ID and timestamps.
+func (s *BoltStore) SaveProcedure(ctx context.Context, p *models.Procedure) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketProcedures)
+		existing, err := get[models.Procedure](b, procedureKey(p.Code))
+		if err != nil {
+			return err
+		}
+		p.UpdatedAt = time.Now()
+		if existing != nil {
+			p.ID = existing.ID
+			p.CreatedAt = existing.CreatedAt
+		} else {
+			if p.ID, err = nextID(b); err != nil {
+				return err
+			}
+			p.CreatedAt = p.UpdatedAt
+		}
+		return put(b, procedureKey(p.Code), p)
+	})
+}
+
+func (s *BoltStore) DeleteProcedure(ctx context.Context, code string) error {
+	return s.deleteKey(bucketProcedures, procedureKey(code), "procedure "+code)
+}
+
+func (s *BoltStore) RecordUnmappedProcedure(ctx context.Context, study *models.Study) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketUnmapped)
+		key := []byte(models.UnmappedProcedureKey(study.ProcedureCode, study.ProcedureDescription))
+		u, err := get[models.UnmappedProcedure](b, key)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			u = &models.UnmappedProcedure{}
+		}
+		u.Record(study, time.Now())
+		return put(b, key, u)
+	})
+}
+
+// ListUnmappedProcedures returns the review queue, most frequent first.
+func (s *BoltStore) ListUnmappedProcedures(ctx context.Context) ([]*models.UnmappedProcedure, error) {
+	var result []*models.UnmappedProcedure
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		result, err = list[models.UnmappedProcedure](tx.Bucket(bucketUnmapped))
+		return err
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Occurrences > result[j].Occurrences
+	})
+	return result, err
+}
+
+func (s *BoltStore) DeleteUnmappedProcedure(ctx context.Context, key string) error {
+	return s.deleteKey(bucketUnmapped, []byte(key), "unmapped procedure "+key)
+}
+
+// Reference data
+
+// GetReferenceData loads the sites, modalities, body parts and credentials
+// edited on the config page.
+func (s *BoltStore) GetReferenceData(ctx context.Context) (*models.ReferenceData, error) {
+	data := &models.ReferenceData{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if err := listValues(tx.Bucket(bucketSites), &data.Sites); err != nil {
+			return err
+		}
+		if err := listValues(tx.Bucket(bucketModalities), &data.Modalities); err != nil {
+			return err
+		}
+		if err := listValues(tx.Bucket(bucketBodyParts), &data.BodyParts); err != nil {
+			return err
+		}
+		return listValues(tx.Bucket(bucketCredentials), &data.Credentials)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func listValues[T any](b *bolt.Bucket, out *[]T) error {
+	items, err := list[T](b)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		*out = append(*out, *item)
+	}
+	return nil
+}
+
+// The Save methods insert or update by code (name for body parts).
+
+func (s *BoltStore) SaveSite(ctx context.Context, site models.Site) error {
+	return s.saveReference(bucketSites, "", site.Code, site)
+}
+
+func (s *BoltStore) UpdateSite(ctx context.Context, originalCode string, site models.Site) error {
+	return s.saveReference(bucketSites, originalCode, site.Code, site)
+}
+
+func (s *BoltStore) DeleteSite(ctx context.Context, code string) error {
+	return s.deleteKey(bucketSites, []byte(code), "sites "+code)
+}
+
+func (s *BoltStore) SaveModality(ctx context.Context, m models.Modality) error {
+	return s.saveReference(bucketModalities, "", m.Code, m)
+}
+
+func (s *BoltStore) UpdateModality(ctx context.Context, originalCode string, m models.Modality) error {
+	return s.saveReference(bucketModalities, originalCode, m.Code, m)
+}
+
+func (s *BoltStore) DeleteModality(ctx context.Context, code string) error {
+	return s.deleteKey(bucketModalities, []byte(code), "modalities "+code)
+}
+
+func (s *BoltStore) SaveBodyPart(ctx context.Context, bp models.BodyPart) error {
+	return s.saveReference(bucketBodyParts, "", bp.Name, bp)
+}
+
+func (s *BoltStore) UpdateBodyPart(ctx context.Context, originalName string, bp models.BodyPart) error {
+	return s.saveReference(bucketBodyParts, originalName, bp.Name, bp)
+}
+
+func (s *BoltStore) DeleteBodyPart(ctx context.Context, name string) error {
+	return s.deleteKey(bucketBodyParts, []byte(name), "body_parts "+name)
+}
+
+func (s *BoltStore) SaveCredential(ctx context.Context, c models.Credential) error {
+	return s.saveReference(bucketCredentials, "", c.Code, c)
+}
+
+func (s *BoltStore) UpdateCredential(ctx context.Context, originalCode string, c models.Credential) error {
+	return s.saveReference(bucketCredentials, originalCode, c.Code, c)
+}
+
+func (s *BoltStore) DeleteCredential(ctx context.Context, code string) error {
+	return s.deleteKey(bucketCredentials, []byte(code), "credentials "+code)
+}
+
+// saveReference stores v under key. When original is set the entry must
+// already exist under that key and is moved if the key changed.
+func (s *BoltStore) saveReference(bucket []byte, original, key string, v interface{}) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if original != "" {
+			if b.Get([]byte(original)) == nil {
+				return fmt.Errorf("%w: %s %s", ErrNotFound, bucket, original)
+			}
+			if err := b.Delete([]byte(original)); err != nil {
+				return err
+			}
+		}
+		return put(b, []byte(key), v)
+	})
+}
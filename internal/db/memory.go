@@ -0,0 +1,766 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps everything in process memory, for demos and tests.
+// A single lock guards all of it. Records are copied on the way in and out,
+// slices and maps included, so callers never share state with the store or
+// with each other. IDs come
+// from per-aggregate counters and are never reused after a delete.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	shifts       []*models.Shift // In ID order
+	shiftIndex   *ShiftIndex     // Rebuilt on every shift write
+	roster       []*models.RosterEntry
+	rules        []*models.AssignmentRule
+	radiologists map[string]*models.Radiologist
+	assignments  []*models.Assignment
+	byStudy      map[string]*models.Assignment // Latest assignment per study
+	workload     map[string]int64              // Open assignments per radiologist
+	studies      map[string]*models.Study
+	procedures   map[string]*models.Procedure // Keyed by upper-cased code
+	unmapped     map[string]*models.UnmappedProcedure
+	ref          models.ReferenceData
+
+	lastShiftID, lastRosterID, lastRuleID, lastAssignmentID, lastProcedureID int64
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		shiftIndex:   NewShiftIndex(nil),
+		radiologists: make(map[string]*models.Radiologist),
+		byStudy:      make(map[string]*models.Assignment),
+		workload:     make(map[string]int64),
+		studies:      make(map[string]*models.Study),
+		procedures:   make(map[string]*models.Procedure),
+		unmapped:     make(map[string]*models.UnmappedProcedure),
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// copyOf returns a copy of v, or nil. The slices, maps and pointers of the
+// stored models are copied too, so neither side can change what the other
+// holds.
+func copyOf[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	switch x := any(&c).(type) {
+	case *models.Shift:
+		x.Modalities = append([]string(nil), x.Modalities...)
+		x.BodyParts = append([]string(nil), x.BodyParts...)
+		x.Urgencies = append([]string(nil), x.Urgencies...)
+		x.Sites = append([]string(nil), x.Sites...)
+		x.RequiredCredentials = append([]string(nil), x.RequiredCredentials...)
+		x.Days = append([]string(nil), x.Days...)
+	case *models.RosterEntry:
+		if x.EndDate != nil {
+			end := *x.EndDate
+			x.EndDate = &end
+		}
+	case *models.AssignmentRule:
+		if x.ConditionFilters != nil {
+			x.ConditionFilters = copyValue(x.ConditionFilters).(map[string]interface{})
+		}
+	case *models.Radiologist:
+		x.Credentials = append([]string(nil), x.Credentials...)
+		x.Specialties = append([]string(nil), x.Specialties...)
+	case *models.Assignment:
+		x.RulesFired = append([]int64(nil), x.RulesFired...)
+		if x.RuleMatchedID != nil {
+			id := *x.RuleMatchedID
+			x.RuleMatchedID = &id
+		}
+		x.Trace = copyTrace(x.Trace)
+	case *models.Study:
+		x.Attributes = append([]string(nil), x.Attributes...)
+	}
+	return &c
+}
+
+func copyTrace(tr *models.AssignmentTrace) *models.AssignmentTrace {
+	if tr == nil {
+		return nil
+	}
+	c := *tr
+	c.Shifts = append([]models.TraceShift(nil), tr.Shifts...)
+	c.Candidates = append([]models.TraceCandidate(nil), tr.Candidates...)
+	c.Rules = append([]models.TraceRule(nil), tr.Rules...)
+	for i := range c.Rules {
+		conditions := append([]models.TraceCondition(nil), c.Rules[i].Conditions...)
+		for j := range conditions {
+			conditions[j].Expected = copyValue(conditions[j].Expected)
+			conditions[j].Actual = copyValue(conditions[j].Actual)
+		}
+		c.Rules[i].Conditions = conditions
+	}
+	c.Removed = append([]models.TraceRemoval(nil), tr.Removed...)
+	c.Scores = append([]models.TraceScore(nil), tr.Scores...)
+	return &c
+}
+
+// copyValue copies the maps and slices within v, as found in rule conditions
+// decoded from JSON or written as Go literals. Anything else is returned as
+// is.
+func copyValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), copyElem(iter.Value(), rv.Type().Elem()))
+		}
+		return m.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			s.Index(i).Set(copyElem(rv.Index(i), rv.Type().Elem()))
+		}
+		return s.Interface()
+	}
+	return v
+}
+
+// copyElem copies one map or slice element, which has type t.
+func copyElem(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Kind() == reflect.Interface && v.IsNil() {
+		return reflect.Zero(t)
+	}
+	c := reflect.ValueOf(copyValue(v.Interface()))
+	if t.Kind() == reflect.Interface {
+		return c.Convert(t)
+	}
+	return c
+}
+
+func copyAll[T any](values []*T) []*T {
+	result := make([]*T, 0, len(values))
+	for _, v := range values {
+		result = append(result, copyOf(v))
+	}
+	return result
+}
+
+// Shifts
+
+func (s *MemoryStore) GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyAll(s.shiftIndex.Match(q)), nil
+}
+
+func (s *MemoryStore) ListShifts(ctx context.Context) ([]*models.Shift, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyAll(s.shifts), nil
+}
+
+// shiftNameTaken reports whether a shift other than id is called name, as
+// the Postgres schema keeps shift names unique. Callers hold mu.
+func (s *MemoryStore) shiftNameTaken(name string, id int64) bool {
+	for _, sh := range s.shifts {
+		if sh.Name == name && sh.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) findShift(id int64) int {
+	for i, sh := range s.shifts {
+		if sh.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetShift(ctx context.Context, id int64) (*models.Shift, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.findShift(id)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: shift %d", ErrNotFound, id)
+	}
+	return copyOf(s.shifts[i]), nil
+}
+
+func (s *MemoryStore) CreateShift(ctx context.Context, sh *models.Shift) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shiftNameTaken(sh.Name, 0) {
+		return fmt.Errorf("%w: shift %q", ErrDuplicate, sh.Name)
+	}
+	s.lastShiftID++
+	sh.ID = s.lastShiftID
+	sh.CreatedAt = time.Now()
+	sh.UpdatedAt = sh.CreatedAt
+	s.shifts = append(s.shifts, copyOf(sh))
+	s.shiftIndex = NewShiftIndex(s.shifts)
+	return nil
+}
+
+func (s *MemoryStore) UpdateShift(ctx context.Context, sh *models.Shift) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findShift(sh.ID)
+	if i < 0 {
+		return fmt.Errorf("%w: shift %d", ErrNotFound, sh.ID)
+	}
+	if s.shiftNameTaken(sh.Name, sh.ID) {
+		return fmt.Errorf("%w: shift %q", ErrDuplicate, sh.Name)
+	}
+	sh.CreatedAt = s.shifts[i].CreatedAt
+	sh.UpdatedAt = time.Now()
+	s.shifts[i] = copyOf(sh)
+	s.shiftIndex = NewShiftIndex(s.shifts)
+	return nil
+}
+
+func (s *MemoryStore) DeleteShift(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findShift(id)
+	if i < 0 {
+		return fmt.Errorf("%w: shift %d", ErrNotFound, id)
+	}
+	s.shifts = append(s.shifts[:i:i], s.shifts[i+1:]...)
+	s.shiftIndex = NewShiftIndex(s.shifts)
+	s.deleteRosterWhere(func(e *models.RosterEntry) bool { return e.ShiftID == id })
+	return nil
+}
+
+// Roster
+
+// deleteRosterWhere must be called with the write lock held.
+func (s *MemoryStore) deleteRosterWhere(match func(*models.RosterEntry) bool) {
+	kept := s.roster[:0:0]
+	for _, e := range s.roster {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	s.roster = kept
+}
+
+func (s *MemoryStore) ListRoster(ctx context.Context) ([]*models.RosterEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyAll(s.roster), nil
+}
+
+func (s *MemoryStore) ListRosterByShift(ctx context.Context, shiftID int64) ([]*models.RosterEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []*models.RosterEntry
+	for _, e := range s.roster {
+		if e.ShiftID == shiftID {
+			result = append(result, copyOf(e))
+		}
+	}
+	return result, nil
+}
+
+// CreateRosterEntry inserts e and sets its ID and timestamps. An empty status
+// is stored as active.
+func (s *MemoryStore) CreateRosterEntry(ctx context.Context, e *models.RosterEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findShift(e.ShiftID) < 0 {
+		return fmt.Errorf("%w: shift %d", ErrNotFound, e.ShiftID)
+	}
+	if _, ok := s.radiologists[e.RadiologistID]; !ok {
+		return fmt.Errorf("%w: radiologist %s", ErrNotFound, e.RadiologistID)
+	}
+	s.lastRosterID++
+	e.ID = s.lastRosterID
+	if e.Status == "" {
+		e.Status = "active"
+	}
+	if e.StartDate.IsZero() {
+		e.StartDate = time.Now()
+	}
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = e.CreatedAt
+	s.roster = append(s.roster, copyOf(e))
+	return nil
+}
+
+func (s *MemoryStore) DeleteRosterEntry(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.roster {
+		if e.ID == id {
+			s.roster = append(s.roster[:i:i], s.roster[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: roster entry %d", ErrNotFound, id)
+}
+
+// Rules
+
+// ListRules returns every rule in evaluation order.
+func (s *MemoryStore) ListRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	s.mu.RLock()
+	result := copyAll(s.rules)
+	s.mu.RUnlock()
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].PriorityOrder < result[j].PriorityOrder
+	})
+	return result, nil
+}
+
+// ListActiveRules returns the enabled rules in evaluation order.
+func (s *MemoryStore) ListActiveRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	all, _ := s.ListRules(ctx)
+	var result []*models.AssignmentRule
+	for _, r := range all {
+		if r.Enabled {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) findRule(id int64) int {
+	for i, r := range s.rules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetRule(ctx context.Context, id int64) (*models.AssignmentRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.findRule(id)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: rule %d", ErrNotFound, id)
+	}
+	return copyOf(s.rules[i]), nil
+}
+
+func (s *MemoryStore) CreateRule(ctx context.Context, r *models.AssignmentRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRuleID++
+	r.ID = s.lastRuleID
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	s.rules = append(s.rules, copyOf(r))
+	return nil
+}
+
+func (s *MemoryStore) UpdateRule(ctx context.Context, r *models.AssignmentRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findRule(r.ID)
+	if i < 0 {
+		return fmt.Errorf("%w: rule %d", ErrNotFound, r.ID)
+	}
+	r.CreatedAt = s.rules[i].CreatedAt
+	r.UpdatedAt = time.Now()
+	s.rules[i] = copyOf(r)
+	return nil
+}
+
+func (s *MemoryStore) DeleteRule(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findRule(id)
+	if i < 0 {
+		return fmt.Errorf("%w: rule %d", ErrNotFound, id)
+	}
+	s.rules = append(s.rules[:i:i], s.rules[i+1:]...)
+	return nil
+}
+
+// Radiologists
+
+func (s *MemoryStore) GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.radiologists[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: radiologist %s", ErrNotFound, id)
+	}
+	return copyOf(r), nil
+}
+
+func (s *MemoryStore) GetRadiologists(ctx context.Context, ids []string) ([]*models.Radiologist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []*models.Radiologist
+	for _, id := range ids {
+		if r, ok := s.radiologists[id]; ok {
+			result = append(result, copyOf(r))
+		}
+	}
+	return result, nil
+}
+
+// ListRadiologists returns the radiologists ordered by ID.
+func (s *MemoryStore) ListRadiologists(ctx context.Context) ([]*models.Radiologist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*models.Radiologist, 0, len(s.radiologists))
+	for _, r := range s.radiologists {
+		result = append(result, copyOf(r))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// SaveRadiologist inserts r or updates the radiologist with the same ID.
+func (s *MemoryStore) SaveRadiologist(ctx context.Context, r *models.Radiologist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.UpdatedAt = time.Now()
+	r.CreatedAt = r.UpdatedAt
+	if existing, ok := s.radiologists[r.ID]; ok {
+		r.CreatedAt = existing.CreatedAt
+	}
+	s.radiologists[r.ID] = copyOf(r)
+	return nil
+}
+
+func (s *MemoryStore) DeleteRadiologist(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.radiologists[id]; !ok {
+		return fmt.Errorf("%w: radiologist %s", ErrNotFound, id)
+	}
+	delete(s.radiologists, id)
+	s.deleteRosterWhere(func(e *models.RosterEntry) bool { return e.RadiologistID == id })
+	return nil
+}
+
+// Assignments
+
+// SaveAssignment inserts a and sets its ID and CreatedAt.
+func (s *MemoryStore) SaveAssignment(ctx context.Context, a *models.Assignment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAssignmentID++
+	a.ID = s.lastAssignmentID
+	a.CreatedAt = time.Now()
+	if a.AssignedAt.IsZero() {
+		a.AssignedAt = a.CreatedAt
+	}
+	if a.Status == "" {
+		a.Status = models.StatusAssigned
+	}
+	stored := copyOf(a)
+	s.assignments = append(s.assignments, stored)
+	s.byStudy[a.StudyID] = stored
+	if a.IsOpen() {
+		s.workload[a.RadiologistID]++
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyOf(s.byStudy[studyID]), nil
+}
+
+func (s *MemoryStore) UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Assignments are appended in ID order
+	i := sort.Search(len(s.assignments), func(i int) bool { return s.assignments[i].ID >= assignmentID })
+	if i == len(s.assignments) || s.assignments[i].ID != assignmentID {
+		return fmt.Errorf("%w: assignment %d", ErrNotFound, assignmentID)
+	}
+	a := s.assignments[i]
+	wasOpen := a.IsOpen()
+	a.Status = status
+	a.StatusUpdatedAt = at
+	switch {
+	case wasOpen && !a.IsOpen() && s.workload[a.RadiologistID] > 0:
+		s.workload[a.RadiologistID]--
+	case !wasOpen && a.IsOpen():
+		s.workload[a.RadiologistID]++
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetRadiologistCurrentWorkload(ctx context.Context, radiologistID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workload[radiologistID], nil
+}
+
+func (s *MemoryStore) GetRadiologistWorkloads(ctx context.Context, radiologistIDs []string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int64, len(radiologistIDs))
+	for _, id := range radiologistIDs {
+		counts[id] = s.workload[id]
+	}
+	return counts, nil
+}
+
+// ListAssignments returns the most recent assignments first, at most limit
+// of them.
+func (s *MemoryStore) ListAssignments(ctx context.Context, limit int) ([]*models.Assignment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if limit > len(s.assignments) {
+		limit = len(s.assignments)
+	}
+	result := make([]*models.Assignment, 0, limit)
+	for i := len(s.assignments) - 1; len(result) < limit; i-- {
+		result = append(result, copyOf(s.assignments[i]))
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) CountAssignments(ctx context.Context) (int64, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var open int64
+	for _, n := range s.workload {
+		open += n
+	}
+	return int64(len(s.assignments)), open, nil
+}
+
+// Studies
+
+func (s *MemoryStore) GetStudy(ctx context.Context, id string) (*models.Study, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyOf(s.studies[id]), nil
+}
+
+func (s *MemoryStore) SaveStudy(ctx context.Context, study *models.Study) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.studies[study.ID] = copyOf(study)
+	return nil
+}
+
+// Procedures
+
+// ListProcedures returns the catalog ordered by code.
+func (s *MemoryStore) ListProcedures(ctx context.Context) ([]*models.Procedure, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*models.Procedure, 0, len(s.procedures))
+	for _, p := range s.procedures {
+		result = append(result, copyOf(p))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result, nil
+}
+
+// GetProcedureByCode matches the code case-insensitively and returns nil, nil
+// when it is not in the catalog.
+func (s *MemoryStore) GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyOf(s.procedures[strings.ToUpper(code)]), nil
+}
+
+// SaveProcedure inserts p or updates the catalog entry with the same code,
+// and sets its ID and timestamps.
+func (s *MemoryStore) SaveProcedure(ctx context.Context, p *models.Procedure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToUpper(p.Code)
+	p.UpdatedAt = time.Now()
+	if existing, ok := s.procedures[key]; ok {
+		p.ID = existing.ID
+		p.CreatedAt = existing.CreatedAt
+	} else {
+		s.lastProcedureID++
+		p.ID = s.lastProcedureID
+		p.CreatedAt = p.UpdatedAt
+	}
+	s.procedures[key] = copyOf(p)
+	return nil
+}
+
+func (s *MemoryStore) DeleteProcedure(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToUpper(code)
+	if _, ok := s.procedures[key]; !ok {
+		return fmt.Errorf("%w: procedure %s", ErrNotFound, code)
+	}
+	delete(s.procedures, key)
+	return nil
+}
+
+func (s *MemoryStore) RecordUnmappedProcedure(ctx context.Context, study *models.Study) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := models.UnmappedProcedureKey(study.ProcedureCode, study.ProcedureDescription)
+	u, ok := s.unmapped[key]
+	if !ok {
+		u = &models.UnmappedProcedure{}
+		s.unmapped[key] = u
+	}
+	u.Record(study, time.Now())
+	return nil
+}
+
+// ListUnmappedProcedures returns the review queue, most frequent first.
+func (s *MemoryStore) ListUnmappedProcedures(ctx context.Context) ([]*models.UnmappedProcedure, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*models.UnmappedProcedure, 0, len(s.unmapped))
+	for _, u := range s.unmapped {
+		// The sample slices are appended to by Record, so copy them too
+		c := *u
+		c.Sites = append([]string(nil), u.Sites...)
+		c.SampleStudyIDs = append([]string(nil), u.SampleStudyIDs...)
+		c.SampleMessages = append([]string(nil), u.SampleMessages...)
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Occurrences != result[j].Occurrences {
+			return result[i].Occurrences > result[j].Occurrences
+		}
+		return result[i].Key() < result[j].Key()
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteUnmappedProcedure(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.unmapped[key]; !ok {
+		return fmt.Errorf("%w: unmapped procedure %s", ErrNotFound, key)
+	}
+	delete(s.unmapped, key)
+	return nil
+}
+
+// Reference data
+
+func (s *MemoryStore) GetReferenceData(ctx context.Context) (*models.ReferenceData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &models.ReferenceData{
+		Sites:       append([]models.Site(nil), s.ref.Sites...),
+		Modalities:  append([]models.Modality(nil), s.ref.Modalities...),
+		BodyParts:   append([]models.BodyPart(nil), s.ref.BodyParts...),
+		Credentials: append([]models.Credential(nil), s.ref.Credentials...),
+	}, nil
+}
+
+func siteCode(x models.Site) string             { return x.Code }
+func modalityCode(x models.Modality) string     { return x.Code }
+func bodyPartName(x models.BodyPart) string     { return x.Name }
+func credentialCode(x models.Credential) string { return x.Code }
+
+func (s *MemoryStore) SaveSite(ctx context.Context, site models.Site) error {
+	return saveRef(&s.mu, &s.ref.Sites, "", site, siteCode)
+}
+
+func (s *MemoryStore) UpdateSite(ctx context.Context, originalCode string, site models.Site) error {
+	return saveRef(&s.mu, &s.ref.Sites, originalCode, site, siteCode)
+}
+
+func (s *MemoryStore) DeleteSite(ctx context.Context, code string) error {
+	return deleteRef(&s.mu, &s.ref.Sites, "sites", code, siteCode)
+}
+
+func (s *MemoryStore) SaveModality(ctx context.Context, m models.Modality) error {
+	return saveRef(&s.mu, &s.ref.Modalities, "", m, modalityCode)
+}
+
+func (s *MemoryStore) UpdateModality(ctx context.Context, originalCode string, m models.Modality) error {
+	return saveRef(&s.mu, &s.ref.Modalities, originalCode, m, modalityCode)
+}
+
+func (s *MemoryStore) DeleteModality(ctx context.Context, code string) error {
+	return deleteRef(&s.mu, &s.ref.Modalities, "modalities", code, modalityCode)
+}
+
+func (s *MemoryStore) SaveBodyPart(ctx context.Context, bp models.BodyPart) error {
+	return saveRef(&s.mu, &s.ref.BodyParts, "", bp, bodyPartName)
+}
+
+func (s *MemoryStore) UpdateBodyPart(ctx context.Context, originalName string, bp models.BodyPart) error {
+	return saveRef(&s.mu, &s.ref.BodyParts, originalName, bp, bodyPartName)
+}
+
+func (s *MemoryStore) DeleteBodyPart(ctx context.Context, name string) error {
+	return deleteRef(&s.mu, &s.ref.BodyParts, "body_parts", name, bodyPartName)
+}
+
+func (s *MemoryStore) SaveCredential(ctx context.Context, c models.Credential) error {
+	return saveRef(&s.mu, &s.ref.Credentials, "", c, credentialCode)
+}
+
+func (s *MemoryStore) UpdateCredential(ctx context.Context, originalCode string, c models.Credential) error {
+	return saveRef(&s.mu, &s.ref.Credentials, originalCode, c, credentialCode)
+}
+
+func (s *MemoryStore) DeleteCredential(ctx context.Context, code string) error {
+	return deleteRef(&s.mu, &s.ref.Credentials, "credentials", code, credentialCode)
+}
+
+// saveRef stores v in place of the entry with the same key, or appends it.
+// When original is set that entry must already exist and v replaces it, key
+// changes included, matching BoltStore.saveReference.
+func saveRef[T any](mu *sync.RWMutex, list *[]T, original string, v T, key func(T) string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	find := key(v)
+	if original != "" {
+		find = original
+	}
+	for i, existing := range *list {
+		if key(existing) == find {
+			(*list)[i] = v
+			return nil
+		}
+	}
+	if original != "" {
+		return fmt.Errorf("%w: %s", ErrNotFound, original)
+	}
+	*list = append(*list, v)
+	return nil
+}
+
+func deleteRef[T any](mu *sync.RWMutex, list *[]T, table, k string, key func(T) string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, existing := range *list {
+		if key(existing) == k {
+			*list = append((*list)[:i:i], (*list)[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s %s", ErrNotFound, table, k)
+}
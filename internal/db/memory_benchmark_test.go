@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"testing"
+	"time"
+)
+
+func BenchmarkMemoryStore_GetRadiologist(b *testing.B) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	numRadiologists := 10000
+	for i := 0; i < numRadiologists; i++ {
+		store.SaveRadiologist(ctx, &models.Radiologist{
+			ID:                   fmt.Sprintf("rad%d", i),
+			FirstName:            "Test",
+			LastName:             "User",
+			MaxConcurrentStudies: 5,
+		})
+	}
+	targetID := fmt.Sprintf("rad%d", numRadiologists-1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.GetRadiologist(ctx, targetID); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkMemoryStore_GetRadiologistCurrentWorkload(b *testing.B) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	radID := "rad1"
+
+	// A mix of the target radiologist and others
+	for i := 0; i < 10000; i++ {
+		store.SaveAssignment(ctx, &models.Assignment{RadiologistID: radID, AssignedAt: time.Now()})
+	}
+	for i := 0; i < 5000; i++ {
+		store.SaveAssignment(ctx, &models.Assignment{RadiologistID: "other_rad", AssignedAt: time.Now()})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = store.GetRadiologistCurrentWorkload(ctx, radID)
+	}
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_later.sql":   {Data: []byte("SELECT 10;")},
+		"m/0002_second.sql":  {Data: []byte("SELECT 2;")},
+		"m/0001_initial.sql": {Data: []byte("SELECT 1;")},
+		"m/README.md":        {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var versions []int
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	if len(versions) != 3 || versions[0] != 1 || versions[1] != 2 || versions[2] != 10 {
+		t.Errorf("Expected versions [1 2 10], got %v", versions)
+	}
+	if migrations[0].SQL != "SELECT 1;" {
+		t.Errorf("Expected SQL of 0001_initial.sql, got %q", migrations[0].SQL)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "missing version separator",
+			files: fstest.MapFS{"m/initial.sql": {}},
+			want:  "name must be",
+		},
+		{
+			name:  "non-numeric version",
+			files: fstest.MapFS{"m/v1_initial.sql": {}},
+			want:  "invalid version",
+		},
+		{
+			name:  "duplicate version",
+			files: fstest.MapFS{"m/0001_a.sql": {}, "m/1_b.sql": {}},
+			want:  "share version 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %s", i, i+1, m.Name)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+-- Schema from architecture.md section 4.1. Timestamps are TIMESTAMPTZ so
+-- values round-trip with their offset regardless of the server time zone.
+
+CREATE TABLE shifts (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    work_type VARCHAR(100) NOT NULL,
+    sites TEXT[] NOT NULL,
+    priority_level INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE radiologists (
+    id VARCHAR(50) PRIMARY KEY,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    credentials TEXT[],
+    specialties TEXT[],
+    max_concurrent_studies INTEGER DEFAULT 20,
+    status VARCHAR(20) DEFAULT 'active',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE roster_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    shift_id BIGINT NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    radiologist_id VARCHAR(50) NOT NULL REFERENCES radiologists(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    status VARCHAR(20) DEFAULT 'active',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE assignment_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    priority_order INTEGER NOT NULL,
+    condition_filters JSONB NOT NULL,
+    action_type VARCHAR(50) NOT NULL,
+    action_target VARCHAR(255),
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- A study may be assigned more than once (an XO change cancels and
+-- reassigns), so study_id is not unique. Worklist assignments carry no
+-- radiologist or shift, hence no foreign keys on those columns.
+CREATE TABLE study_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    study_id VARCHAR(100) NOT NULL,
+    radiologist_id VARCHAR(50) NOT NULL,
+    shift_id BIGINT REFERENCES shifts(id) ON DELETE SET NULL,
+    assigned_at TIMESTAMPTZ DEFAULT NOW(),
+    escalated BOOLEAN DEFAULT FALSE,
+    assignment_strategy VARCHAR(50),
+    rule_matched_id BIGINT REFERENCES assignment_rules(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(50),
+    entity_id VARCHAR(100),
+    action VARCHAR(50),
+    old_values JSONB,
+    new_values JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE procedures (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    modality VARCHAR(50),
+    body_part VARCHAR(50),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_procedures_code ON procedures(code);
+CREATE INDEX idx_roster_shift ON roster_assignments(shift_id);
+CREATE INDEX idx_roster_radiologist ON roster_assignments(radiologist_id);
+CREATE INDEX idx_study_assignments_study ON study_assignments(study_id);
+CREATE INDEX idx_study_assignments_radiologist ON study_assignments(radiologist_id);
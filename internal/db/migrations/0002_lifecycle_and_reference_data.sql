@@ -0,0 +1,53 @@
+-- Columns and tables added since the original schema: shift credentials,
+-- assignment lifecycle, stored orders, the unmapped procedure queue and the
+-- reference data edited on /config.
+
+ALTER TABLE shifts ADD COLUMN required_credentials TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE study_assignments ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'assigned';
+ALTER TABLE study_assignments ADD COLUMN status_updated_at TIMESTAMPTZ;
+
+-- Workload queries only count open assignments
+CREATE INDEX idx_study_assignments_open ON study_assignments(radiologist_id)
+    WHERE status IN ('assigned', 'in_progress');
+
+-- Latest normalized order per study, compared against on XO changes
+CREATE TABLE studies (
+    id VARCHAR(100) PRIMARY KEY,
+    data JSONB NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE unmapped_procedures (
+    code VARCHAR(50) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    modality VARCHAR(50),
+    body_part VARCHAR(50),
+    sites TEXT[] NOT NULL DEFAULT '{}',
+    occurrences BIGINT NOT NULL DEFAULT 0,
+    first_seen TIMESTAMPTZ,
+    last_seen TIMESTAMPTZ,
+    sample_study_ids TEXT[] NOT NULL DEFAULT '{}',
+    sample_messages TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (code, description)
+);
+
+CREATE TABLE sites (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE modalities (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE body_parts (
+    name VARCHAR(50) PRIMARY KEY,
+    search_string TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE credentials (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,8 @@
+-- Shift operating hours. Times are HH:MM wall-clock strings in time_zone, an
+-- IANA zone name; empty values keep a shift running around the clock in the
+-- server's zone.
+
+ALTER TABLE shifts ADD COLUMN start_time VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE shifts ADD COLUMN end_time VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE shifts ADD COLUMN days TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE shifts ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT '';
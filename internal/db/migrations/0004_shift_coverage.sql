@@ -0,0 +1,6 @@
+-- Structured shift coverage. An empty set or one containing '*' matches any
+-- value; shifts without modalities still match on work_type.
+
+ALTER TABLE shifts ADD COLUMN modalities TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE shifts ADD COLUMN body_parts TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE shifts ADD COLUMN urgencies TEXT[] NOT NULL DEFAULT '{}';
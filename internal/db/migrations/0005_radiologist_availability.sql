@@ -0,0 +1,6 @@
+-- Radiologist availability (on_shift, available, break, offline). at_capacity
+-- is derived from open workload and never stored; an empty value counts as
+-- available.
+
+ALTER TABLE radiologists ADD COLUMN availability TEXT NOT NULL DEFAULT '';
+ALTER TABLE radiologists ADD COLUMN availability_changed_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Decision trace for assignments made in explain mode (see
+-- models.AssignmentTrace); NULL for everything else.
+
+ALTER TABLE study_assignments ADD COLUMN trace JSONB;
@@ -0,0 +1,5 @@
+-- Every rule that fired for an assignment, in evaluation order, and the
+-- worklist it was sent to. rule_matched_id keeps the rule that decided it.
+
+ALTER TABLE study_assignments ADD COLUMN rules_fired BIGINT[] NOT NULL DEFAULT '{}';
+ALTER TABLE study_assignments ADD COLUMN worklist VARCHAR(100) NOT NULL DEFAULT '';
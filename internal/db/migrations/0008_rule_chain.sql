@@ -0,0 +1,5 @@
+-- How a matching rule affects the rules after it: continue, terminal or
+-- fallback. Soft rules skip a filter that would leave no candidates.
+
+ALTER TABLE assignment_rules ADD COLUMN chain VARCHAR(20) NOT NULL DEFAULT 'continue';
+ALTER TABLE assignment_rules ADD COLUMN soft BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const procedureColumns = `id, code, COALESCE(description, ''), COALESCE(modality, ''), COALESCE(body_part, ''), created_at, updated_at`
+
+func scanProcedure(row pgx.CollectableRow) (*models.Procedure, error) {
+	var p models.Procedure
+	err := row.Scan(&p.ID, &p.Code, &p.Description, &p.Modality, &p.BodyPart, &p.CreatedAt, &p.UpdatedAt)
+	return &p, err
+}
+
+func (s *PostgresStore) ListProcedures(ctx context.Context) ([]*models.Procedure, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+procedureColumns+` FROM procedures ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanProcedure)
+}
+
+// GetProcedureByCode matches the code case-insensitively and returns nil, nil
+// when it is not in the catalog.
+func (s *PostgresStore) GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+procedureColumns+` FROM procedures
+		WHERE UPPER(code) = UPPER($1) ORDER BY id LIMIT 1`, code)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pgx.CollectOneRow(rows, scanProcedure)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// SaveProcedure inserts p or updates the catalog entry with the same code,
+// and sets its ID and timestamps.
+func (s *PostgresStore) SaveProcedure(ctx context.Context, p *models.Procedure) error {
+	return s.pool.QueryRow(ctx, `INSERT INTO procedures (code, description, modality, body_part)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO UPDATE SET
+			description = EXCLUDED.description,
+			modality = EXCLUDED.modality,
+			body_part = EXCLUDED.body_part,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		p.Code, p.Description, p.Modality, p.BodyPart,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (s *PostgresStore) DeleteProcedure(ctx context.Context, code string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM procedures WHERE UPPER(code) = UPPER($1)`, code)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: procedure %s", ErrNotFound, code)
+	}
+	return nil
+}
+
+// Unmapped procedure review queue
+
+const unmappedColumns = `code, description, COALESCE(modality, ''), COALESCE(body_part, ''), sites, occurrences,
+	first_seen, last_seen, sample_study_ids, sample_messages`
+
+func scanUnmapped(row pgx.CollectableRow) (*models.UnmappedProcedure, error) {
+	var u models.UnmappedProcedure
+	var firstSeen, lastSeen *time.Time
+	err := row.Scan(&u.Code, &u.Description, &u.Modality, &u.BodyPart, &u.Sites, &u.Occurrences,
+		&firstSeen, &lastSeen, &u.SampleStudyIDs, &u.SampleMessages)
+	if firstSeen != nil {
+		u.FirstSeen = *firstSeen
+	}
+	if lastSeen != nil {
+		u.LastSeen = *lastSeen
+	}
+	return &u, err
+}
+
+// RecordUnmappedProcedure counts another occurrence of the study's procedure.
+// The row is locked while the sample lists are updated so concurrent engines
+// do not lose counts.
+func (s *PostgresStore) RecordUnmappedProcedure(ctx context.Context, study *models.Study) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Make sure the row exists so it can be locked
+	if _, err := tx.Exec(ctx, `INSERT INTO unmapped_procedures (code, description) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, study.ProcedureCode, study.ProcedureDescription); err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, `SELECT `+unmappedColumns+` FROM unmapped_procedures
+		WHERE code = $1 AND description = $2 FOR UPDATE`, study.ProcedureCode, study.ProcedureDescription)
+	if err != nil {
+		return err
+	}
+	u, err := pgx.CollectOneRow(rows, scanUnmapped)
+	if err != nil {
+		return err
+	}
+
+	u.Record(study, time.Now())
+
+	if _, err := tx.Exec(ctx, `UPDATE unmapped_procedures
+		SET modality = $3, body_part = $4, sites = $5, occurrences = $6, first_seen = $7, last_seen = $8,
+			sample_study_ids = $9, sample_messages = $10
+		WHERE code = $1 AND description = $2`,
+		u.Code, u.Description, u.Modality, u.BodyPart, nonNil(u.Sites), u.Occurrences, u.FirstSeen, u.LastSeen,
+		nonNil(u.SampleStudyIDs), nonNil(u.SampleMessages)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListUnmappedProcedures returns the review queue, most frequent first.
+func (s *PostgresStore) ListUnmappedProcedures(ctx context.Context) ([]*models.UnmappedProcedure, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+unmappedColumns+` FROM unmapped_procedures
+		ORDER BY occurrences DESC, code, description`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanUnmapped)
+}
+
+func (s *PostgresStore) DeleteUnmappedProcedure(ctx context.Context, key string) error {
+	// Codes and descriptions come from HL7 fields, which cannot contain the
+	// key's | separator
+	code, description, _ := strings.Cut(key, "|")
+	tag, err := s.pool.Exec(ctx, `DELETE FROM unmapped_procedures WHERE code = $1 AND description = $2`, code, description)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: unmapped procedure %s", ErrNotFound, key)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const radiologistColumns = `id, COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(credentials, '{}'),
+	COALESCE(specialties, '{}'), COALESCE(max_concurrent_studies, 0), COALESCE(status, ''), availability,
+	availability_changed_at, created_at, updated_at`
+
+func scanRadiologist(row pgx.CollectableRow) (*models.Radiologist, error) {
+	var r models.Radiologist
+	var changedAt *time.Time
+	err := row.Scan(&r.ID, &r.FirstName, &r.LastName, &r.Credentials, &r.Specialties, &r.MaxConcurrentStudies,
+		&r.Status, &r.Availability, &changedAt, &r.CreatedAt, &r.UpdatedAt)
+	if changedAt != nil {
+		r.AvailabilityChangedAt = *changedAt
+	}
+	return &r, err
+}
+
+func (s *PostgresStore) ListRadiologists(ctx context.Context) ([]*models.Radiologist, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+radiologistColumns+` FROM radiologists ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRadiologist)
+}
+
+// SaveRadiologist inserts r or updates the radiologist with the same ID.
+func (s *PostgresStore) SaveRadiologist(ctx context.Context, r *models.Radiologist) error {
+	return s.pool.QueryRow(ctx, `INSERT INTO radiologists
+		(id, first_name, last_name, credentials, specialties, max_concurrent_studies, status,
+			availability, availability_changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			first_name = EXCLUDED.first_name,
+			last_name = EXCLUDED.last_name,
+			credentials = EXCLUDED.credentials,
+			specialties = EXCLUDED.specialties,
+			max_concurrent_studies = EXCLUDED.max_concurrent_studies,
+			status = EXCLUDED.status,
+			availability = EXCLUDED.availability,
+			availability_changed_at = EXCLUDED.availability_changed_at,
+			updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		r.ID, r.FirstName, r.LastName, nonNil(r.Credentials), nonNil(r.Specialties), r.MaxConcurrentStudies, r.Status,
+		r.Availability, nullTime(r.AvailabilityChangedAt),
+	).Scan(&r.CreatedAt, &r.UpdatedAt)
+}
+
+// DeleteRadiologist removes the radiologist and their roster entries.
+func (s *PostgresStore) DeleteRadiologist(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM radiologists WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: radiologist %s", ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"radiology-assignment/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetReferenceData loads the sites, modalities, body parts and credentials
+// edited on the config page.
+func (s *PostgresStore) GetReferenceData(ctx context.Context) (*models.ReferenceData, error) {
+	var data models.ReferenceData
+
+	rows, err := s.pool.Query(ctx, `SELECT code, name FROM sites ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	if data.Sites, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.Site]); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT code, name FROM modalities ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	if data.Modalities, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.Modality]); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT name, search_string FROM body_parts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	if data.BodyParts, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.BodyPart]); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT code, name FROM credentials ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	if data.Credentials, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.Credential]); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// The Save methods insert or update by code (name for body parts).
+
+func (s *PostgresStore) SaveSite(ctx context.Context, site models.Site) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO sites (code, name) VALUES ($1, $2)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name`, site.Code, site.Name)
+	return err
+}
+
+func (s *PostgresStore) UpdateSite(ctx context.Context, originalCode string, site models.Site) error {
+	return s.updateReference(ctx, `UPDATE sites SET code = $2, name = $3 WHERE code = $1`, originalCode, site.Code, site.Name)
+}
+
+func (s *PostgresStore) DeleteSite(ctx context.Context, code string) error {
+	return s.deleteReference(ctx, "sites", "code", code)
+}
+
+func (s *PostgresStore) SaveModality(ctx context.Context, m models.Modality) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO modalities (code, name) VALUES ($1, $2)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name`, m.Code, m.Name)
+	return err
+}
+
+func (s *PostgresStore) UpdateModality(ctx context.Context, originalCode string, m models.Modality) error {
+	return s.updateReference(ctx, `UPDATE modalities SET code = $2, name = $3 WHERE code = $1`, originalCode, m.Code, m.Name)
+}
+
+func (s *PostgresStore) DeleteModality(ctx context.Context, code string) error {
+	return s.deleteReference(ctx, "modalities", "code", code)
+}
+
+func (s *PostgresStore) SaveBodyPart(ctx context.Context, bp models.BodyPart) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO body_parts (name, search_string) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET search_string = EXCLUDED.search_string`, bp.Name, bp.SearchString)
+	return err
+}
+
+func (s *PostgresStore) UpdateBodyPart(ctx context.Context, originalName string, bp models.BodyPart) error {
+	return s.updateReference(ctx, `UPDATE body_parts SET name = $2, search_string = $3 WHERE name = $1`, originalName, bp.Name, bp.SearchString)
+}
+
+func (s *PostgresStore) DeleteBodyPart(ctx context.Context, name string) error {
+	return s.deleteReference(ctx, "body_parts", "name", name)
+}
+
+func (s *PostgresStore) SaveCredential(ctx context.Context, c models.Credential) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO credentials (code, name) VALUES ($1, $2)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name`, c.Code, c.Name)
+	return err
+}
+
+func (s *PostgresStore) UpdateCredential(ctx context.Context, originalCode string, c models.Credential) error {
+	return s.updateReference(ctx, `UPDATE credentials SET code = $2, name = $3 WHERE code = $1`, originalCode, c.Code, c.Name)
+}
+
+func (s *PostgresStore) DeleteCredential(ctx context.Context, code string) error {
+	return s.deleteReference(ctx, "credentials", "code", code)
+}
+
+// updateReference runs an UPDATE keyed by the original code or name, which
+// the update may itself change.
+func (s *PostgresStore) updateReference(ctx context.Context, sql, original, key, value string) error {
+	tag, err := s.pool.Exec(ctx, sql, original, key, value)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, original)
+	}
+	return nil
+}
+
+// deleteReference removes one row by key. table and column are constants
+// supplied by the callers above, never user input.
+func (s *PostgresStore) deleteReference(ctx context.Context, table, column, key string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM `+table+` WHERE `+column+` = $1`, key)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s %s", ErrNotFound, table, key)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const ruleColumns = `id, name, priority_order, condition_filters, action_type, COALESCE(action_target, ''),
+	chain, soft, COALESCE(enabled, TRUE), created_at, updated_at`
+
+func scanRule(row pgx.CollectableRow) (*models.AssignmentRule, error) {
+	var r models.AssignmentRule
+	err := row.Scan(&r.ID, &r.Name, &r.PriorityOrder, &r.ConditionFilters, &r.ActionType, &r.ActionTarget,
+		&r.Chain, &r.Soft, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	return &r, err
+}
+
+// conditionFilters keeps a nil filter map out of the NOT NULL column
+func conditionFilters(r *models.AssignmentRule) map[string]interface{} {
+	if r.ConditionFilters == nil {
+		return map[string]interface{}{}
+	}
+	return r.ConditionFilters
+}
+
+// ListRules returns every rule in evaluation order.
+func (s *PostgresStore) ListRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+ruleColumns+` FROM assignment_rules ORDER BY priority_order, id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRule)
+}
+
+// ListActiveRules returns the enabled rules in evaluation order.
+func (s *PostgresStore) ListActiveRules(ctx context.Context) ([]*models.AssignmentRule, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+ruleColumns+` FROM assignment_rules
+		WHERE enabled ORDER BY priority_order, id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRule)
+}
+
+func (s *PostgresStore) GetRule(ctx context.Context, id int64) (*models.AssignmentRule, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+ruleColumns+` FROM assignment_rules WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := pgx.CollectOneRow(rows, scanRule)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: rule %d", ErrNotFound, id)
+	}
+	return r, err
+}
+
+// CreateRule inserts r and sets its ID and timestamps.
+func (s *PostgresStore) CreateRule(ctx context.Context, r *models.AssignmentRule) error {
+	return s.pool.QueryRow(ctx, `INSERT INTO assignment_rules
+		(name, priority_order, condition_filters, action_type, action_target, chain, soft, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		r.Name, r.PriorityOrder, conditionFilters(r), r.ActionType, r.ActionTarget, r.ChainMode(), r.Soft, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (s *PostgresStore) UpdateRule(ctx context.Context, r *models.AssignmentRule) error {
+	err := s.pool.QueryRow(ctx, `UPDATE assignment_rules
+		SET name = $2, priority_order = $3, condition_filters = $4, action_type = $5, action_target = $6,
+			chain = $7, soft = $8, enabled = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		r.ID, r.Name, r.PriorityOrder, conditionFilters(r), r.ActionType, r.ActionTarget, r.ChainMode(), r.Soft, r.Enabled,
+	).Scan(&r.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: rule %d", ErrNotFound, r.ID)
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteRule(ctx context.Context, id int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM assignment_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: rule %d", ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"math/bits"
+	"radiology-assignment/internal/models"
+	"sort"
+	"strings"
+)
+
+// ShiftIndex answers shift coverage queries without scanning every shift.
+// Each coverage dimension maps a value to the set of shifts naming it, plus
+// the set of wildcard shifts; a query intersects one union per dimension.
+// It is immutable once built and safe for concurrent use.
+type ShiftIndex struct {
+	shifts     []*models.Shift // Highest priority first
+	modalities dimension
+	bodyParts  dimension
+	urgencies  dimension
+	sites      dimension
+}
+
+type dimension struct {
+	foldCase bool
+	values   map[string]bitset
+	any      bitset
+}
+
+// NewShiftIndex indexes the shifts that take work, i.e. those with a
+// priority of zero or more.
+func NewShiftIndex(shifts []*models.Shift) *ShiftIndex {
+	var eligible []*models.Shift
+	for _, sh := range shifts {
+		if sh.PriorityLevel >= 0 {
+			eligible = append(eligible, sh)
+		}
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return eligible[i].PriorityLevel > eligible[j].PriorityLevel
+	})
+
+	idx := &ShiftIndex{
+		shifts:     eligible,
+		modalities: newDimension(len(eligible), true),
+		bodyParts:  newDimension(len(eligible), true),
+		urgencies:  newDimension(len(eligible), true),
+		sites:      newDimension(len(eligible), false),
+	}
+	for i, sh := range eligible {
+		idx.modalities.add(i, sh.CoveredModalities())
+		idx.bodyParts.add(i, sh.BodyParts)
+		idx.urgencies.add(i, sh.Urgencies)
+		idx.sites.add(i, sh.Sites)
+	}
+	return idx
+}
+
+// Len returns the number of indexed shifts.
+func (idx *ShiftIndex) Len() int {
+	return len(idx.shifts)
+}
+
+// Match returns the shifts covering q, highest priority first. The shifts
+// are shared with the index and must not be modified.
+func (idx *ShiftIndex) Match(q models.ShiftQuery) []*models.Shift {
+	if len(idx.shifts) == 0 {
+		return nil
+	}
+	result := newBitset(len(idx.shifts))
+	result.fill(len(idx.shifts))
+	idx.modalities.narrow(result, q.Modality)
+	idx.bodyParts.narrow(result, q.BodyPart)
+	idx.urgencies.narrow(result, q.Urgency)
+	idx.sites.narrow(result, q.Site)
+
+	var matched []*models.Shift
+	result.each(func(i int) {
+		matched = append(matched, idx.shifts[i])
+	})
+	return matched
+}
+
+func newDimension(n int, foldCase bool) dimension {
+	return dimension{foldCase: foldCase, values: make(map[string]bitset), any: newBitset(n)}
+}
+
+func (d *dimension) key(v string) string {
+	if d.foldCase {
+		return strings.ToUpper(v)
+	}
+	return v
+}
+
+func (d *dimension) add(i int, set []string) {
+	if models.IsWildcard(set) {
+		d.any.set(i)
+		return
+	}
+	for _, v := range set {
+		k := d.key(v)
+		b, ok := d.values[k]
+		if !ok {
+			b = newBitset(len(d.any) * 64)
+			d.values[k] = b
+		}
+		b.set(i)
+	}
+}
+
+// narrow keeps the shifts in result that cover value. An empty value is
+// not filtered on.
+func (d *dimension) narrow(result bitset, value string) {
+	if value == "" {
+		return
+	}
+	named := d.values[d.key(value)]
+	for w := range result {
+		var v uint64
+		if named != nil {
+			v = named[w]
+		}
+		result[w] &= v | d.any[w]
+	}
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+// fill sets the first n bits.
+func (b bitset) fill(n int) {
+	for i := range b {
+		b[i] = ^uint64(0)
+	}
+	if rem := n % 64; rem != 0 {
+		b[len(b)-1] = 1<<rem - 1
+	}
+}
+
+func (b bitset) each(fn func(int)) {
+	for w, word := range b {
+		for word != 0 {
+			fn(w*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
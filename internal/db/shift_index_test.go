@@ -0,0 +1,133 @@
+package db
+
+import (
+	"fmt"
+	"math/rand"
+	"radiology-assignment/internal/models"
+	"testing"
+)
+
+var (
+	indexModalities = []string{"CT", "CTA", "MRI", "US", "XR", "NM"}
+	indexBodyParts  = []string{"Head", "Chest", "Abdomen", "Spine", "Knee", "Pelvis"}
+	indexUrgencies  = []string{"STAT", "ASAP", "ROUTINE"}
+)
+
+// randomShifts builds n shifts with random coverage; roughly one in five sets
+// is a wildcard.
+func randomShifts(rng *rand.Rand, n int) []*models.Shift {
+	pick := func(values []string) []string {
+		if rng.Intn(5) == 0 {
+			if rng.Intn(2) == 0 {
+				return nil
+			}
+			return []string{models.Wildcard}
+		}
+		var set []string
+		for _, v := range values {
+			if rng.Intn(3) == 0 {
+				set = append(set, v)
+			}
+		}
+		if set == nil {
+			set = []string{values[rng.Intn(len(values))]}
+		}
+		return set
+	}
+
+	shifts := make([]*models.Shift, n)
+	for i := range shifts {
+		shifts[i] = &models.Shift{
+			ID:            int64(i + 1),
+			Name:          fmt.Sprintf("Shift %d", i+1),
+			Modalities:    pick(indexModalities),
+			BodyParts:     pick(indexBodyParts),
+			Urgencies:     pick(indexUrgencies),
+			Sites:         pick([]string{"SiteA", "SiteB", "SiteC", "SiteD"}),
+			PriorityLevel: rng.Intn(12) - 1,
+		}
+	}
+	return shifts
+}
+
+func TestShiftIndex_MatchesCovers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	shifts := randomShifts(rng, 300)
+	idx := NewShiftIndex(shifts)
+
+	for i := 0; i < 500; i++ {
+		q := models.ShiftQuery{
+			Modality: indexModalities[rng.Intn(len(indexModalities))],
+			BodyPart: indexBodyParts[rng.Intn(len(indexBodyParts))],
+			Urgency:  indexUrgencies[rng.Intn(len(indexUrgencies))],
+			Site:     []string{"SiteA", "SiteB", "SiteX", ""}[rng.Intn(4)],
+		}
+		if rng.Intn(4) == 0 {
+			q.BodyPart = ""
+		}
+
+		// Reference result: a linear scan in priority order
+		var want []int64
+		for p := 11; p >= 0; p-- {
+			for _, sh := range shifts {
+				if sh.PriorityLevel == p && sh.Covers(q) {
+					want = append(want, sh.ID)
+				}
+			}
+		}
+		var got []int64
+		for _, sh := range idx.Match(q) {
+			got = append(got, sh.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("%+v: expected %v, got %v", q, want, got)
+		}
+	}
+}
+
+func TestShiftIndex_Matching(t *testing.T) {
+	shifts := []*models.Shift{
+		{ID: 1, Name: "CT", Modalities: []string{"CT"}},
+		{ID: 2, Name: "CTA", Modalities: []string{"CTA"}},
+		{ID: 3, Name: "Legacy MRI", WorkType: "MRI"},
+		{ID: 4, Name: "Anything", Modalities: []string{models.Wildcard}},
+		{ID: 5, Name: "Neuro", Modalities: []string{"ct", "MRI"}, BodyParts: []string{"Head"}, Urgencies: []string{"STAT"}},
+	}
+	idx := NewShiftIndex(shifts)
+
+	tests := []struct {
+		name  string
+		query models.ShiftQuery
+		want  string
+	}{
+		{"CT does not match CTA", models.ShiftQuery{Modality: "CT", BodyPart: "Chest"}, "[1 4]"},
+		{"CTA does not match CT", models.ShiftQuery{Modality: "CTA"}, "[2 4]"},
+		{"work type stands in for modalities", models.ShiftQuery{Modality: "mri", BodyPart: "Knee"}, "[3 4]"},
+		{"all dimensions must match", models.ShiftQuery{Modality: "CT", BodyPart: "head", Urgency: "STAT"}, "[1 4 5]"},
+		{"urgency outside the set", models.ShiftQuery{Modality: "CT", BodyPart: "Head", Urgency: "ROUTINE"}, "[1 4]"},
+		{"unknown modality", models.ShiftQuery{Modality: "PET"}, "[4]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int64
+			for _, sh := range idx.Match(tt.query) {
+				ids = append(ids, sh.ID)
+			}
+			if got := fmt.Sprint(ids); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// NFR-5.2.2 sizes the shift catalogue at 500+ shifts
+func BenchmarkShiftIndex_Match(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	idx := NewShiftIndex(randomShifts(rng, 600))
+	q := models.ShiftQuery{Modality: "MRI", BodyPart: "Knee", Urgency: "ROUTINE", Site: "SiteB"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Match(q)
+	}
+}
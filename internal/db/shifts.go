@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const shiftColumns = `id, name, work_type, modalities, body_parts, urgencies, sites, COALESCE(priority_level, 0),
+	required_credentials, start_time, end_time, days, time_zone, created_at, updated_at`
+
+func scanShift(row pgx.CollectableRow) (*models.Shift, error) {
+	var sh models.Shift
+	err := row.Scan(&sh.ID, &sh.Name, &sh.WorkType, &sh.Modalities, &sh.BodyParts, &sh.Urgencies, &sh.Sites,
+		&sh.PriorityLevel, &sh.RequiredCredentials,
+		&sh.StartTime, &sh.EndTime, &sh.Days, &sh.TimeZone, &sh.CreatedAt, &sh.UpdatedAt)
+	return &sh, err
+}
+
+func collectShifts(rows pgx.Rows) ([]*models.Shift, error) {
+	return pgx.CollectRows(rows, scanShift)
+}
+
+func (s *PostgresStore) ListShifts(ctx context.Context) ([]*models.Shift, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+shiftColumns+` FROM shifts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return collectShifts(rows)
+}
+
+func (s *PostgresStore) GetShift(ctx context.Context, id int64) (*models.Shift, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+shiftColumns+` FROM shifts WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := pgx.CollectOneRow(rows, scanShift)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: shift %d", ErrNotFound, id)
+	}
+	return sh, err
+}
+
+// CreateShift inserts sh and sets its ID and timestamps.
+func (s *PostgresStore) CreateShift(ctx context.Context, sh *models.Shift) error {
+	err := s.pool.QueryRow(ctx, `INSERT INTO shifts
+		(name, work_type, modalities, body_parts, urgencies, sites, priority_level, required_credentials,
+		 start_time, end_time, days, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at`,
+		sh.Name, sh.WorkType, nonNil(sh.Modalities), nonNil(sh.BodyParts), nonNil(sh.Urgencies), nonNil(sh.Sites),
+		sh.PriorityLevel, nonNil(sh.RequiredCredentials), sh.StartTime, sh.EndTime, nonNil(sh.Days), sh.TimeZone,
+	).Scan(&sh.ID, &sh.CreatedAt, &sh.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: shift %q", ErrDuplicate, sh.Name)
+	}
+	return err
+}
+
+func (s *PostgresStore) UpdateShift(ctx context.Context, sh *models.Shift) error {
+	err := s.pool.QueryRow(ctx, `UPDATE shifts
+		SET name = $2, work_type = $3, modalities = $4, body_parts = $5, urgencies = $6, sites = $7,
+			priority_level = $8, required_credentials = $9,
+			start_time = $10, end_time = $11, days = $12, time_zone = $13, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`,
+		sh.ID, sh.Name, sh.WorkType, nonNil(sh.Modalities), nonNil(sh.BodyParts), nonNil(sh.Urgencies), nonNil(sh.Sites),
+		sh.PriorityLevel, nonNil(sh.RequiredCredentials), sh.StartTime, sh.EndTime, nonNil(sh.Days), sh.TimeZone,
+	).Scan(&sh.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: shift %d", ErrNotFound, sh.ID)
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: shift %q", ErrDuplicate, sh.Name)
+	}
+	return err
+}
+
+// DeleteShift removes the shift and its roster entries.
+func (s *PostgresStore) DeleteShift(ctx context.Context, id int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM shifts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: shift %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Roster
+
+const rosterColumns = `id, shift_id, radiologist_id, start_date, end_date, COALESCE(status, ''), created_at, updated_at`
+
+func scanRosterEntry(row pgx.CollectableRow) (*models.RosterEntry, error) {
+	var e models.RosterEntry
+	err := row.Scan(&e.ID, &e.ShiftID, &e.RadiologistID, &e.StartDate, &e.EndDate, &e.Status, &e.CreatedAt, &e.UpdatedAt)
+	return &e, err
+}
+
+func (s *PostgresStore) ListRoster(ctx context.Context) ([]*models.RosterEntry, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+rosterColumns+` FROM roster_assignments ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRosterEntry)
+}
+
+func (s *PostgresStore) ListRosterByShift(ctx context.Context, shiftID int64) ([]*models.RosterEntry, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+rosterColumns+` FROM roster_assignments WHERE shift_id = $1 ORDER BY id`, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanRosterEntry)
+}
+
+// CreateRosterEntry inserts e and sets its ID and timestamps. An empty status
+// is stored as active.
+func (s *PostgresStore) CreateRosterEntry(ctx context.Context, e *models.RosterEntry) error {
+	if e.Status == "" {
+		e.Status = "active"
+	}
+	startDate := e.StartDate
+	if startDate.IsZero() {
+		startDate = time.Now()
+	}
+	err := s.pool.QueryRow(ctx, `INSERT INTO roster_assignments (shift_id, radiologist_id, start_date, end_date, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, start_date, created_at, updated_at`,
+		e.ShiftID, e.RadiologistID, startDate, e.EndDate, e.Status,
+	).Scan(&e.ID, &e.StartDate, &e.CreatedAt, &e.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("%w: shift %d or radiologist %s", ErrNotFound, e.ShiftID, e.RadiologistID)
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteRosterEntry(ctx context.Context, id int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM roster_assignments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: roster entry %d", ErrNotFound, id)
+	}
+	return nil
+}
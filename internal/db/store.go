@@ -0,0 +1,183 @@
+// Package db persists the assignment engine's data in PostgreSQL, in an
+// embedded single-file bbolt database, or in memory for demos and tests.
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"radiology-assignment/internal/assignment"
+	"radiology-assignment/internal/models"
+	"time"
+)
+
+// The repositories below group the store's methods by aggregate, so callers
+// can depend on just the part they use. Every backend implements all of them
+// through Store.
+
+type ShiftRepository interface {
+	// GetShiftsByWorkType returns the shifts whose coverage takes q, highest
+	// priority first, whatever their operating hours
+	GetShiftsByWorkType(ctx context.Context, q models.ShiftQuery) ([]*models.Shift, error)
+	ListShifts(ctx context.Context) ([]*models.Shift, error)
+	GetShift(ctx context.Context, id int64) (*models.Shift, error)
+	CreateShift(ctx context.Context, sh *models.Shift) error
+	UpdateShift(ctx context.Context, sh *models.Shift) error
+	// DeleteShift also removes the shift's roster entries
+	DeleteShift(ctx context.Context, id int64) error
+}
+
+type RosterRepository interface {
+	ListRoster(ctx context.Context) ([]*models.RosterEntry, error)
+	ListRosterByShift(ctx context.Context, shiftID int64) ([]*models.RosterEntry, error)
+	// CreateRosterEntry fails with ErrNotFound unless the shift and the
+	// radiologist exist
+	CreateRosterEntry(ctx context.Context, e *models.RosterEntry) error
+	DeleteRosterEntry(ctx context.Context, id int64) error
+}
+
+type RuleRepository interface {
+	// ListRules returns every rule in evaluation order
+	ListRules(ctx context.Context) ([]*models.AssignmentRule, error)
+	ListActiveRules(ctx context.Context) ([]*models.AssignmentRule, error)
+	GetRule(ctx context.Context, id int64) (*models.AssignmentRule, error)
+	CreateRule(ctx context.Context, r *models.AssignmentRule) error
+	UpdateRule(ctx context.Context, r *models.AssignmentRule) error
+	DeleteRule(ctx context.Context, id int64) error
+}
+
+type RadiologistRepository interface {
+	GetRadiologist(ctx context.Context, id string) (*models.Radiologist, error)
+	GetRadiologists(ctx context.Context, ids []string) ([]*models.Radiologist, error)
+	ListRadiologists(ctx context.Context) ([]*models.Radiologist, error)
+	SaveRadiologist(ctx context.Context, r *models.Radiologist) error
+	// DeleteRadiologist also removes the radiologist's roster entries
+	DeleteRadiologist(ctx context.Context, id string) error
+}
+
+type AssignmentRepository interface {
+	SaveAssignment(ctx context.Context, a *models.Assignment) error
+	GetAssignmentByStudyID(ctx context.Context, studyID string) (*models.Assignment, error)
+	UpdateAssignmentStatus(ctx context.Context, assignmentID int64, status string, at time.Time) error
+	GetRadiologistCurrentWorkload(ctx context.Context, radiologistID string) (int64, error)
+	GetRadiologistWorkloads(ctx context.Context, radiologistIDs []string) (map[string]int64, error)
+	// ListAssignments returns the most recent assignments first
+	ListAssignments(ctx context.Context, limit int) ([]*models.Assignment, error)
+	// CountAssignments returns the number of assignments and how many are open
+	CountAssignments(ctx context.Context) (total, open int64, err error)
+}
+
+type StudyRepository interface {
+	GetStudy(ctx context.Context, id string) (*models.Study, error)
+	SaveStudy(ctx context.Context, study *models.Study) error
+}
+
+type ProcedureRepository interface {
+	ListProcedures(ctx context.Context) ([]*models.Procedure, error)
+	GetProcedureByCode(ctx context.Context, code string) (*models.Procedure, error)
+	// SaveProcedure inserts p or updates the catalog entry with the same code
+	SaveProcedure(ctx context.Context, p *models.Procedure) error
+	DeleteProcedure(ctx context.Context, code string) error
+	RecordUnmappedProcedure(ctx context.Context, study *models.Study) error
+	ListUnmappedProcedures(ctx context.Context) ([]*models.UnmappedProcedure, error)
+	// DeleteUnmappedProcedure removes a queue entry by its UnmappedProcedure.Key
+	DeleteUnmappedProcedure(ctx context.Context, key string) error
+}
+
+// ReferenceRepository holds the config page's lookup tables. Entries are
+// keyed by code, or by name for body parts; the Save methods insert or
+// update by that key and the Update methods may change it.
+type ReferenceRepository interface {
+	GetReferenceData(ctx context.Context) (*models.ReferenceData, error)
+	SaveSite(ctx context.Context, site models.Site) error
+	UpdateSite(ctx context.Context, originalCode string, site models.Site) error
+	DeleteSite(ctx context.Context, code string) error
+	SaveModality(ctx context.Context, m models.Modality) error
+	UpdateModality(ctx context.Context, originalCode string, m models.Modality) error
+	DeleteModality(ctx context.Context, code string) error
+	SaveBodyPart(ctx context.Context, bp models.BodyPart) error
+	UpdateBodyPart(ctx context.Context, originalName string, bp models.BodyPart) error
+	DeleteBodyPart(ctx context.Context, name string) error
+	SaveCredential(ctx context.Context, c models.Credential) error
+	UpdateCredential(ctx context.Context, originalCode string, c models.Credential) error
+	DeleteCredential(ctx context.Context, code string) error
+}
+
+// Store is implemented by every storage backend. It serves both the engine,
+// as its DataStore, and the admin UI.
+type Store interface {
+	ShiftRepository
+	RosterRepository
+	RuleRepository
+	RadiologistRepository
+	AssignmentRepository
+	StudyRepository
+	ProcedureRepository
+	ReferenceRepository
+
+	Close() error
+}
+
+var _ assignment.DataStore = Store(nil)
+
+// Backends accepted by Open
+const (
+	BackendMemory   = "memory"
+	BackendBolt     = "bolt"
+	BackendPostgres = "postgres"
+)
+
+// Config selects and locates a storage backend.
+type Config struct {
+	Backend     string // memory, bolt or postgres
+	Path        string // Database file for bolt
+	DatabaseURL string // Connection URL for postgres
+}
+
+// Open returns the configured backend, migrating the Postgres schema first.
+func Open(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendBolt:
+		return OpenBolt(cfg.Path)
+	case BackendPostgres:
+		s, err := OpenPostgres(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.Migrate(ctx); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("db: unknown backend %q", cfg.Backend)
+	}
+}
+
+// StoreRoster serves the engine's roster lookups straight from a repository.
+type StoreRoster struct {
+	Repo RosterRepository
+}
+
+func (r *StoreRoster) GetByShift(shiftID int64) []*models.RosterEntry {
+	entries, err := r.Repo.ListRosterByShift(context.Background(), shiftID)
+	if err != nil {
+		log.Printf("Failed to load roster for shift %d: %v", shiftID, err)
+	}
+	return entries
+}
+
+// StoreRules serves the engine's rule lookups straight from a repository.
+type StoreRules struct {
+	Repo RuleRepository
+}
+
+func (r *StoreRules) GetActive() []*models.AssignmentRule {
+	rules, err := r.Repo.ListActiveRules(context.Background())
+	if err != nil {
+		log.Printf("Failed to load rules: %v", err)
+	}
+	return rules
+}
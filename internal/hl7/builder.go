@@ -0,0 +1,213 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// AckCode is the MSA-1 acknowledgment code.
+type AckCode string
+
+const (
+	AckAccept AckCode = "AA" // Message processed
+	AckError  AckCode = "AE" // Message understood but could not be processed
+	AckReject AckCode = "AR" // Message type, version or structure not supported
+)
+
+// Error condition codes from HL7 table 0357, sent in ERR-3.
+const (
+	ErrCodeSegmentSequence        = "100"
+	ErrCodeRequiredFieldMissing   = "101"
+	ErrCodeDataType               = "102"
+	ErrCodeTableValueNotFound     = "103"
+	ErrCodeUnsupportedMessageType = "200"
+	ErrCodeUnsupportedEventCode   = "201"
+	ErrCodeApplicationInternal    = "207"
+)
+
+var errorCodeText = map[string]string{
+	ErrCodeSegmentSequence:        "Segment sequence error",
+	ErrCodeRequiredFieldMissing:   "Required field missing",
+	ErrCodeDataType:               "Data type error",
+	ErrCodeTableValueNotFound:     "Table value not found",
+	ErrCodeUnsupportedMessageType: "Unsupported message type",
+	ErrCodeUnsupportedEventCode:   "Unsupported event code",
+	ErrCodeApplicationInternal:    "Application internal error",
+}
+
+// Ack describes the acknowledgment to send for an inbound message.
+type Ack struct {
+	Code      AckCode
+	Text      string // MSA-3 free text
+	ErrorCode string // ERR-3 (table 0357), only sent for AE/AR
+	Segment   string // ERR-2 location: segment ID
+	Sequence  int    // ERR-2 location: segment sequence
+	Field     int    // ERR-2 location: field position
+}
+
+// AckForError builds an AE acknowledgment pointing at the segment that failed.
+func AckForError(err error) Ack {
+	ack := Ack{Code: AckError, Text: err.Error(), ErrorCode: ErrCodeApplicationInternal}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		ack.ErrorCode = ErrCodeSegmentSequence
+		if pe.Field > 0 {
+			ack.ErrorCode = ErrCodeRequiredFieldMissing
+		}
+		ack.Segment = pe.Segment
+		ack.Sequence = pe.Sequence
+		ack.Field = pe.Field
+	}
+	return ack
+}
+
+var controlIDSeq atomic.Uint64
+
+// newControlID returns a control ID for outbound messages, unique within this
+// process.
+func newControlID(now time.Time) string {
+	return fmt.Sprintf("%s%06d", now.Format(hl7TimeLayout), controlIDSeq.Add(1)%1000000)
+}
+
+// BuildACK builds an ACK for the message whose header is msh. Sending and
+// receiving application/facility are swapped and MSA-2 echoes MSH-10 so the
+// sender can reconcile. msh may be nil when the inbound header itself could
+// not be read; MSA-2 is then left empty.
+func BuildACK(msh *Segment, ack Ack, now time.Time) string {
+	if msh == nil {
+		msh = &Segment{Fields: []string{"MSH", string(fieldSeparator), `^~\&`}}
+	}
+
+	version := msh.Field(12)
+	if version == "" {
+		version = "2.5.1"
+	}
+	messageType := "ACK"
+	if event := msh.Component(9, 2); event != "" {
+		messageType += string(componentSeparator) + event
+	}
+
+	header := &Segment{Fields: []string{
+		"MSH", string(fieldSeparator), `^~\&`,
+		msh.Field(5), // Sending application <- original receiving application
+		msh.Field(6),
+		msh.Field(3),
+		msh.Field(4),
+		now.Format(hl7TimeLayout),
+		"",
+		messageType,
+		newControlID(now),
+		msh.Field(11),
+		version,
+	}}
+
+	segments := []*Segment{
+		header,
+		{Fields: []string{"MSA", string(ack.Code), msh.Field(10), Escape(ack.Text)}},
+	}
+
+	if ack.Code != AckAccept && ack.ErrorCode != "" {
+		location := ""
+		if ack.Segment != "" {
+			location = ack.Segment
+			if ack.Sequence > 0 {
+				location += string(componentSeparator) + fmt.Sprint(ack.Sequence)
+				if ack.Field > 0 {
+					location += string(componentSeparator) + fmt.Sprint(ack.Field)
+				}
+			}
+		}
+		code := strings.Join([]string{ack.ErrorCode, errorCodeText[ack.ErrorCode], "HL70357"}, string(componentSeparator))
+		segments = append(segments, &Segment{Fields: []string{"ERR", "", location, code, "E"}})
+	}
+
+	return (&Message{Segments: segments}).String()
+}
+
+// BuildORU turns the original inbound order into an ORU^R01 enriched with a
+// ZRA segment describing the assignment (architecture.md section 2.2). The
+// header is re-addressed back to the original sender and a new control ID is
+// allocated; all other segments are kept in order, with ZRA placed after ZRD
+// (or appended when the order had no ZRD).
+func BuildORU(original *Message, event *models.AssignmentEvent, now time.Time) (*Message, error) {
+	msh := original.Segment("MSH")
+	if msh == nil {
+		return nil, ErrMissingMSH
+	}
+	if event == nil || event.Assignment == nil {
+		return nil, errors.New("hl7: assignment event is required")
+	}
+
+	version := msh.Field(12)
+	if version == "" {
+		version = "2.5.1"
+	}
+	header := &Segment{Fields: []string{
+		"MSH", string(fieldSeparator), `^~\&`,
+		msh.Field(5),
+		msh.Field(6),
+		msh.Field(3),
+		msh.Field(4),
+		now.Format(hl7TimeLayout),
+		"",
+		"ORU^R01",
+		newControlID(now),
+		msh.Field(11),
+		version,
+	}}
+
+	out := &Message{Segments: []*Segment{header}}
+	zra := buildZRASegment(event)
+	inserted := false
+	for _, seg := range original.Segments {
+		switch seg.Name() {
+		case "MSH", "ZRA":
+			// Header is rebuilt above; a ZRA from an earlier pass is replaced
+			continue
+		}
+		out.Segments = append(out.Segments, seg)
+		if seg.Name() == "ZRD" && !inserted {
+			out.Segments = append(out.Segments, zra)
+			inserted = true
+		}
+	}
+	if !inserted {
+		out.Segments = append(out.Segments, zra)
+	}
+	return out, nil
+}
+
+// buildZRASegment renders ZRA|1|ID^Family^Given|Shift|STRATEGY|Timestamp.
+// Worklist assignments carry WORKLIST^Worklist in ZRA-2.
+func buildZRASegment(event *models.AssignmentEvent) *Segment {
+	a := event.Assignment
+
+	radiologist := Escape(a.RadiologistID)
+	if rad := event.Radiologist; rad != nil {
+		radiologist = strings.Join([]string{Escape(rad.ID), Escape(rad.LastName), Escape(rad.FirstName)}, string(componentSeparator))
+	} else if a.Worklist != "" {
+		radiologist = strings.Join([]string{radiologist, Escape(a.Worklist)}, string(componentSeparator))
+	}
+
+	decidedAt := a.AssignedAt
+	if decidedAt.IsZero() {
+		decidedAt = a.CreatedAt
+	}
+	timestamp := ""
+	if !decidedAt.IsZero() {
+		timestamp = decidedAt.Format(hl7TimeLayout)
+	}
+
+	return &Segment{Fields: []string{
+		"ZRA",
+		"1",
+		radiologist,
+		Escape(event.ShiftName),
+		Escape(strings.ToUpper(a.Strategy)),
+		timestamp,
+	}}
+}
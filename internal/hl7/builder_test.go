@@ -0,0 +1,159 @@
+package hl7
+
+import (
+	"radiology-assignment/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildACK_Accept(t *testing.T) {
+	msh, err := ParseHeader(sampleORM)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	raw := BuildACK(msh, Ack{Code: AckAccept, Text: "OK"}, time.Date(2026, 2, 4, 12, 5, 31, 0, time.UTC))
+	ack, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("ACK did not parse: %v", err)
+	}
+
+	if got := ack.MessageType(); got != "ACK^O01" {
+		t.Errorf("Expected ACK^O01, got %s", got)
+	}
+	if got := ack.Get("MSH", 3, 1); got != "ASSIGNMENT_ENGINE" {
+		t.Errorf("Expected sending application to be swapped, got %s", got)
+	}
+	if got := ack.Get("MSH", 5, 1); got != "PACS" {
+		t.Errorf("Expected receiving application to be swapped, got %s", got)
+	}
+	if got := ack.Get("MSA", 1, 1); got != "AA" {
+		t.Errorf("Expected AA, got %s", got)
+	}
+	if got := ack.Get("MSA", 2, 1); got != "MSG0001" {
+		t.Errorf("Expected MSA-2 to echo MSG0001, got %s", got)
+	}
+	if ack.Segment("ERR") != nil {
+		t.Errorf("Expected no ERR segment on AA")
+	}
+}
+
+func TestBuildACK_Errors(t *testing.T) {
+	msh, _ := ParseHeader(sampleORM)
+
+	tests := []struct {
+		name     string
+		ack      Ack
+		wantCode string
+		wantErr  string
+	}{
+		{
+			name:     "Parse error",
+			ack:      AckForError(&ParseError{Segment: "OBR", Sequence: 3, Field: 3}),
+			wantCode: "AE",
+			wantErr:  "101",
+		},
+		{
+			name:     "Unsupported type",
+			ack:      Ack{Code: AckReject, Text: "Unsupported", ErrorCode: ErrCodeUnsupportedMessageType},
+			wantCode: "AR",
+			wantErr:  "200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack, err := ParseMessage(BuildACK(msh, tt.ack, time.Now()))
+			if err != nil {
+				t.Fatalf("ACK did not parse: %v", err)
+			}
+			if got := ack.Get("MSA", 1, 1); got != tt.wantCode {
+				t.Errorf("Expected %s, got %s", tt.wantCode, got)
+			}
+			if got := ack.Get("MSA", 2, 1); got != "MSG0001" {
+				t.Errorf("Expected MSA-2 to echo MSG0001, got %s", got)
+			}
+			if got := ack.Get("ERR", 3, 1); got != tt.wantErr {
+				t.Errorf("Expected ERR-3 %s, got %s", tt.wantErr, got)
+			}
+		})
+	}
+}
+
+func TestBuildACK_EscapesText(t *testing.T) {
+	raw := BuildACK(nil, Ack{Code: AckReject, Text: "bad|text^here"}, time.Now())
+	ack, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("ACK did not parse: %v", err)
+	}
+	if got := ack.Get("MSA", 3, 1); got != "bad|text^here" {
+		t.Errorf("Expected escaped text to round trip, got %q", got)
+	}
+	if !strings.HasPrefix(raw, "MSH|^~\\&|") {
+		t.Errorf("Unexpected header: %q", raw)
+	}
+}
+
+func TestBuildORU(t *testing.T) {
+	original, err := ParseMessage(sampleORM)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	decided := time.Date(2026, 2, 4, 12, 5, 31, 0, time.UTC)
+	event := &models.AssignmentEvent{
+		Assignment:  &models.Assignment{StudyID: "ACCESSION_456", RadiologistID: "DR_SMITH_ID", Strategy: "load_balanced", AssignedAt: decided},
+		Radiologist: &models.Radiologist{ID: "DR_SMITH_ID", FirstName: "John", LastName: "Smith"},
+		ShiftName:   "MRI_MSK_ROBINA",
+	}
+
+	oru, err := BuildORU(original, event, decided)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := oru.MessageType(); got != "ORU^R01" {
+		t.Errorf("Expected ORU^R01, got %s", got)
+	}
+	if got := oru.Get("MSH", 5, 1); got != "PACS" {
+		t.Errorf("Expected message to be addressed back to PACS, got %s", got)
+	}
+	if oru.ControlID() == "" || oru.ControlID() == original.ControlID() {
+		t.Errorf("Expected a new control ID, got %q", oru.ControlID())
+	}
+
+	var names []string
+	for _, seg := range oru.Segments {
+		names = append(names, seg.Name())
+	}
+	if got := strings.Join(names, ","); got != "MSH,PID,OBR,OBX,ZRD,ZRA" {
+		t.Errorf("Unexpected segment order: %s", got)
+	}
+
+	zra := oru.Segment("ZRA")
+	if got := zra.String(); got != "ZRA|1|DR_SMITH_ID^Smith^John|MRI_MSK_ROBINA|LOAD_BALANCED|20260204120531" {
+		t.Errorf("Unexpected ZRA segment: %s", got)
+	}
+}
+
+func TestBuildORU_ReplacesExistingZRA(t *testing.T) {
+	original, _ := ParseMessage("MSH|^~\\&|PACS|Robina|ENGINE|ENT|20260204120530||ORM^O01|C1|P|2.5.1\r" +
+		"OBR|1||ACC1|CT HEAD\rZRA|1|OLD\r")
+	event := &models.AssignmentEvent{Assignment: &models.Assignment{RadiologistID: "WORKLIST", Strategy: models.StrategyWorklist, Worklist: "neuro"}}
+
+	oru, err := BuildORU(original, event, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	zras := oru.SegmentsByName("ZRA")
+	if len(zras) != 1 {
+		t.Fatalf("Expected exactly one ZRA, got %d", len(zras))
+	}
+	if got := zras[0].Component(2, 1); got != "WORKLIST" {
+		t.Errorf("Expected WORKLIST in ZRA-2, got %s", got)
+	}
+	if got := zras[0].Component(2, 2); got != "neuro" {
+		t.Errorf("Expected the worklist name in ZRA-2.2, got %s", got)
+	}
+}
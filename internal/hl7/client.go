@@ -0,0 +1,127 @@
+package hl7
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDialTimeout = 5 * time.Second
+	DefaultAckTimeout  = 30 * time.Second
+)
+
+// NAKError is returned by Client.Send when the receiver answers with an
+// application error or reject rather than an accept.
+type NAKError struct {
+	Code AckCode
+	Text string
+}
+
+func (e *NAKError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("hl7: peer replied %s", e.Code)
+	}
+	return fmt.Sprintf("hl7: peer replied %s: %s", e.Code, e.Text)
+}
+
+// Client sends messages to a downstream MLLP endpoint and waits for the ACK.
+// The connection is kept open between sends and re-dialled after any error.
+type Client struct {
+	Addr        string
+	DialTimeout time.Duration
+	AckTimeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *MLLPReader
+}
+
+func NewClient(addr string) *Client {
+	return &Client{
+		Addr:        addr,
+		DialTimeout: DefaultDialTimeout,
+		AckTimeout:  DefaultAckTimeout,
+	}
+}
+
+// Send delivers msg and returns the parsed ACK. An ACK with MSA-1 other than
+// AA/CA is reported as *NAKError; a missing or mismatched ACK is reported as
+// a transport error and the connection is dropped.
+func (c *Client) Send(ctx context.Context, msg *Message) (*Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(c.AckTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		c.reset()
+		return nil, err
+	}
+
+	if err := WriteMessage(c.conn, []byte(msg.String())); err != nil {
+		c.reset()
+		return nil, err
+	}
+
+	payload, err := c.reader.ReadMessage()
+	if err != nil {
+		c.reset()
+		return nil, fmt.Errorf("hl7: waiting for ACK from %s: %w", c.Addr, err)
+	}
+
+	ack, err := ParseMessage(string(payload))
+	if err != nil {
+		c.reset()
+		return nil, fmt.Errorf("hl7: invalid ACK from %s: %w", c.Addr, err)
+	}
+	if got, want := ack.Get("MSA", 2, 1), msg.ControlID(); got != want {
+		c.reset()
+		return nil, fmt.Errorf("hl7: ACK for %q does not match sent message %q", got, want)
+	}
+
+	switch code := AckCode(ack.Get("MSA", 1, 1)); code {
+	case AckAccept, "CA":
+		return ack, nil
+	default:
+		return ack, &NAKError{Code: code, Text: ack.Get("MSA", 3, 1)}
+	}
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	if c.conn != nil {
+		return nil
+	}
+	dialer := net.Dialer{Timeout: c.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("hl7: dial %s: %w", c.Addr, err)
+	}
+	c.conn = conn
+	c.reader = NewMLLPReader(conn)
+	return nil
+}
+
+func (c *Client) reset() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.reader = nil
+}
+
+// Close drops the connection, if any.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+	return nil
+}
@@ -0,0 +1,91 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeReceiver acknowledges every framed message with the given code.
+func fakeReceiver(t *testing.T, code AckCode) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := NewMLLPReader(conn)
+				for {
+					payload, err := r.ReadMessage()
+					if err != nil {
+						return
+					}
+					received <- string(payload)
+					msh, _ := ParseHeader(string(payload))
+					WriteMessage(conn, []byte(BuildACK(msh, Ack{Code: code, Text: "test"}, time.Now())))
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestClient_SendAccepted(t *testing.T) {
+	addr, received := fakeReceiver(t, AckAccept)
+	client := NewClient(addr)
+	defer client.Close()
+
+	msg, _ := ParseMessage(sampleORM)
+	for i := 0; i < 2; i++ {
+		ack, err := client.Send(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("Send %d failed: %v", i, err)
+		}
+		if got := ack.Get("MSA", 2, 1); got != "MSG0001" {
+			t.Errorf("Expected ACK for MSG0001, got %s", got)
+		}
+		if got := <-received; got != sampleORM {
+			t.Errorf("Receiver got unexpected payload: %q", got)
+		}
+	}
+}
+
+func TestClient_SendRejected(t *testing.T) {
+	addr, _ := fakeReceiver(t, AckError)
+	client := NewClient(addr)
+	defer client.Close()
+
+	msg, _ := ParseMessage(sampleORM)
+	_, err := client.Send(context.Background(), msg)
+	var ackErr *NAKError
+	if !errors.As(err, &ackErr) {
+		t.Fatalf("Expected NAKError, got %v", err)
+	}
+	if ackErr.Code != AckError {
+		t.Errorf("Expected AE, got %s", ackErr.Code)
+	}
+}
+
+func TestClient_DialFailure(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := NewClient(addr)
+	msg, _ := ParseMessage(sampleORM)
+	if _, err := client.Send(context.Background(), msg); err == nil {
+		t.Fatal("Expected error when receiver is down")
+	}
+}
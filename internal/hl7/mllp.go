@@ -0,0 +1,107 @@
+package hl7
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MLLP framing bytes: <VT> payload <FS><CR>
+const (
+	StartBlock     byte = 0x0B
+	EndBlock       byte = 0x1C
+	CarriageReturn byte = 0x0D
+
+	// DefaultMaxMessageSize caps a single framed message to protect the
+	// listener from peers that never send an end block.
+	DefaultMaxMessageSize = 1 << 20
+)
+
+var ErrMessageTooLarge = errors.New("mllp: message exceeds maximum size")
+
+// MLLPReader reads framed HL7 messages from a stream. A single reader can be
+// used for any number of messages on a persistent connection, and frames may
+// arrive split across several reads.
+type MLLPReader struct {
+	r       *bufio.Reader
+	MaxSize int
+}
+
+func NewMLLPReader(r io.Reader) *MLLPReader {
+	return &MLLPReader{
+		r:       bufio.NewReader(r),
+		MaxSize: DefaultMaxMessageSize,
+	}
+}
+
+// ReadMessage blocks until a complete frame has been received and returns its
+// payload. Bytes before the start block are discarded. io.EOF is returned when
+// the stream ends cleanly between frames; io.ErrUnexpectedEOF when it ends
+// inside one.
+func (m *MLLPReader) ReadMessage() ([]byte, error) {
+	// Skip anything up to and including the start block
+	for {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == StartBlock {
+			break
+		}
+	}
+
+	var buf []byte
+	for {
+		b, err := m.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if b == EndBlock {
+			next, err := m.r.ReadByte()
+			if err != nil {
+				if err == io.EOF {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			if next == CarriageReturn {
+				return buf, nil
+			}
+			// Not a frame terminator, keep both bytes as payload
+			buf = append(buf, b)
+			if err := m.r.UnreadByte(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if b == StartBlock {
+			// A new frame started before the previous one ended; drop the
+			// incomplete payload and resynchronise on the new frame.
+			buf = buf[:0]
+			continue
+		}
+
+		buf = append(buf, b)
+		if m.MaxSize > 0 && len(buf) > m.MaxSize {
+			return nil, ErrMessageTooLarge
+		}
+	}
+}
+
+// WriteMessage frames payload with MLLP delimiters and writes it to w.
+func WriteMessage(w io.Writer, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+3)
+	frame = append(frame, StartBlock)
+	frame = append(frame, payload...)
+	frame = append(frame, EndBlock, CarriageReturn)
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("mllp: write: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package hl7
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// chunkReader returns at most n bytes per Read to simulate partial reads.
+type chunkReader struct {
+	data []byte
+	n    int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	n := c.n
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(c.data) {
+		n = len(c.data)
+	}
+	copy(p, c.data[:n])
+	c.data = c.data[n:]
+	return n, nil
+}
+
+func frame(s string) []byte {
+	var buf bytes.Buffer
+	_ = WriteMessage(&buf, []byte(s))
+	return buf.Bytes()
+}
+
+func TestMLLPReader_MultipleMessagesPartialReads(t *testing.T) {
+	var stream []byte
+	stream = append(stream, []byte("noise")...)
+	stream = append(stream, frame("MSH|first\r")...)
+	stream = append(stream, frame("MSH|second\r")...)
+
+	r := NewMLLPReader(&chunkReader{data: stream, n: 3})
+
+	for _, want := range []string{"MSH|first\r", "MSH|second\r"} {
+		got, err := r.ReadMessage()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+
+	if _, err := r.ReadMessage(); err != io.EOF {
+		t.Errorf("Expected io.EOF after last frame, got %v", err)
+	}
+}
+
+func TestMLLPReader_TruncatedFrame(t *testing.T) {
+	stream := []byte{StartBlock, 'M', 'S', 'H', EndBlock}
+	r := NewMLLPReader(bytes.NewReader(stream))
+	if _, err := r.ReadMessage(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestMLLPReader_EmbeddedEndBlock(t *testing.T) {
+	payload := []byte{'A', EndBlock, 'B'}
+	r := NewMLLPReader(bytes.NewReader(frame(string(payload))))
+	got, err := r.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("Expected %v, got %v", payload, got)
+	}
+}
+
+func TestMLLPReader_MaxSize(t *testing.T) {
+	r := NewMLLPReader(bytes.NewReader(frame("0123456789")))
+	r.MaxSize = 4
+	if _, err := r.ReadMessage(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+}
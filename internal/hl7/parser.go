@@ -0,0 +1,311 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"radiology-assignment/internal/models"
+	"strings"
+	"time"
+)
+
+const hl7TimeLayout = "20060102150405"
+
+var (
+	ErrEmptyMessage = errors.New("hl7: empty message")
+	ErrMissingMSH   = errors.New("hl7: message does not start with MSH")
+)
+
+// ParseError reports a segment that could not be decoded.
+type ParseError struct {
+	Segment  string // segment name, or "" if it could not be determined
+	Sequence int    // 1-based position of the segment in the message
+	Field    int    // field number within the segment, 0 if not field specific
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	loc := e.Segment
+	if e.Field > 0 {
+		loc = fmt.Sprintf("%s-%d", e.Segment, e.Field)
+	}
+	if loc == "" {
+		return fmt.Sprintf("hl7: segment %d: %v", e.Sequence, e.Err)
+	}
+	return fmt.Sprintf("hl7: segment %d (%s): %v", e.Sequence, loc, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseMessage splits a raw HL7 v2 message into segments and fields.
+// Segments may be separated by \r, \n or \r\n.
+func ParseMessage(raw string) (*Message, error) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\r")
+	raw = strings.ReplaceAll(raw, "\n", "\r")
+	raw = strings.Trim(raw, "\r")
+	if raw == "" {
+		return nil, ErrEmptyMessage
+	}
+	if !strings.HasPrefix(raw, "MSH") {
+		return nil, ErrMissingMSH
+	}
+
+	msg := &Message{}
+	for i, line := range strings.Split(raw, string(segmentSeparator)) {
+		if line == "" {
+			continue
+		}
+		seg, err := parseSegment(line)
+		if err != nil {
+			return nil, &ParseError{Segment: seg.Name(), Sequence: i + 1, Err: err}
+		}
+		msg.Segments = append(msg.Segments, seg)
+	}
+	return msg, nil
+}
+
+// ParseHeader decodes only the MSH segment of a raw message. It lets callers
+// acknowledge a message (echoing MSH-10) even when a later segment is invalid.
+func ParseHeader(raw string) (*Segment, error) {
+	raw = strings.TrimLeft(raw, "\r\n")
+	if raw == "" {
+		return nil, ErrEmptyMessage
+	}
+	if !strings.HasPrefix(raw, "MSH") {
+		return nil, ErrMissingMSH
+	}
+	line := raw
+	if i := strings.IndexAny(raw, "\r\n"); i >= 0 {
+		line = raw[:i]
+	}
+	seg, err := parseSegment(line)
+	if err != nil {
+		return nil, &ParseError{Segment: "MSH", Sequence: 1, Err: err}
+	}
+	return seg, nil
+}
+
+func parseSegment(line string) (*Segment, error) {
+	fields := strings.Split(line, string(fieldSeparator))
+	seg := &Segment{Fields: fields}
+
+	name := fields[0]
+	if len(name) != 3 || strings.ToUpper(name) != name {
+		return seg, fmt.Errorf("invalid segment name %q", name)
+	}
+	if len(fields) < 2 {
+		return seg, errors.New("segment has no fields")
+	}
+
+	if name == "MSH" {
+		if fields[1] != "^~\\&" {
+			return seg, fmt.Errorf("unsupported encoding characters %q", fields[1])
+		}
+		// Re-insert MSH-1 so that MSH-n lines up with Fields[n]
+		seg.Fields = append([]string{"MSH", string(fieldSeparator)}, fields[1:]...)
+	}
+	return seg, nil
+}
+
+// ExtractStudy maps an ORM^O01 message onto a Study using the field mapping
+// in architecture.md section 2.1. ZRD values take precedence over the
+// standard segments when the site sends them.
+func ExtractStudy(msg *Message) (*models.Study, error) {
+	obr := msg.Segment("OBR")
+	if obr == nil {
+		return nil, &ParseError{Segment: "OBR", Err: errors.New("segment missing")}
+	}
+
+	study := &models.Study{
+		ID:                   obr.Component(3, 1), // Filler order / accession
+		MessageID:            msg.ControlID(),
+		Site:                 msg.Get("MSH", 4, 1), // Sending facility
+		Timestamp:            normalizeTimestamp(obr.Component(7, 1)),
+		ProcedureCode:        obr.Component(4, 1),
+		ProcedureDescription: obr.Component(4, 2),
+		OrderingPhysician:    formatName(obr, 16),
+		Technician:           formatName(obr, 34),
+		Transcriptionist:     formatName(obr, 35),
+		Urgency:              normalizeUrgency(obr.Component(5, 1)),
+		PriorLocation:        msg.Get("PV1", 6, 1),
+	}
+	if study.ID == "" {
+		study.ID = obr.Component(2, 1) // Fall back to placer order number
+	}
+	if study.ID == "" {
+		return nil, &ParseError{Segment: "OBR", Sequence: segmentSequence(msg, obr), Field: 3, Err: errors.New("no accession or placer order number")}
+	}
+	if study.Site == "" {
+		study.Site = msg.Get("MSH", 3, 1)
+	}
+
+	study.OrderControl = strings.ToUpper(msg.Get("ORC", 1, 1))
+	if study.OrderControl == "" {
+		study.OrderControl = models.OrderControlNew
+		if strings.HasPrefix(msg.MessageType(), "ORU") {
+			study.OrderControl = models.OrderControlResult
+		}
+	}
+	if study.OrderControl == models.OrderControlStatus {
+		study.ReportStatus = orderStatuses[strings.ToUpper(msg.Get("ORC", 5, 1))]
+	}
+	if v := resultStatuses[strings.ToUpper(obr.Component(25, 1))]; v != "" {
+		study.ReportStatus = v
+	}
+
+	study.Modality, study.BodyPart = parseProcedureCode(study.ProcedureCode)
+
+	if zrd := msg.Segment("ZRD"); zrd != nil {
+		if v := zrd.Component(2, 1); v != "" {
+			study.Site = v
+		}
+		if v := zrd.Component(3, 1); v != "" {
+			study.Modality = v
+		}
+		if v := zrd.Component(4, 1); v != "" {
+			study.Urgency = normalizeUrgency(v)
+		}
+		if v := zrd.Component(5, 1); v != "" {
+			study.BodyPart = v
+		}
+	}
+	if study.Urgency == "" {
+		study.Urgency = "ROUTINE"
+	}
+
+	study.Indication = extractIndication(msg)
+
+	if pid := msg.Segment("PID"); pid != nil {
+		if dob, err := time.Parse("20060102", firstN(pid.Component(7, 1), 8)); err == nil {
+			study.PatientAge = ageAt(dob, study.GetExamTime())
+		}
+	}
+
+	return study, nil
+}
+
+// segmentSequence returns the 1-based position of seg within msg.
+func segmentSequence(msg *Message, seg *Segment) int {
+	for i, s := range msg.Segments {
+		if s == seg {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseProcedureCode derives modality and body part from codes of the form
+// "MRI MSK" or "CT CHEST".
+func parseProcedureCode(code string) (modality, bodyPart string) {
+	parts := strings.Fields(code)
+	if len(parts) >= 1 {
+		modality = parts[0]
+	}
+	if len(parts) >= 2 {
+		bodyPart = parts[1]
+	}
+	return
+}
+
+// extractIndication prefers an OBX explicitly coded as the indication and
+// otherwise falls back to the first OBX value.
+func extractIndication(msg *Message) string {
+	obxs := msg.SegmentsByName("OBX")
+	for _, obx := range obxs {
+		if strings.EqualFold(obx.Component(3, 1), "Indication") {
+			return obx.Component(5, 1)
+		}
+	}
+	if len(obxs) > 0 {
+		return obxs[0].Component(5, 1)
+	}
+	return ""
+}
+
+// formatName renders an XCN field (ID^Family^Given^...) as "Given Family",
+// falling back to the ID when no name is present.
+func formatName(seg *Segment, n int) string {
+	id := seg.Component(n, 1)
+	family := seg.Component(n, 2)
+	given := seg.Component(n, 3)
+	name := strings.TrimSpace(given + " " + family)
+	if name == "" {
+		return id
+	}
+	return name
+}
+
+// orderStatuses maps ORC-5 (HL7 table 0038) to assignment statuses.
+var orderStatuses = map[string]string{
+	"SC": models.StatusAssigned,
+	"IP": models.StatusInProgress,
+	"CM": models.StatusFinalized,
+	"CA": models.StatusCancelled,
+	"DC": models.StatusCancelled,
+}
+
+// resultStatuses maps OBR-25 (HL7 table 0123) to assignment statuses.
+var resultStatuses = map[string]string{
+	"I": models.StatusInProgress,
+	"P": models.StatusReported,
+	"R": models.StatusReported,
+	"A": models.StatusReported,
+	"F": models.StatusFinalized,
+	"C": models.StatusFinalized,
+	"X": models.StatusCancelled,
+}
+
+func normalizeUrgency(v string) string {
+	switch strings.ToUpper(strings.TrimSpace(v)) {
+	case "":
+		return ""
+	case "S", "STAT":
+		return "STAT"
+	case "A", "ASAP":
+		return "ASAP"
+	case "R", "ROUTINE":
+		return "ROUTINE"
+	default:
+		return strings.ToUpper(strings.TrimSpace(v))
+	}
+}
+
+// normalizeTimestamp pads or truncates an HL7 DTM value to YYYYMMDDHHMMSS so
+// it can be read by Study.GetExamTime. Fractional seconds and offsets are
+// dropped.
+func normalizeTimestamp(v string) string {
+	if i := strings.IndexAny(v, ".+-"); i >= 0 {
+		v = v[:i]
+	}
+	switch {
+	case len(v) >= 14:
+		return v[:14]
+	case len(v) == 12:
+		return v + "00"
+	case len(v) == 8:
+		return v + "000000"
+	}
+	return v
+}
+
+func firstN(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func ageAt(dob, at time.Time) int {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
@@ -0,0 +1,220 @@
+package hl7
+
+import (
+	"errors"
+	"testing"
+)
+
+const sampleORM = "MSH|^~\\&|PACS|Robina|ASSIGNMENT_ENGINE|ENTERPRISE|20260204120530||ORM^O01|MSG0001|P|2.5.1\r" +
+	"PID|1||MRN123^^^Robina||Doe^John||19800115|M\r" +
+	"OBR|1|STUDY_ID_123|ACCESSION_456|MRI MSK^MRI MUSCULOSKELETAL|||20260204120000|||^^^MSK||||||DR01^Smith^Alice\r" +
+	"OBX|1|TX|Indication^Clinical Indication||Patient with knee pain\r" +
+	"ZRD|1|Robina^Site Code|MRI^Modality|STAT^Urgency\r"
+
+func TestParseMessage_Segments(t *testing.T) {
+	msg, err := ParseMessage(sampleORM)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(msg.Segments) != 5 {
+		t.Fatalf("Expected 5 segments, got %d", len(msg.Segments))
+	}
+	if msg.MessageType() != "ORM^O01" {
+		t.Errorf("Expected ORM^O01, got %s", msg.MessageType())
+	}
+	if msg.ControlID() != "MSG0001" {
+		t.Errorf("Expected control ID MSG0001, got %s", msg.ControlID())
+	}
+	if got := msg.Get("MSH", 4, 1); got != "Robina" {
+		t.Errorf("Expected MSH-4 Robina, got %s", got)
+	}
+	if got := msg.String(); got != sampleORM {
+		t.Errorf("Expected round trip to preserve message, got %q", got)
+	}
+}
+
+func TestParseMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantSeg string
+	}{
+		{name: "Empty", raw: "\r\r"},
+		{name: "No MSH", raw: "PID|1||MRN123\r"},
+		{name: "Bad Encoding", raw: "MSH|^~|PACS\r", wantSeg: "MSH"},
+		{name: "Bad Segment Name", raw: "MSH|^~\\&|PACS\rpid|1\r", wantSeg: "pid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMessage(tt.raw)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			var pe *ParseError
+			if tt.wantSeg != "" {
+				if !errors.As(err, &pe) {
+					t.Fatalf("Expected ParseError, got %v", err)
+				}
+				if pe.Segment != tt.wantSeg {
+					t.Errorf("Expected segment %s, got %s", tt.wantSeg, pe.Segment)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractStudy(t *testing.T) {
+	msg, err := ParseMessage(sampleORM)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	study, err := ExtractStudy(msg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	checks := []struct {
+		field, got, want string
+	}{
+		{"ID", study.ID, "ACCESSION_456"},
+		{"MessageID", study.MessageID, "MSG0001"},
+		{"Site", study.Site, "Robina"},
+		{"Timestamp", study.Timestamp, "20260204120000"},
+		{"Modality", study.Modality, "MRI"},
+		{"BodyPart", study.BodyPart, "MSK"},
+		{"Urgency", study.Urgency, "STAT"},
+		{"Indication", study.Indication, "Patient with knee pain"},
+		{"ProcedureCode", study.ProcedureCode, "MRI MSK"},
+		{"ProcedureDescription", study.ProcedureDescription, "MRI MUSCULOSKELETAL"},
+		{"OrderingPhysician", study.OrderingPhysician, "Alice Smith"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: expected %q, got %q", c.field, c.want, c.got)
+		}
+	}
+	if study.PatientAge != 46 {
+		t.Errorf("Expected patient age 46, got %d", study.PatientAge)
+	}
+}
+
+func TestExtractStudy_Defaults(t *testing.T) {
+	raw := "MSH|^~\\&|PACS|SiteB|ENGINE|ENT|20260204120530||ORM^O01|MSG0002|P|2.5.1\n" +
+		"OBR|1|PLACER_1||CT CHEST^CT Chest|S||202602041200\n"
+
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	study, err := ExtractStudy(msg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if study.ID != "PLACER_1" {
+		t.Errorf("Expected placer order number fallback, got %s", study.ID)
+	}
+	if study.Site != "SiteB" || study.Modality != "CT" || study.BodyPart != "CHEST" {
+		t.Errorf("Unexpected site/modality/body part: %s %s %s", study.Site, study.Modality, study.BodyPart)
+	}
+	if study.Urgency != "STAT" {
+		t.Errorf("Expected OBR-5 priority S to map to STAT, got %s", study.Urgency)
+	}
+	if study.Timestamp != "20260204120000" {
+		t.Errorf("Expected padded timestamp, got %s", study.Timestamp)
+	}
+	if study.OrderControl != "NW" {
+		t.Errorf("Expected missing ORC to default to NW, got %s", study.OrderControl)
+	}
+}
+
+func TestExtractStudy_OrderControl(t *testing.T) {
+	raw := "MSH|^~\\&|PACS|SiteB|ENGINE|ENT|20260204120530||ORM^O01|MSG0004|P|2.5.1\r" +
+		"ORC|xo|PLACER_1\r" +
+		"OBR|1|PLACER_1|ACC_1|CT CHEST^CT Chest\r"
+
+	msg, err := ParseMessage(raw)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	study, err := ExtractStudy(msg)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if study.OrderControl != "XO" {
+		t.Errorf("Expected XO, got %s", study.OrderControl)
+	}
+}
+
+func TestExtractStudy_MissingOBR(t *testing.T) {
+	msg, err := ParseMessage("MSH|^~\\&|PACS|SiteB|ENGINE|ENT|20260204120530||ORM^O01|MSG0003|P|2.5.1\r")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := ExtractStudy(msg); err == nil {
+		t.Fatal("Expected error when OBR is missing")
+	}
+}
+
+func TestExtractStudy_ReportStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantControl string
+		wantStatus  string
+	}{
+		{
+			name:        "ORM status change",
+			raw:         "MSH|^~\\&|RIS|SiteA|ENGINE|ENT|20260204130000||ORM^O01|MSG10|P|2.5.1\rORC|SC|ACC_1|||IP\rOBR|1|ACC_1|ACC_1|CT HEAD\r",
+			wantControl: "SC",
+			wantStatus:  "in_progress",
+		},
+		{
+			name:        "ORU preliminary",
+			raw:         "MSH|^~\\&|RIS|SiteA|ENGINE|ENT|20260204130000||ORU^R01|MSG11|P|2.5.1\rOBR|1|ACC_1|ACC_1|CT HEAD|||||||||||||||||||||P\r",
+			wantControl: "RE",
+			wantStatus:  "reported",
+		},
+		{
+			name:        "ORU final",
+			raw:         "MSH|^~\\&|RIS|SiteA|ENGINE|ENT|20260204130000||ORU^R01|MSG12|P|2.5.1\rORC|RE|ACC_1\rOBR|1|ACC_1|ACC_1|CT HEAD|||||||||||||||||||||F\r",
+			wantControl: "RE",
+			wantStatus:  "finalized",
+		},
+		{
+			name:        "New order ignores ORC-5",
+			raw:         "MSH|^~\\&|RIS|SiteA|ENGINE|ENT|20260204130000||ORM^O01|MSG13|P|2.5.1\rORC|NW|ACC_1|||SC\rOBR|1|ACC_1|ACC_1|CT HEAD\r",
+			wantControl: "NW",
+			wantStatus:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseMessage(tt.raw)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			study, err := ExtractStudy(msg)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if study.OrderControl != tt.wantControl {
+				t.Errorf("Expected order control %s, got %s", tt.wantControl, study.OrderControl)
+			}
+			if study.ReportStatus != tt.wantStatus {
+				t.Errorf("Expected status %q, got %q", tt.wantStatus, study.ReportStatus)
+			}
+		})
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	if got := Unescape(`A\S\B\F\C\E\`); got != `A^B|C\` {
+		t.Errorf("Unexpected unescape result: %q", got)
+	}
+	if got := Unescape(Escape("x^y|z")); got != "x^y|z" {
+		t.Errorf("Escape round trip failed: %q", got)
+	}
+}
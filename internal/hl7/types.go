@@ -0,0 +1,152 @@
+package hl7
+
+import "strings"
+
+const (
+	segmentSeparator   = '\r'
+	fieldSeparator     = '|'
+	componentSeparator = '^'
+	repetitionSep      = '~'
+	escapeChar         = '\\'
+	subcomponentSep    = '&'
+)
+
+// Message is a parsed HL7 v2 message. Segments are kept in wire order so the
+// original message can be re-encoded (and enriched) without loss.
+type Message struct {
+	Segments []*Segment
+}
+
+// Segment is a single HL7 segment. Fields[0] holds the segment name, so
+// Fields[n] is the n-th field in HL7 numbering (e.g. OBR-4 is Fields[4]).
+// For MSH, Fields[1] is the field separator and Fields[2] the encoding
+// characters, keeping MSH-n == Fields[n] as well.
+type Segment struct {
+	Fields []string
+}
+
+// Name returns the three character segment identifier (MSH, PID, OBR...).
+func (s *Segment) Name() string {
+	if len(s.Fields) == 0 {
+		return ""
+	}
+	return s.Fields[0]
+}
+
+// Field returns the raw (still escaped) value of field n, or "" when absent.
+func (s *Segment) Field(n int) string {
+	if n < 0 || n >= len(s.Fields) {
+		return ""
+	}
+	return s.Fields[n]
+}
+
+// Component returns component c (1-based) of field n, unescaped.
+// Only the first repetition of a repeating field is considered.
+func (s *Segment) Component(n, c int) string {
+	field := s.Field(n)
+	if i := strings.IndexByte(field, repetitionSep); i >= 0 {
+		field = field[:i]
+	}
+	parts := strings.Split(field, string(componentSeparator))
+	if c < 1 || c > len(parts) {
+		return ""
+	}
+	return Unescape(parts[c-1])
+}
+
+// String encodes the segment back to its wire form (without separator).
+func (s *Segment) String() string {
+	if s.Name() == "MSH" && len(s.Fields) > 2 {
+		// MSH-1 is the field separator itself and is not delimited
+		return "MSH" + string(fieldSeparator) + strings.Join(s.Fields[2:], string(fieldSeparator))
+	}
+	return strings.Join(s.Fields, string(fieldSeparator))
+}
+
+// Segment returns the first segment with the given name, or nil.
+func (m *Message) Segment(name string) *Segment {
+	for _, seg := range m.Segments {
+		if seg.Name() == name {
+			return seg
+		}
+	}
+	return nil
+}
+
+// SegmentsByName returns every segment with the given name in wire order.
+func (m *Message) SegmentsByName(name string) []*Segment {
+	var result []*Segment
+	for _, seg := range m.Segments {
+		if seg.Name() == name {
+			result = append(result, seg)
+		}
+	}
+	return result
+}
+
+// Get returns component c of field n of the first segment named name.
+func (m *Message) Get(name string, n, c int) string {
+	seg := m.Segment(name)
+	if seg == nil {
+		return ""
+	}
+	return seg.Component(n, c)
+}
+
+// MessageType returns MSH-9 as "ORM^O01".
+func (m *Message) MessageType() string {
+	code := m.Get("MSH", 9, 1)
+	event := m.Get("MSH", 9, 2)
+	if event == "" {
+		return code
+	}
+	return code + string(componentSeparator) + event
+}
+
+// ControlID returns MSH-10, the message control ID.
+func (m *Message) ControlID() string {
+	return m.Get("MSH", 10, 1)
+}
+
+// String encodes the message using carriage returns between segments.
+func (m *Message) String() string {
+	var sb strings.Builder
+	for i, seg := range m.Segments {
+		if i > 0 {
+			sb.WriteByte(segmentSeparator)
+		}
+		sb.WriteString(seg.String())
+	}
+	sb.WriteByte(segmentSeparator)
+	return sb.String()
+}
+
+var unescaper = strings.NewReplacer(
+	`\F\`, string(fieldSeparator),
+	`\S\`, string(componentSeparator),
+	`\R\`, string(repetitionSep),
+	`\T\`, string(subcomponentSep),
+	`\E\`, string(escapeChar),
+)
+
+// Unescape decodes the standard HL7 delimiter escape sequences.
+func Unescape(s string) string {
+	if strings.IndexByte(s, escapeChar) < 0 {
+		return s
+	}
+	return unescaper.Replace(s)
+}
+
+var escaper = strings.NewReplacer(
+	string(escapeChar), `\E\`,
+	string(fieldSeparator), `\F\`,
+	string(componentSeparator), `\S\`,
+	string(repetitionSep), `\R\`,
+	string(subcomponentSep), `\T\`,
+)
+
+// Escape encodes delimiter characters so s can be placed in a component.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
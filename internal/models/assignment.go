@@ -1,6 +1,46 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// Assignment lifecycle states. A study moves assigned -> in_progress ->
+// reported -> finalized and may be cancelled at any point; only assigned
+// and in_progress studies count against a radiologist's workload.
+const (
+	StatusAssigned   = "assigned"
+	StatusInProgress = "in_progress"
+	StatusReported   = "reported"
+	StatusFinalized  = "finalized"
+	StatusCancelled  = "cancelled"
+)
+
+// Assignment strategies: how the engine arrived at the assignee.
+const (
+	StrategyShiftPrimary       = "shift_primary"       // Load balanced on the highest priority shift
+	StrategySpecialArrangement = "special_arrangement" // A rule named the radiologist or shift
+	StrategyOverflow           = "overflow"            // Load balanced onto a lower priority shift
+	StrategyEscalated          = "escalated"           // An escalation rule fired
+	StrategyWorklist           = "worklist"            // A rule sent the study to a worklist
+	StrategyManual             = "manual"              // Nobody could take it; left on the manual worklist
+)
+
+// WorklistAssignee is the RadiologistID of assignments sent to a worklist.
+const WorklistAssignee = "WORKLIST"
+
+// ManualWorklist holds the studies the engine could not assign to anyone,
+// for a person to assign by hand.
+const ManualWorklist = "manual"
+
+var ErrInvalidTransition = errors.New("invalid assignment status transition")
+
+var statusTransitions = map[string][]string{
+	StatusAssigned:   {StatusInProgress, StatusReported, StatusFinalized, StatusCancelled},
+	StatusInProgress: {StatusReported, StatusFinalized, StatusCancelled},
+	StatusReported:   {StatusFinalized, StatusCancelled},
+	StatusFinalized:  {StatusCancelled},
+}
 
 type Assignment struct {
 	ID            int64     `json:"id"`
@@ -9,7 +49,55 @@ type Assignment struct {
 	ShiftID       int64     `json:"shift_id"`
 	AssignedAt    time.Time `json:"assigned_at"`
 	Escalated     bool      `json:"escalated"`
-	Strategy      string    `json:"strategy"`
-	RuleMatchedID *int64    `json:"rule_matched_id"`
+	Strategy      string    `json:"strategy"` // One of the Strategy constants
+	Worklist      string    `json:"worklist,omitempty"`
+	RuleMatchedID *int64    `json:"rule_matched_id"` // The rule that decided the assignee, if any
+	RulesFired    []int64   `json:"rules_fired"`     // Every rule that matched, in evaluation order
 	CreatedAt     time.Time `json:"created_at"`
+
+	Status          string    `json:"status"`
+	StatusUpdatedAt time.Time `json:"status_updated_at"`
+
+	// Trace is kept for assignments made in explain mode
+	Trace *AssignmentTrace `json:"trace,omitempty"`
+}
+
+// CurrentStatus returns the lifecycle state, treating records saved before
+// statuses existed as assigned.
+func (a *Assignment) CurrentStatus() string {
+	if a.Status == "" {
+		return StatusAssigned
+	}
+	return a.Status
+}
+
+// IsOpen reports whether the study still occupies the radiologist.
+func (a *Assignment) IsOpen() bool {
+	status := a.CurrentStatus()
+	return status == StatusAssigned || status == StatusInProgress
+}
+
+// CanTransition reports whether the assignment may move to status.
+func (a *Assignment) CanTransition(status string) bool {
+	for _, next := range statusTransitions[a.CurrentStatus()] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidStatus reports whether status is a known lifecycle state.
+func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok || status == StatusCancelled
+}
+
+// AssignmentEvent is published by the engine once a study has been assigned.
+// It carries everything the emitter needs to enrich the original order.
+type AssignmentEvent struct {
+	Assignment  *Assignment  `json:"assignment"`
+	Study       *Study       `json:"study"`
+	Radiologist *Radiologist `json:"radiologist,omitempty"`
+	ShiftName   string       `json:"shift_name"`
 }
@@ -0,0 +1,654 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Condition operators for AssignmentRule.ConditionFilters. A filter whose
+// value is a plain string or number tests equality; an object applies every
+// operator it names, all of which must hold:
+//
+//	"modality":    {"in": ["CT", "MR"]}
+//	"indication":  {"regex": "(?i)stroke"}
+//	"patient_age": {"gte": 18, "lt": 65}
+//	"technician":  {"missing": true}
+//
+// Text compares exactly; use a regex with (?i) to ignore case.
+const (
+	OpEquals = "eq"
+	OpIn     = "in"
+	OpNotIn  = "not_in"
+	OpPrefix = "prefix" // Operand is a string or a list, any of which may match
+	OpRegex  = "regex"  // RE2 syntax, matched anywhere in the value
+
+	// Numeric fields only
+	OpGT  = "gt"
+	OpGTE = "gte"
+	OpLT  = "lt"
+	OpLTE = "lte"
+
+	OpExists  = "exists"  // true when the field is set: non-empty text, non-zero number
+	OpMissing = "missing" // The opposite of exists
+
+	OpTimeRange = "time_range" // exam_time only: "HH:MM-HH:MM", inclusive, may cross midnight
+)
+
+// Condition groups. In a filter object they sit alongside the field filters:
+//
+//	"any": [{"urgency": "STAT", "site": "Remote"}, {"site": "Partner"}]
+//	"all": [{...}, {...}]  every object holds
+//	"not": {...}           the object does not hold
+//
+// A filter object is itself an implicit all, so groups nest to any depth.
+const (
+	GroupAll = "all"
+	GroupAny = "any"
+	GroupNot = "not"
+)
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	numberField
+	listField
+)
+
+type studyField struct {
+	kind  fieldKind
+	value func(s *Study, now time.Time) interface{}
+}
+
+func textOf(get func(s *Study) string) studyField {
+	return studyField{textField, func(s *Study, _ time.Time) interface{} { return get(s) }}
+}
+
+// studyFields are the fields a condition can test, by filter key.
+var studyFields = map[string]studyField{
+	"modality":              textOf(func(s *Study) string { return s.Modality }),
+	"body_part":             textOf(func(s *Study) string { return s.BodyPart }),
+	"urgency":               textOf(func(s *Study) string { return s.Urgency }),
+	"indication":            textOf(func(s *Study) string { return s.Indication }),
+	"site":                  textOf(func(s *Study) string { return s.Site }),
+	"procedure_code":        textOf(func(s *Study) string { return s.ProcedureCode }),
+	"procedure_description": textOf(func(s *Study) string { return s.ProcedureDescription }),
+	"ordering_physician":    textOf(func(s *Study) string { return s.OrderingPhysician }),
+	"prior_location":        textOf(func(s *Study) string { return s.PriorLocation }),
+	"technician":            textOf(func(s *Study) string { return s.Technician }),
+	"transcriptionist":      textOf(func(s *Study) string { return s.Transcriptionist }),
+	"order_control":         textOf(func(s *Study) string { return s.OrderControl }),
+	"report_status":         textOf(func(s *Study) string { return s.ReportStatus }),
+	"normalization_source":  textOf(func(s *Study) string { return s.NormalizationSource }),
+	"exam_time": {textField, func(s *Study, _ time.Time) interface{} {
+		return s.GetExamTime().Format("15:04")
+	}},
+	"exam_day": {textField, func(s *Study, _ time.Time) interface{} {
+		return s.GetExamTime().Weekday().String()
+	}},
+	"patient_age": {numberField, func(s *Study, _ time.Time) interface{} {
+		return float64(s.PatientAge)
+	}},
+	"normalization_confidence": {numberField, func(s *Study, _ time.Time) interface{} {
+		return s.NormalizationConfidence
+	}},
+	// Minutes since the study was ingested
+	"age_minutes": {numberField, func(s *Study, now time.Time) interface{} {
+		if s.IngestTime.IsZero() {
+			return 0.0
+		}
+		return now.Sub(s.IngestTime).Minutes()
+	}},
+	"attributes": {listField, func(s *Study, _ time.Time) interface{} {
+		return s.Attributes
+	}},
+}
+
+// ConditionField describes a study field for building conditions.
+type ConditionField struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // text, number or list
+}
+
+// ConditionFields returns the study fields a rule can test, sorted by name.
+func ConditionFields() []ConditionField {
+	kinds := map[fieldKind]string{textField: "text", numberField: "number", listField: "list"}
+	fields := make([]ConditionField, 0, len(studyFields))
+	for name, f := range studyFields {
+		fields = append(fields, ConditionField{Name: name, Kind: kinds[f.kind]})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// Condition is one parsed test of a study field.
+type Condition struct {
+	Key     string // Filter key as written, e.g. patient_age_min
+	Field   string // Study field tested
+	Op      string
+	Operand interface{} // As written, for display
+
+	texts   []string
+	numbers []float64
+	pattern *regexp.Regexp
+	want    bool // For exists and missing
+	clock   ClockRange
+}
+
+// ConditionGroup is a node of a rule's condition tree.
+type ConditionGroup struct {
+	Op         string // GroupAll, GroupAny or GroupNot
+	Path       string // Where the group sits in the filters, e.g. any[1]; empty at the top
+	Conditions []Condition
+	Groups     []*ConditionGroup
+}
+
+// ParseConditions checks a rule's filters and turns them into a condition
+// tree, ordered by key within each group. Besides the study fields and
+// groups, the filters saved before operators existed are accepted:
+//
+//	min_age_minutes  age_minutes gt N
+//	patient_age_min  patient_age gte N
+//	patient_age_max  patient_age lte N
+//	exam_time_range  exam_time time_range "HH:MM-HH:MM"
+//	days_of_week     exam_day in [...], full or three-letter day names
+func ParseConditions(filters map[string]interface{}) (*ConditionGroup, error) {
+	return parseGroup(GroupAll, "", filters)
+}
+
+func parseGroup(op, path string, filters map[string]interface{}) (*ConditionGroup, error) {
+	g := &ConditionGroup{Op: op, Path: path}
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := filters[key]
+		switch key {
+		case GroupAll, GroupAny:
+			sub := &ConditionGroup{Op: key, Path: joinPath(path, key)}
+			items, ok := toFilterList(val)
+			if !ok || len(items) == 0 {
+				return nil, fmt.Errorf("%s: expected a list of condition objects", sub.Path)
+			}
+			for i, item := range items {
+				child, err := parseGroup(GroupAll, fmt.Sprintf("%s[%d]", sub.Path, i), item)
+				if err != nil {
+					return nil, err
+				}
+				sub.Groups = append(sub.Groups, child)
+			}
+			g.Groups = append(g.Groups, sub)
+
+		case GroupNot:
+			notPath := joinPath(path, key)
+			item, ok := val.(map[string]interface{})
+			if !ok || len(item) == 0 {
+				return nil, fmt.Errorf("%s: expected a condition object", notPath)
+			}
+			child, err := parseGroup(GroupNot, notPath, item)
+			if err != nil {
+				return nil, err
+			}
+			g.Groups = append(g.Groups, child)
+
+		default:
+			conditions, err := parseFilter(key, val)
+			if err != nil {
+				if path != "" {
+					err = fmt.Errorf("%s: %w", path, err)
+				}
+				return nil, err
+			}
+			g.Conditions = append(g.Conditions, conditions...)
+		}
+	}
+	return g, nil
+}
+
+// parseFilter parses one field filter, which may apply several operators.
+func parseFilter(key string, val interface{}) ([]Condition, error) {
+	field, op := key, OpEquals
+	switch key {
+	case "min_age_minutes":
+		field, op = "age_minutes", OpGT
+	case "patient_age_min":
+		field, op = "patient_age", OpGTE
+	case "patient_age_max":
+		field, op = "patient_age", OpLTE
+	case "exam_time_range":
+		field, op = "exam_time", OpTimeRange
+	case "days_of_week":
+		field, op = "exam_day", OpIn
+	default:
+		if _, ok := studyFields[key]; !ok {
+			return nil, fmt.Errorf("unknown condition field %q", key)
+		}
+		if ops, ok := val.(map[string]interface{}); ok {
+			if len(ops) == 0 {
+				return nil, fmt.Errorf("%s: no operator given", key)
+			}
+			names := make([]string, 0, len(ops))
+			for name := range ops {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var conditions []Condition
+			for _, name := range names {
+				c, err := parseCondition(key, field, name, ops[name])
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, c)
+			}
+			return conditions, nil
+		}
+	}
+	c, err := parseCondition(key, field, op, val)
+	if err != nil {
+		return nil, err
+	}
+	return []Condition{c}, nil
+}
+
+// Eval reports whether the study satisfies the group. If visit is not nil it
+// is called for every condition in the tree, and nothing is skipped once the
+// outcome is known, so the whole tree can be reported.
+func (g *ConditionGroup) Eval(s *Study, now time.Time, visit func(g *ConditionGroup, c *Condition, actual interface{}, passed bool)) bool {
+	all, some := true, false
+	decided := func() bool {
+		if visit != nil {
+			return false
+		}
+		if g.Op == GroupAny {
+			return some
+		}
+		return !all
+	}
+
+	for i := range g.Conditions {
+		c := &g.Conditions[i]
+		actual, passed := c.Eval(s, now)
+		if visit != nil {
+			visit(g, c, actual, passed)
+		}
+		all, some = all && passed, some || passed
+		if decided() {
+			break
+		}
+	}
+	for _, sub := range g.Groups {
+		if decided() {
+			break
+		}
+		passed := sub.Eval(s, now, visit)
+		all, some = all && passed, some || passed
+	}
+
+	switch g.Op {
+	case GroupAny:
+		return some
+	case GroupNot:
+		return !all
+	}
+	return all
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// toFilterList accepts a list of filter objects, as decoded from JSON or
+// written in Go.
+func toFilterList(v interface{}) ([]map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		return v, true
+	case []interface{}:
+		out := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			out[i] = m
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func parseCondition(key, field, op string, operand interface{}) (Condition, error) {
+	c := Condition{Key: key, Field: field, Op: op, Operand: operand}
+	kind := studyFields[field].kind
+	fail := func(format string, args ...interface{}) (Condition, error) {
+		return Condition{}, fmt.Errorf("%s %s: %s", key, op, fmt.Sprintf(format, args...))
+	}
+
+	switch op {
+	case OpEquals, OpIn, OpNotIn:
+		if kind == numberField {
+			nums, ok := toNumbers(operand, op == OpEquals)
+			if !ok {
+				return fail("expected %s, got %v", plural("a number", op), operand)
+			}
+			c.numbers = nums
+			return c, nil
+		}
+		texts, ok := toTexts(operand, op == OpEquals)
+		if !ok {
+			return fail("expected %s, got %v", plural("text", op), operand)
+		}
+		if field == "exam_day" {
+			for i, d := range texts {
+				day, ok := parseDay(d)
+				if !ok {
+					return fail("unknown day %q", d)
+				}
+				texts[i] = day
+			}
+		}
+		c.texts = texts
+
+	case OpPrefix:
+		if kind == numberField {
+			return fail("not supported on a numeric field")
+		}
+		texts, ok := toTexts(operand, false)
+		if !ok {
+			return fail("expected text or a list, got %v", operand)
+		}
+		c.texts = texts
+
+	case OpRegex:
+		if kind == numberField {
+			return fail("not supported on a numeric field")
+		}
+		expr, ok := operand.(string)
+		if !ok {
+			return fail("expected a pattern, got %v", operand)
+		}
+		re, err := compileRegex(expr)
+		if err != nil {
+			return fail("%v", err)
+		}
+		c.pattern = re
+
+	case OpGT, OpGTE, OpLT, OpLTE:
+		if kind != numberField {
+			return fail("only supported on numeric fields")
+		}
+		nums, ok := toNumbers(operand, true)
+		if !ok {
+			return fail("expected a number, got %v", operand)
+		}
+		c.numbers = nums
+
+	case OpExists, OpMissing:
+		want, ok := operand.(bool)
+		if !ok {
+			return fail("expected true or false, got %v", operand)
+		}
+		c.want = want
+
+	case OpTimeRange:
+		if field != "exam_time" {
+			return fail("only supported on exam_time")
+		}
+		s, _ := operand.(string)
+		r, err := ParseClockRange(s)
+		if err != nil {
+			return fail("%v", err)
+		}
+		c.clock = r
+
+	default:
+		return Condition{}, fmt.Errorf("%s: unknown operator %q", key, op)
+	}
+	return c, nil
+}
+
+// Eval tests the condition against a study, returning the value it saw.
+func (c *Condition) Eval(s *Study, now time.Time) (actual interface{}, passed bool) {
+	actual = studyFields[c.Field].value(s, now)
+	switch v := actual.(type) {
+	case string:
+		return actual, c.testText(v)
+	case float64:
+		return actual, c.testNumber(v)
+	case []string:
+		return actual, c.testList(v)
+	}
+	return actual, false
+}
+
+// Requires returns the only values the field can take for the condition to
+// pass, or false if it does not limit the field to a set of values.
+func (c *Condition) Requires() ([]string, bool) {
+	if c.Key != c.Field || studyFields[c.Field].kind != textField {
+		return nil, false
+	}
+	if c.Op != OpEquals && c.Op != OpIn {
+		return nil, false
+	}
+	return c.texts, true
+}
+
+func (c *Condition) testText(v string) bool {
+	switch c.Op {
+	case OpExists:
+		return (v != "") == c.want
+	case OpMissing:
+		return (v == "") == c.want
+	case OpNotIn:
+		return !c.matchText(v)
+	case OpTimeRange:
+		m, err := parseClock(v)
+		return err == nil && c.clock.Contains(m)
+	}
+	return c.matchText(v)
+}
+
+// matchText reports whether v satisfies eq, in, prefix or regex.
+func (c *Condition) matchText(v string) bool {
+	if c.pattern != nil {
+		return c.pattern.MatchString(v)
+	}
+	for _, t := range c.texts {
+		if v == t || c.Op == OpPrefix && strings.HasPrefix(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Condition) testNumber(v float64) bool {
+	switch c.Op {
+	case OpExists:
+		return (v != 0) == c.want
+	case OpMissing:
+		return (v == 0) == c.want
+	case OpGT:
+		return v > c.numbers[0]
+	case OpGTE:
+		return v >= c.numbers[0]
+	case OpLT:
+		return v < c.numbers[0]
+	case OpLTE:
+		return v <= c.numbers[0]
+	}
+	in := false
+	for _, n := range c.numbers {
+		if v == n {
+			in = true
+		}
+	}
+	return in == (c.Op != OpNotIn)
+}
+
+// testList passes when any element matches, or for not_in when none do.
+func (c *Condition) testList(values []string) bool {
+	switch c.Op {
+	case OpExists:
+		return (len(values) > 0) == c.want
+	case OpMissing:
+		return (len(values) == 0) == c.want
+	}
+	for _, v := range values {
+		if c.matchText(v) {
+			return c.Op != OpNotIn
+		}
+	}
+	return c.Op == OpNotIn
+}
+
+// ClockRange is a span of wall-clock minutes after midnight. It runs past
+// midnight when End is before Start.
+type ClockRange struct {
+	Start, End int
+}
+
+// ParseClockRange parses "HH:MM-HH:MM".
+func ParseClockRange(v string) (ClockRange, error) {
+	parts := strings.Split(v, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ClockRange{}, fmt.Errorf("%q is not HH:MM-HH:MM", v)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return ClockRange{}, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return ClockRange{}, err
+	}
+	return ClockRange{start, end}, nil
+}
+
+// Contains reports whether minute falls in the range, both ends included.
+func (r ClockRange) Contains(minute int) bool {
+	if r.End < r.Start {
+		return minute >= r.Start || minute <= r.End
+	}
+	return minute >= r.Start && minute <= r.End
+}
+
+// toTexts accepts a string, or a list of strings unless single is set.
+func toTexts(v interface{}, single bool) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		if single {
+			return nil, false
+		}
+		return append([]string(nil), v...), true
+	case []interface{}:
+		if single {
+			return nil, false
+		}
+		out := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// toNumbers accepts a number, or a list of numbers unless single is set.
+func toNumbers(v interface{}, single bool) ([]float64, bool) {
+	if n, ok := toNumber(v); ok {
+		return []float64{n}, true
+	}
+	if single {
+		return nil, false
+	}
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case []int:
+		for _, n := range v {
+			items = append(items, n)
+		}
+	case []float64:
+		for _, n := range v {
+			items = append(items, n)
+		}
+	default:
+		return nil, false
+	}
+	out := make([]float64, len(items))
+	for i, item := range items {
+		n, ok := toNumber(item)
+		if !ok {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func plural(what, op string) string {
+	if op == OpEquals {
+		return what
+	}
+	return what + " or a list"
+}
+
+// parseDay accepts a full or three-letter day name in any case.
+func parseDay(name string) (string, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := d.String()
+		if strings.EqualFold(name, full) || strings.EqualFold(name, full[:3]) {
+			return full, true
+		}
+	}
+	return "", false
+}
+
+var regexes sync.Map // pattern -> *regexp.Regexp
+
+// compileRegex is regexp.Compile with a cache, as rules are parsed for every
+// study.
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexes.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexes.Store(expr, re)
+	return re, nil
+}
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// matches parses filters and evaluates them against s, treating filters that
+// do not parse as never matching, as the engine does.
+func matches(filters map[string]interface{}, s *Study) bool {
+	g, err := ParseConditions(filters)
+	return err == nil && g.Eval(s, time.Now(), nil)
+}
+
+func TestConditions_ExtendedFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      *AssignmentRule
+		study     *Study
+		wantMatch bool
+	}{
+		{
+			name: "Procedure Code Match",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{"procedure_code": "CTHEAD"},
+			},
+			study:     &Study{ProcedureCode: "CTHEAD"},
+			wantMatch: true,
+		},
+		{
+			name: "Procedure Code Mismatch",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{"procedure_code": "CTHEAD"},
+			},
+			study:     &Study{ProcedureCode: "CTABD"},
+			wantMatch: false,
+		},
+		{
+			name: "Ordering Physician Match",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{"ordering_physician": "Dr. Smith"},
+			},
+			study:     &Study{OrderingPhysician: "Dr. Smith"},
+			wantMatch: true,
+		},
+		{
+			name: "Patient Age Range Match",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"patient_age_min": 10,
+					"patient_age_max": 20,
+				},
+			},
+			study:     &Study{PatientAge: 15},
+			wantMatch: true,
+		},
+		{
+			name: "Patient Age Too Young",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"patient_age_min": 10,
+				},
+			},
+			study:     &Study{PatientAge: 5},
+			wantMatch: false,
+		},
+		{
+			name: "Patient Age Too Old",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"patient_age_max": 65,
+				},
+			},
+			study:     &Study{PatientAge: 70},
+			wantMatch: false,
+		},
+		{
+			name: "Time Range Match (Day)",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"exam_time_range": "08:00-12:00",
+				},
+			},
+			study:     &Study{Timestamp: "20231010090000"}, // 09:00
+			wantMatch: true,
+		},
+		{
+			name: "Time Range Mismatch (Day)",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"exam_time_range": "08:00-12:00",
+				},
+			},
+			study:     &Study{Timestamp: "20231010130000"}, // 13:00
+			wantMatch: false,
+		},
+		{
+			name: "Time Range Match (Overnight)",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"exam_time_range": "22:00-06:00",
+				},
+			},
+			study:     &Study{Timestamp: "20231010230000"}, // 23:00
+			wantMatch: true,
+		},
+		{
+			name: "Time Range Match (Overnight Early Morning)",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"exam_time_range": "22:00-06:00",
+				},
+			},
+			study:     &Study{Timestamp: "20231011050000"}, // 05:00
+			wantMatch: true,
+		},
+		{
+			name: "Day of Week Match",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"days_of_week": []string{"Monday", "Wednesday"},
+				},
+			},
+			study: &Study{
+				// 2023-10-09 is a Monday
+				Timestamp: "20231009100000",
+			},
+			wantMatch: true,
+		},
+		{
+			name: "Day of Week Mismatch",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"days_of_week": []string{"Tuesday", "Thursday"},
+				},
+			},
+			study: &Study{
+				// 2023-10-09 is a Monday
+				Timestamp: "20231009100000",
+			},
+			wantMatch: false,
+		},
+		{
+			name: "Day of Week Interface Slice",
+			rule: &AssignmentRule{
+				ConditionFilters: map[string]interface{}{
+					"days_of_week": []interface{}{"Monday", "Wednesday"},
+				},
+			},
+			study: &Study{
+				Timestamp: "20231009100000",
+			},
+			wantMatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(tt.rule.ConditionFilters, tt.study); got != tt.wantMatch {
+				t.Errorf("Expected match %v, got %v", tt.wantMatch, got)
+			}
+		})
+	}
+}
+
+func TestClockRange_Contains(t *testing.T) {
+	tests := []struct {
+		current  string
+		rangeStr string
+		want     bool
+	}{
+		{"09:00", "08:00-12:00", true},
+		{"08:00", "08:00-12:00", true},
+		{"12:00", "08:00-12:00", true},
+		{"07:59", "08:00-12:00", false},
+		{"12:01", "08:00-12:00", false},
+		{"23:00", "22:00-06:00", true},
+		{"05:00", "22:00-06:00", true},
+		{"12:00", "22:00-06:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current+" in "+tt.rangeStr, func(t *testing.T) {
+			r, err := ParseClockRange(tt.rangeStr)
+			if err != nil {
+				t.Fatalf("Expected %q to parse, got %v", tt.rangeStr, err)
+			}
+			at, _ := time.Parse("15:04", tt.current)
+			if got := r.Contains(at.Hour()*60 + at.Minute()); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "08:00", "8-12", "08:00-25:00"} {
+		if _, err := ParseClockRange(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestConditions_Operators(t *testing.T) {
+	study := &Study{
+		Modality:      "CT",
+		ProcedureCode: "CTHEAD",
+		Indication:    "Suspected Stroke, left side weakness",
+		Urgency:       "STAT",
+		PatientAge:    72,
+		Attributes:    []string{"contrast"},
+		IngestTime:    time.Now().Add(-45 * time.Minute),
+	}
+
+	tests := []struct {
+		name      string
+		filters   map[string]interface{}
+		wantMatch bool
+	}{
+		{"modality in", map[string]interface{}{"modality": map[string]interface{}{"in": []interface{}{"CT", "MR"}}}, true},
+		{"modality not in", map[string]interface{}{"modality": map[string]interface{}{"not_in": []string{"CT"}}}, false},
+		{"modality equality", map[string]interface{}{"modality": "MR"}, false},
+		{"procedure prefix", map[string]interface{}{"procedure_code": map[string]interface{}{"prefix": "CT"}}, true},
+		{"procedure prefix list", map[string]interface{}{"procedure_code": map[string]interface{}{"prefix": []string{"MR", "XR"}}}, false},
+		{"indication regex", map[string]interface{}{"indication": map[string]interface{}{"regex": "(?i)stroke"}}, true},
+		{"indication regex miss", map[string]interface{}{"indication": map[string]interface{}{"regex": "^trauma"}}, false},
+		{"age in range", map[string]interface{}{"patient_age": map[string]interface{}{"gte": 65, "lt": 80.0}}, true},
+		{"age out of range", map[string]interface{}{"patient_age": map[string]interface{}{"gte": 18, "lt": 65}}, false},
+		{"age list", map[string]interface{}{"patient_age": map[string]interface{}{"in": []interface{}{70.0, 72.0}}}, true},
+		{"wait time", map[string]interface{}{"age_minutes": map[string]interface{}{"gt": 30}}, true},
+		{"technician missing", map[string]interface{}{"technician": map[string]interface{}{"missing": true}}, true},
+		{"technician exists", map[string]interface{}{"technician": map[string]interface{}{"exists": true}}, false},
+		{"attribute in", map[string]interface{}{"attributes": map[string]interface{}{"in": []string{"contrast"}}}, true},
+		{"attribute not in", map[string]interface{}{"attributes": map[string]interface{}{"not_in": []string{"contrast"}}}, false},
+		{"all must hold", map[string]interface{}{"modality": "CT", "urgency": map[string]interface{}{"in": []string{"ROUTINE"}}}, false},
+		// Invalid filters never match rather than panic
+		{"numeric urgency", map[string]interface{}{"urgency": 1}, false},
+		{"unknown field", map[string]interface{}{"colour": "red"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(tt.filters, study); got != tt.wantMatch {
+				t.Errorf("Expected match %v, got %v", tt.wantMatch, got)
+			}
+		})
+	}
+}
+
+func TestConditions_Groups(t *testing.T) {
+	// STAT at Remote, or any study from Partner Network
+	filters := map[string]interface{}{
+		"any": []interface{}{
+			map[string]interface{}{"urgency": "STAT", "site": "Remote"},
+			map[string]interface{}{"site": "Partner Network"},
+		},
+		"not": map[string]interface{}{"modality": "XR"},
+	}
+
+	tests := []struct {
+		name      string
+		study     *Study
+		wantMatch bool
+	}{
+		{"STAT at Remote", &Study{Urgency: "STAT", Site: "Remote", Modality: "CT"}, true},
+		{"routine at Remote", &Study{Urgency: "ROUTINE", Site: "Remote", Modality: "CT"}, false},
+		{"routine from Partner", &Study{Urgency: "ROUTINE", Site: "Partner Network", Modality: "CT"}, true},
+		{"excluded modality", &Study{Urgency: "STAT", Site: "Remote", Modality: "XR"}, false},
+		{"elsewhere", &Study{Urgency: "STAT", Site: "Main", Modality: "CT"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matches(filters, tt.study); got != tt.wantMatch {
+				t.Errorf("Expected match %v, got %v", tt.wantMatch, got)
+			}
+		})
+	}
+
+	// Visited, every condition is reported with its place in the tree
+	g, err := ParseConditions(filters)
+	if err != nil {
+		t.Fatalf("Expected the filters to parse, got %v", err)
+	}
+	var groups []string
+	visit := func(g *ConditionGroup, c *Condition, actual interface{}, passed bool) {
+		groups = append(groups, g.Path+"/"+c.Key)
+	}
+	if !g.Eval(tests[2].study, time.Now(), visit) {
+		t.Error("Expected the visited conditions to match")
+	}
+	want := []string{"any[0]/site", "any[0]/urgency", "any[1]/site", "not/modality"}
+	if strings.Join(groups, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected traced conditions %v, got %v", want, groups)
+	}
+}
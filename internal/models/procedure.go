@@ -11,3 +11,63 @@ type Procedure struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// MaxUnmappedSamples bounds the example studies kept per unmapped procedure
+const MaxUnmappedSamples = 5
+
+// UnmappedProcedure is a procedure code and description that could not be
+// normalized, queued for review until an admin adds it to the catalog.
+type UnmappedProcedure struct {
+	Code           string    `json:"code"`
+	Description    string    `json:"description"`
+	Modality       string    `json:"modality"`  // As sent by the site
+	BodyPart       string    `json:"body_part"` // As sent by the site
+	Sites          []string  `json:"sites"`
+	Occurrences    int64     `json:"occurrences"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+	SampleStudyIDs []string  `json:"sample_study_ids"`
+	SampleMessages []string  `json:"sample_messages,omitempty"`
+}
+
+// Key identifies an unmapped code/description pair
+func (u *UnmappedProcedure) Key() string {
+	return UnmappedProcedureKey(u.Code, u.Description)
+}
+
+func UnmappedProcedureKey(code, description string) string {
+	return code + "|" + description
+}
+
+// Record counts another occurrence of the procedure in study, keeping the
+// first few studies and raw messages as samples.
+func (u *UnmappedProcedure) Record(study *Study, at time.Time) {
+	if u.Occurrences == 0 {
+		u.Code = study.ProcedureCode
+		u.Description = study.ProcedureDescription
+		u.Modality = study.Modality
+		u.BodyPart = study.BodyPart
+		u.FirstSeen = at
+	}
+	u.Occurrences++
+	u.LastSeen = at
+
+	if study.Site != "" {
+		known := false
+		for _, s := range u.Sites {
+			if s == study.Site {
+				known = true
+				break
+			}
+		}
+		if !known {
+			u.Sites = append(u.Sites, study.Site)
+		}
+	}
+	if len(u.SampleStudyIDs) < MaxUnmappedSamples {
+		u.SampleStudyIDs = append(u.SampleStudyIDs, study.ID)
+		if study.RawMessage != "" {
+			u.SampleMessages = append(u.SampleMessages, study.RawMessage)
+		}
+	}
+}
@@ -1,15 +1,151 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	RadiologistStatusActive   = "active"
+	RadiologistStatusInactive = "inactive"
+)
+
+// RadiologistStatuses are the values accepted in Radiologist.Status.
+var RadiologistStatuses = []string{RadiologistStatusActive, RadiologistStatusInactive}
+
+// Availability is where an active radiologist is in their working day.
+// AvailabilityAtCapacity is never stored: it is derived from open workload.
+const (
+	AvailabilityOnShift    = "on_shift"
+	AvailabilityAvailable  = "available"
+	AvailabilityAtCapacity = "at_capacity"
+	AvailabilityOffline    = "offline"
+	AvailabilityBreak      = "break"
+)
+
+// Availabilities are the states that can be set on a radiologist.
+var Availabilities = []string{AvailabilityOnShift, AvailabilityAvailable, AvailabilityBreak, AvailabilityOffline}
+
+// availabilityTransitions lists the states each state can move to. A
+// radiologist has to be back on before they can go on a break.
+var availabilityTransitions = map[string][]string{
+	AvailabilityOnShift:   {AvailabilityAvailable, AvailabilityBreak, AvailabilityOffline},
+	AvailabilityAvailable: {AvailabilityOnShift, AvailabilityBreak, AvailabilityOffline},
+	AvailabilityBreak:     {AvailabilityOnShift, AvailabilityAvailable, AvailabilityOffline},
+	AvailabilityOffline:   {AvailabilityOnShift, AvailabilityAvailable},
+}
 
 type Radiologist struct {
-	ID                   string    `json:"id"`
-	FirstName            string    `json:"first_name"`
-	LastName             string    `json:"last_name"`
-	Credentials          []string  `json:"credentials"`
-	Specialties          []string  `json:"specialties"`
-	MaxConcurrentStudies int       `json:"max_concurrent_studies"`
-	Status               string    `json:"status"` // active, inactive
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	ID                   string   `json:"id"`
+	FirstName            string   `json:"first_name"`
+	LastName             string   `json:"last_name"`
+	Credentials          []string `json:"credentials"`
+	Specialties          []string `json:"specialties"`
+	MaxConcurrentStudies int      `json:"max_concurrent_studies"` // 0 for no limit
+	Status               string   `json:"status"`                 // active, inactive
+
+	// Availability is one of Availabilities; radiologists saved before it
+	// existed have none and count as available
+	Availability          string    `json:"availability"`
+	AvailabilityChangedAt time.Time `json:"availability_changed_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StoredAvailability returns the availability last set, treating none as
+// available.
+func (r *Radiologist) StoredAvailability() string {
+	if r.Availability == "" {
+		return AvailabilityAvailable
+	}
+	return r.Availability
+}
+
+// CurrentAvailability returns the radiologist's availability given their open
+// workload: a radiologist taking work who has reached MaxConcurrentStudies is
+// at capacity.
+func (r *Radiologist) CurrentAvailability(openWorkload int64) string {
+	a := r.StoredAvailability()
+	if !r.TakesWork() {
+		return a
+	}
+	if r.MaxConcurrentStudies > 0 && openWorkload >= int64(r.MaxConcurrentStudies) {
+		return AvailabilityAtCapacity
+	}
+	return a
+}
+
+// TakesWork reports whether the availability last set lets the radiologist
+// be assigned studies, capacity aside.
+func (r *Radiologist) TakesWork() bool {
+	a := r.StoredAvailability()
+	return a == AvailabilityOnShift || a == AvailabilityAvailable
+}
+
+// SetAvailability moves the radiologist to availability a at the given time.
+// Setting the current state again keeps the original timestamp.
+func (r *Radiologist) SetAvailability(a string, at time.Time) error {
+	from := r.StoredAvailability()
+	if a == from {
+		if r.Availability == "" {
+			r.Availability = a
+			r.AvailabilityChangedAt = at
+		}
+		return nil
+	}
+	if a == AvailabilityAtCapacity {
+		return fmt.Errorf("%s is derived from workload and cannot be set", a)
+	}
+	if _, ok := availabilityTransitions[a]; !ok {
+		return fmt.Errorf("unknown availability %q", a)
+	}
+	for _, to := range availabilityTransitions[from] {
+		if to == a {
+			r.Availability = a
+			r.AvailabilityChangedAt = at
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot change availability from %s to %s", from, a)
+}
+
+// Validate checks the radiologist's fields, and that every credential is one
+// of known. Credential codes match case-insensitively and are rewritten to the
+// reference spelling.
+func (r *Radiologist) Validate(known []Credential) error {
+	if strings.TrimSpace(r.ID) == "" {
+		return fmt.Errorf("id is required")
+	}
+	if r.MaxConcurrentStudies < 0 {
+		return fmt.Errorf("max concurrent studies cannot be negative")
+	}
+	validStatus := false
+	for _, s := range RadiologistStatuses {
+		if r.Status == s {
+			validStatus = true
+			break
+		}
+	}
+	if !validStatus {
+		return fmt.Errorf("unknown status %q", r.Status)
+	}
+	if _, ok := availabilityTransitions[r.Availability]; r.Availability != "" && !ok {
+		return fmt.Errorf("unknown availability %q", r.Availability)
+	}
+	for i, code := range r.Credentials {
+		matched := false
+		for _, c := range known {
+			if strings.EqualFold(c.Code, code) {
+				r.Credentials[i] = c.Code
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unknown credential %q", code)
+		}
+	}
+	return nil
 }
@@ -2,6 +2,11 @@ package models
 
 import "time"
 
+const (
+	RosterStatusActive   = "active"
+	RosterStatusInactive = "inactive"
+)
+
 type RosterEntry struct {
 	ID            int64      `json:"id"`
 	ShiftID       int64      `json:"shift_id"`
@@ -12,3 +17,26 @@ type RosterEntry struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
+
+// EffectiveAt reports whether the entry rosters its radiologist at t. Start
+// and end dates are calendar days in t's zone, so an entry ending on the 5th
+// still covers the evening of the 5th. A zero StartDate or nil EndDate leaves
+// that side open, and entries saved without a status count as active.
+func (e *RosterEntry) EffectiveAt(t time.Time) bool {
+	if e.Status != "" && e.Status != RosterStatusActive {
+		return false
+	}
+	if !e.StartDate.IsZero() && t.Before(dayIn(e.StartDate, t.Location())) {
+		return false
+	}
+	if e.EndDate != nil && !t.Before(dayIn(*e.EndDate, t.Location()).AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// dayIn returns midnight at the start of date's calendar day in loc.
+func dayIn(date time.Time, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
@@ -1,19 +1,67 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type AssignmentRule struct {
 	ID               int64                  `json:"id"`
 	Name             string                 `json:"name"`
 	PriorityOrder    int                    `json:"priority_order"`
-	ConditionFilters map[string]interface{} `json:"condition_filters"`
-	ActionType       string                 `json:"action_type"` // ASSIGN_TO_SHIFT, ASSIGN_TO_RADIOLOGIST, ESCALATE
+	ConditionFilters map[string]interface{} `json:"condition_filters"` // See ParseConditions
+	ActionType       string                 `json:"action_type"`       // ASSIGN_TO_SHIFT, ASSIGN_TO_RADIOLOGIST, ESCALATE
 	ActionTarget     string                 `json:"action_target"`
+	Chain            string                 `json:"chain"` // One of RuleChains; empty is RuleChainContinue
+	Soft             bool                   `json:"soft"`  // Skip a filter that would leave no candidates
 	Enabled          bool                   `json:"enabled"`
 	CreatedAt        time.Time              `json:"created_at"`
 	UpdatedAt        time.Time              `json:"updated_at"`
 }
 
+// How a matching rule affects the rules after it. Rules are evaluated in
+// priority order.
+const (
+	// RuleChainContinue applies the action and goes on to the next rule, so
+	// every matching rule narrows the candidates in turn.
+	RuleChainContinue = "continue"
+	// RuleChainTerminal applies the action and stops: no later rule is
+	// evaluated.
+	RuleChainTerminal = "terminal"
+	// RuleChainFallback only applies when the rules before it have left no
+	// candidates, and then starts again from every candidate the study had
+	// before any rule ran.
+	RuleChainFallback = "fallback"
+)
+
+// RuleChains are the accepted values of AssignmentRule.Chain.
+var RuleChains = []string{RuleChainContinue, RuleChainTerminal, RuleChainFallback}
+
+// ChainMode returns the rule's chain, treating empty as RuleChainContinue.
+func (r *AssignmentRule) ChainMode() string {
+	if r.Chain == "" {
+		return RuleChainContinue
+	}
+	return r.Chain
+}
+
+// Validate checks the chain and the condition filters, so a rule that cannot
+// be evaluated is refused when it is saved rather than skipped for every
+// study.
+func (r *AssignmentRule) Validate() error {
+	known := false
+	for _, c := range RuleChains {
+		known = known || r.ChainMode() == c
+	}
+	if !known {
+		return fmt.Errorf("unknown rule chain %q", r.Chain)
+	}
+	if _, err := ParseConditions(r.ConditionFilters); err != nil {
+		return fmt.Errorf("conditions: %w", err)
+	}
+	return nil
+}
+
 // Matches checks if the rule applies to the given study
 func (r *AssignmentRule) Matches(study *Study) bool {
 	// Implementation will go here or in engine logic
@@ -1,14 +1,245 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
 
 type Shift struct {
-	ID                  int64     `json:"id"`
-	Name                string    `json:"name"`
-	WorkType            string    `json:"work_type"`
-	Sites               []string  `json:"sites"`
-	PriorityLevel       int       `json:"priority_level"`
-	RequiredCredentials []string  `json:"required_credentials"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	WorkType string `json:"work_type"` // Display label; the modality for shifts without Modalities
+
+	// Coverage: the work the shift takes. An empty set or one containing "*"
+	// matches anything. Codes match case-insensitively, sites exactly.
+	Modalities []string `json:"modalities"`
+	BodyParts  []string `json:"body_parts"`
+	Urgencies  []string `json:"urgencies"`
+	Sites      []string `json:"sites"`
+
+	PriorityLevel       int      `json:"priority_level"`
+	RequiredCredentials []string `json:"required_credentials"`
+
+	// Operating hours as HH:MM wall-clock times in TimeZone. A shift whose
+	// EndTime is at or before its StartTime runs past midnight into the next
+	// day; leaving both empty runs the shift around the clock.
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	// Days the shift starts on ("Mon" to "Sun"); empty for every day
+	Days []string `json:"days"`
+	// IANA zone name, e.g. Australia/Brisbane; empty for the server's zone
+	TimeZone string `json:"time_zone"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wildcard in a coverage set matches any value.
+const Wildcard = "*"
+
+// Urgencies are the normalized order priorities a shift can cover.
+var Urgencies = []string{"STAT", "ASAP", "ROUTINE"}
+
+// ShiftQuery describes a piece of work to find shifts for. Empty fields are
+// not filtered on.
+type ShiftQuery struct {
+	Modality string
+	BodyPart string
+	Urgency  string
+	Site     string
+}
+
+// CoveredModalities returns the modality set, falling back to WorkType for
+// shifts saved before coverage sets existed.
+func (s *Shift) CoveredModalities() []string {
+	if len(s.Modalities) > 0 || s.WorkType == "" {
+		return s.Modalities
+	}
+	return []string{s.WorkType}
+}
+
+// Covers reports whether the shift takes the work described by q, ignoring
+// operating hours and priority.
+func (s *Shift) Covers(q ShiftQuery) bool {
+	return coverageMatches(s.CoveredModalities(), q.Modality, true) &&
+		coverageMatches(s.BodyParts, q.BodyPart, true) &&
+		coverageMatches(s.Urgencies, q.Urgency, true) &&
+		coverageMatches(s.Sites, q.Site, false)
+}
+
+// IsWildcard reports whether a coverage set matches any value.
+func IsWildcard(set []string) bool {
+	if len(set) == 0 {
+		return true
+	}
+	for _, v := range set {
+		if v == Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+func coverageMatches(set []string, value string, foldCase bool) bool {
+	if value == "" || IsWildcard(set) {
+		return true
+	}
+	for _, v := range set {
+		if v == value || foldCase && strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Weekdays are the day abbreviations accepted in Shift.Days, Monday first.
+var Weekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Validate checks the priority, operating hours and time zone. Shifts with a
+// negative priority never take work, so they are refused rather than saved
+// and silently left out of matching.
+func (s *Shift) Validate() error {
+	if s.PriorityLevel < 0 {
+		return fmt.Errorf("priority must be zero or more, got %d", s.PriorityLevel)
+	}
+	if _, err := parseClock(s.StartTime); err != nil {
+		return fmt.Errorf("start time: %w", err)
+	}
+	if _, err := parseClock(s.EndTime); err != nil {
+		return fmt.Errorf("end time: %w", err)
+	}
+	for _, d := range s.Days {
+		if _, ok := weekdayByName(d); !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	if _, err := loadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("time zone: %w", err)
+	}
+	return nil
+}
+
+// Overnight reports whether the shift crosses midnight.
+func (s *Shift) Overnight() bool {
+	start, end := s.clockRange()
+	return end <= start && s.hasHours()
+}
+
+// Location returns the shift's time zone, falling back to the server's when
+// it is empty or unknown.
+func (s *Shift) Location() *time.Location {
+	loc, err := loadLocation(s.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ActiveAt reports whether the shift is running at t.
+func (s *Shift) ActiveAt(t time.Time) bool {
+	if !s.hasHours() && len(s.Days) == 0 {
+		return true
+	}
+	local := t.In(s.Location())
+	minute := local.Hour()*60 + local.Minute()
+	start, end := s.clockRange()
+
+	if start < end {
+		return minute >= start && minute < end && s.runsOn(local.Weekday())
+	}
+	// Overnight or a full 24 hours: before the end it is still the previous
+	// day's shift
+	if minute >= start {
+		return s.runsOn(local.Weekday())
+	}
+	if minute < end {
+		return s.runsOn((local.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// Window returns when the shift starting on date's calendar day begins and
+// ends, in the shift's time zone. ok is false if the shift does not run that
+// day.
+func (s *Shift) Window(date time.Time) (start, end time.Time, ok bool) {
+	if !s.runsOn(date.Weekday()) {
+		return time.Time{}, time.Time{}, false
+	}
+	y, m, d := date.Date()
+	startMin, endMin := s.clockRange()
+	start = time.Date(y, m, d, startMin/60, startMin%60, 0, 0, s.Location())
+	end = time.Date(y, m, d, endMin/60, endMin%60, 0, 0, s.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
+
+func (s *Shift) hasHours() bool {
+	return s.StartTime != "" || s.EndTime != ""
+}
+
+// clockRange returns the start and end as minutes after midnight. A missing
+// start is midnight and a missing end is the end of the day.
+func (s *Shift) clockRange() (start, end int) {
+	start, _ = parseClock(s.StartTime)
+	end = 24 * 60
+	if s.EndTime != "" {
+		end, _ = parseClock(s.EndTime)
+	}
+	return start, end
+}
+
+func (s *Shift) runsOn(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if wd, ok := weekdayByName(d); ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into minutes after midnight; empty is midnight.
+func parseClock(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func weekdayByName(name string) (time.Weekday, bool) {
+	for i, d := range Weekdays {
+		if d == name {
+			return time.Weekday((i + 1) % 7), true
+		}
+	}
+	return 0, false
+}
+
+var locations sync.Map // zone name -> *time.Location
+
+// loadLocation is time.LoadLocation with a cache, as shifts are checked for
+// every study.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
 }
@@ -2,13 +2,24 @@ package models
 
 import "time"
 
+// ORC-1 order control codes
+const (
+	OrderControlNew         = "NW"
+	OrderControlCancel      = "CA"
+	OrderControlDiscontinue = "DC"
+	OrderControlChange      = "XO"
+	OrderControlStatus      = "SC" // Status changed, ORC-5 carries the new status
+	OrderControlResult      = "RE" // Observations to follow, as sent on ORU^R01
+)
+
 type Study struct {
-	ID         string    `json:"id"`
-	MessageID  string    `json:"message_id"`
-	Site       string    `json:"site"`
-	Timestamp  string    `json:"timestamp"` // HL7 timestamp format
-	Modality   string    `json:"modality"`
-	BodyPart   string    `json:"body_part"`
+	ID                   string    `json:"id"`
+	MessageID            string    `json:"message_id"`
+	OrderControl         string    `json:"order_control"` // ORC-1, NW when absent
+	Site                 string    `json:"site"`
+	Timestamp            string    `json:"timestamp"` // HL7 timestamp format
+	Modality             string    `json:"modality"`
+	BodyPart             string    `json:"body_part"`
 	Urgency              string    `json:"urgency"`
 	Indication           string    `json:"indication"`
 	ProcedureCode        string    `json:"procedure_code"`
@@ -19,16 +30,44 @@ type Study struct {
 	PriorLocation        string    `json:"prior_location"`
 	Technician           string    `json:"technician"`
 	Transcriptionist     string    `json:"transcriptionist"`
+	RawMessage           string    `json:"raw_message,omitempty"`   // Original HL7, kept for outbound enrichment
+	ReportStatus         string    `json:"report_status,omitempty"` // Assignment status from ORC-5 or OBR-25
+
+	// Set by the engine when the procedure is normalized
+	Attributes              []string `json:"attributes,omitempty"`
+	NormalizationSource     string   `json:"normalization_source,omitempty"` // catalog, description or unmapped
+	NormalizationConfidence float64  `json:"normalization_confidence"`
 }
 
+// GetExamTime returns when the exam was performed. The HL7 timestamp is the
+// sender's wall-clock time, so it is read in the server's local zone.
 func (s *Study) GetExamTime() time.Time {
 	if s.Timestamp == "" {
 		return s.IngestTime
 	}
 	// Try parsing HL7 format YYYYMMDDHHMMSS
-	t, err := time.Parse("20060102150405", s.Timestamp)
-	if err == nil {
+	if t, err := time.ParseInLocation("20060102150405", s.Timestamp, time.Local); err == nil {
 		return t
 	}
 	return s.IngestTime
 }
+
+// IsCancellation reports whether the order withdraws a previously sent study.
+func (s *Study) IsCancellation() bool {
+	return s.OrderControl == OrderControlCancel || s.OrderControl == OrderControlDiscontinue
+}
+
+// IsStatusUpdate reports whether the message only reports progress on an
+// existing order, e.g. the study being read or the report finalized.
+func (s *Study) IsStatusUpdate() bool {
+	return s.OrderControl == OrderControlStatus || s.OrderControl == OrderControlResult
+}
+
+// RoutingChanged reports whether any attribute that drives shift matching
+// differs between two versions of the same order.
+func (s *Study) RoutingChanged(prev *Study) bool {
+	return s.Modality != prev.Modality ||
+		s.BodyPart != prev.BodyPart ||
+		s.Urgency != prev.Urgency ||
+		s.Site != prev.Site
+}
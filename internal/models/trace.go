@@ -0,0 +1,63 @@
+package models
+
+// AssignmentTrace records how the engine reached a decision, step by step,
+// so an unexpected assignment can be explained after the fact.
+type AssignmentTrace struct {
+	Shifts     []TraceShift     `json:"shifts"`     // Matched and running at the study's time
+	Candidates []TraceCandidate `json:"candidates"` // Eligible and below capacity, before any rule
+	Rules      []TraceRule      `json:"rules"`      // Active rules in evaluation order
+	Removed    []TraceRemoval   `json:"removed"`    // Rostered radiologists dropped, by step
+	Scores     []TraceScore     `json:"scores"`     // Load balancing over the remaining candidates
+	Outcome    string           `json:"outcome"`
+}
+
+type TraceShift struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	PriorityLevel int    `json:"priority_level"`
+}
+
+type TraceCandidate struct {
+	RadiologistID string `json:"radiologist_id"`
+	ShiftID       int64  `json:"shift_id"`
+	CurrentLoad   int64  `json:"current_load"`
+}
+
+type TraceRule struct {
+	ID         int64            `json:"id"`
+	Name       string           `json:"name"`
+	ActionType string           `json:"action_type"`
+	Matched    bool             `json:"matched"`
+	Conditions []TraceCondition `json:"conditions"`
+	Note       string           `json:"note,omitempty"` // How the rule's chain settings played out
+}
+
+// TraceCondition is one rule condition checked against the study. Group is
+// where it sits in the condition tree, empty at the top level.
+type TraceCondition struct {
+	Group    string      `json:"group,omitempty"`
+	Field    string      `json:"field"`
+	Op       string      `json:"op"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+	Passed   bool        `json:"passed"`
+}
+
+// TraceRemoval is a radiologist taken out of the running. Step is
+// "eligibility" or "capacity" during resolution, or the action type of the
+// rule identified by RuleID.
+type TraceRemoval struct {
+	Step          string `json:"step"`
+	RuleID        int64  `json:"rule_id,omitempty"`
+	RadiologistID string `json:"radiologist_id"`
+	ShiftID       int64  `json:"shift_id"`
+	Reason        string `json:"reason"`
+}
+
+type TraceScore struct {
+	RadiologistID        string `json:"radiologist_id"`
+	ShiftID              int64  `json:"shift_id"`
+	CurrentLoad          int64  `json:"current_load"`
+	MaxConcurrentStudies int    `json:"max_concurrent_studies"`
+	Selected             bool   `json:"selected"`
+}
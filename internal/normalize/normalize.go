@@ -0,0 +1,169 @@
+// Package normalize maps the procedure details sent by each site onto the
+// canonical modality, body part and attributes used for routing.
+package normalize
+
+import (
+	"radiology-assignment/internal/models"
+	"strings"
+	"unicode"
+)
+
+// Sources recorded in Study.NormalizationSource
+const (
+	SourceCatalog     = "catalog"     // Procedure code found in the catalog
+	SourceDescription = "description" // Body part matched a synonym in the description or code
+	SourceUnmapped    = "unmapped"    // Nothing matched, the values sent by the site are kept
+)
+
+// Confidence recorded in Study.NormalizationConfidence for each way a body
+// part can be resolved.
+const (
+	ConfidenceCatalog   = 1.0
+	ConfidenceCanonical = 0.9 // Token is the body part's own name
+	ConfidenceSynonym   = 0.7 // Token is a listed synonym or misspelling
+	ambiguityPenalty    = 0.2 // Tokens pointed at more than one body part
+)
+
+// Normalizer resolves body parts and attributes from free text using the
+// synonym tables in models.DefaultBodyPart and models.DefaultAttributes.
+type Normalizer struct {
+	bodyParts  map[string]string // synonym -> body part name
+	attributes map[string]string // synonym -> attribute code
+}
+
+// New indexes the given synonym tables. Both tables list synonyms as a comma
+// separated string.
+func New(bodyParts []*models.BodyPart, attributes []*models.Attribute) *Normalizer {
+	n := &Normalizer{
+		bodyParts:  make(map[string]string),
+		attributes: make(map[string]string),
+	}
+	for _, bp := range bodyParts {
+		n.bodyParts[strings.ToUpper(bp.Name)] = bp.Name
+		for _, syn := range splitSynonyms(bp.SearchString) {
+			if _, exists := n.bodyParts[syn]; !exists {
+				n.bodyParts[syn] = bp.Name
+			}
+		}
+	}
+	for _, attr := range attributes {
+		n.attributes[strings.ToUpper(attr.Code)] = attr.Code
+		for _, syn := range splitSynonyms(attr.Name) {
+			if _, exists := n.attributes[syn]; !exists {
+				n.attributes[syn] = attr.Code
+			}
+		}
+	}
+	return n
+}
+
+// Default returns a Normalizer over the built-in synonym tables.
+func Default() *Normalizer {
+	return New(models.DefaultBodyPart, models.DefaultAttributes)
+}
+
+func splitSynonyms(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if syn := strings.ToUpper(strings.TrimSpace(part)); syn != "" {
+			result = append(result, syn)
+		}
+	}
+	return result
+}
+
+// Normalize fills in the study's body part, attributes and normalization
+// source and confidence. proc is the catalog entry for the study's procedure
+// code, or nil when the code is not in the catalog. Attributes are always
+// taken from the description.
+func (n *Normalizer) Normalize(study *models.Study, proc *models.Procedure) {
+	descTokens := tokenize(study.ProcedureDescription)
+	study.Attributes = n.matchAttributes(descTokens)
+
+	if proc != nil {
+		if proc.Modality != "" {
+			study.Modality = proc.Modality
+		}
+		if proc.BodyPart != "" {
+			study.BodyPart = proc.BodyPart
+		}
+		study.NormalizationSource = SourceCatalog
+		study.NormalizationConfidence = ConfidenceCatalog
+		return
+	}
+
+	// The description is the most specific; the code and the body part the
+	// site sent are often abbreviations such as CTHEAD or ABDOEMN
+	bodyPart, confidence := n.matchBodyPart(descTokens, tokenize(study.ProcedureCode), tokenize(study.BodyPart))
+	if bodyPart == "" {
+		study.NormalizationSource = SourceUnmapped
+		study.NormalizationConfidence = 0
+		return
+	}
+	study.BodyPart = bodyPart
+	study.NormalizationSource = SourceDescription
+	study.NormalizationConfidence = confidence
+}
+
+// matchBodyPart returns the first body part found, searching each token
+// group in turn. A token that does not match on its own is tried together
+// with the next one in its group, both as written ("KNEES BOTH") and joined
+// ("LOWER EXTREMITY" -> LOWEREXTREMITY).
+func (n *Normalizer) matchBodyPart(groups ...[]string) (string, float64) {
+	var found string
+	confidence := 0.0
+	ambiguous := false
+
+	for _, tokens := range groups {
+		for i := range tokens {
+			candidates := []string{tokens[i]}
+			if i+1 < len(tokens) {
+				candidates = append(candidates, tokens[i]+" "+tokens[i+1], tokens[i]+tokens[i+1])
+			}
+			for _, c := range candidates {
+				name, ok := n.bodyParts[c]
+				if !ok {
+					continue
+				}
+				matchConfidence := ConfidenceSynonym
+				if c == strings.ToUpper(name) {
+					matchConfidence = ConfidenceCanonical
+				}
+				switch {
+				case found == "":
+					found, confidence = name, matchConfidence
+				case name != found:
+					ambiguous = true
+				case matchConfidence > confidence:
+					confidence = matchConfidence
+				}
+				break
+			}
+		}
+	}
+
+	if ambiguous {
+		confidence -= ambiguityPenalty
+	}
+	return found, confidence
+}
+
+func (n *Normalizer) matchAttributes(tokens []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, t := range tokens {
+		if code, ok := n.attributes[t]; ok && !seen[code] {
+			seen[code] = true
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// tokenize upper-cases s and splits it on anything that is not a letter or
+// digit.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
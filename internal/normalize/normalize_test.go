@@ -0,0 +1,102 @@
+package normalize
+
+import (
+	"radiology-assignment/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	n := Default()
+
+	tests := []struct {
+		name           string
+		study          models.Study
+		proc           *models.Procedure
+		wantModality   string
+		wantBodyPart   string
+		wantSource     string
+		wantConfidence float64
+		wantAttributes []string
+	}{
+		{
+			name:           "Catalog match wins",
+			study:          models.Study{ProcedureCode: "CTHEAD", ProcedureDescription: "CT ABDOMEN", Modality: "CT", BodyPart: "ABDOMEN"},
+			proc:           &models.Procedure{Code: "CTHEAD", Modality: "CT", BodyPart: "Head"},
+			wantModality:   "CT",
+			wantBodyPart:   "Head",
+			wantSource:     SourceCatalog,
+			wantConfidence: ConfidenceCatalog,
+		},
+		{
+			name:           "Canonical name in description",
+			study:          models.Study{ProcedureCode: "X123", ProcedureDescription: "MRI Knees bilateral", Modality: "MRI"},
+			wantModality:   "MRI",
+			wantBodyPart:   "KNEES",
+			wantSource:     SourceDescription,
+			wantConfidence: ConfidenceCanonical,
+			wantAttributes: []string{"BILATERAL"},
+		},
+		{
+			name:           "Misspelling in description",
+			study:          models.Study{ProcedureCode: "US01", ProcedureDescription: "US ABDOEMN complete", Modality: "US"},
+			wantModality:   "US",
+			wantBodyPart:   "ABDOMEN",
+			wantSource:     SourceDescription,
+			wantConfidence: ConfidenceSynonym,
+			wantAttributes: []string{"COMPLETE"},
+		},
+		{
+			name:           "Misspelled body part from message",
+			study:          models.Study{ProcedureCode: "MR SHOULER", Modality: "MR", BodyPart: "SHOULER"},
+			wantModality:   "MR",
+			wantBodyPart:   "SHOULDERS",
+			wantSource:     SourceDescription,
+			wantConfidence: ConfidenceSynonym,
+		},
+		{
+			name:           "Joined tokens",
+			study:          models.Study{ProcedureDescription: "XR Lower Extremity"},
+			wantBodyPart:   "LOWEREXTREMITY",
+			wantSource:     SourceDescription,
+			wantConfidence: ConfidenceCanonical,
+		},
+		{
+			name:           "Several body parts lower confidence",
+			study:          models.Study{ProcedureDescription: "CT CHEST ABDOMEN PELVIS"},
+			wantBodyPart:   "CHEST",
+			wantSource:     SourceDescription,
+			wantConfidence: ConfidenceCanonical - ambiguityPenalty,
+		},
+		{
+			name:         "Unmapped keeps message values",
+			study:        models.Study{ProcedureCode: "ZZ99", ProcedureDescription: "Special protocol", Modality: "NM", BodyPart: "MSK"},
+			wantModality: "NM",
+			wantBodyPart: "MSK",
+			wantSource:   SourceUnmapped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			study := tt.study
+			n.Normalize(&study, tt.proc)
+
+			if study.Modality != tt.wantModality {
+				t.Errorf("Expected modality %q, got %q", tt.wantModality, study.Modality)
+			}
+			if study.BodyPart != tt.wantBodyPart {
+				t.Errorf("Expected body part %q, got %q", tt.wantBodyPart, study.BodyPart)
+			}
+			if study.NormalizationSource != tt.wantSource {
+				t.Errorf("Expected source %q, got %q", tt.wantSource, study.NormalizationSource)
+			}
+			if study.NormalizationConfidence != tt.wantConfidence {
+				t.Errorf("Expected confidence %v, got %v", tt.wantConfidence, study.NormalizationConfidence)
+			}
+			if !reflect.DeepEqual(study.Attributes, tt.wantAttributes) {
+				t.Errorf("Expected attributes %v, got %v", tt.wantAttributes, study.Attributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"radiology-assignment/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	deadDir   = "dead"
+	resendDir = "resend"
+)
+
+var ErrDeadLetterNotFound = errors.New("queue: dead letter not found")
+
+// DeadLetter is an outbound assignment notification the emitter gave up on.
+type DeadLetter struct {
+	ID        string                  `json:"id"`
+	Event     *models.AssignmentEvent `json:"event"`
+	Message   string                  `json:"message"` // Last encoded HL7 payload attempted
+	Attempts  int                     `json:"attempts"`
+	LastError string                  `json:"last_error"`
+	FailedAt  time.Time               `json:"failed_at"`
+}
+
+// DeadLetterStore keeps dead letters as one JSON file each, so the emitter
+// and the API can share it across processes. An entry lives in dead/ until an
+// operator either discards it or requests a resend, which moves it to
+// resend/ for the emitter to pick up.
+type DeadLetterStore struct {
+	dir string
+}
+
+func NewDeadLetterStore(dir string) (*DeadLetterStore, error) {
+	for _, sub := range []string{deadDir, resendDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("queue: create dead letter dir: %w", err)
+		}
+	}
+	return &DeadLetterStore{dir: dir}, nil
+}
+
+func (s *DeadLetterStore) path(sub, id string) string {
+	return filepath.Join(s.dir, sub, id+".json")
+}
+
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// Add durably records dl in the dead letter queue. An ID is allocated when
+// dl.ID is empty.
+func (s *DeadLetterStore) Add(dl *DeadLetter) error {
+	if dl.ID == "" {
+		dl.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	if !validID(dl.ID) {
+		return fmt.Errorf("queue: invalid dead letter id %q", dl.ID)
+	}
+	if dl.FailedAt.IsZero() {
+		dl.FailedAt = time.Now()
+	}
+
+	data, err := json.MarshalIndent(dl, "", "  ")
+	if err != nil {
+		return fmt.Errorf("queue: encode dead letter: %w", err)
+	}
+
+	// Write then rename so readers never observe a partial file
+	tmp := filepath.Join(s.dir, dl.ID+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("queue: write dead letter: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("queue: write dead letter: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("queue: fsync dead letter: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(deadDir, dl.ID)); err != nil {
+		return fmt.Errorf("queue: store dead letter: %w", err)
+	}
+	return syncDir(filepath.Join(s.dir, deadDir))
+}
+
+// List returns dead letters awaiting an operator decision, oldest first.
+func (s *DeadLetterStore) List() ([]*DeadLetter, error) {
+	return s.list(deadDir)
+}
+
+// PendingResends returns dead letters an operator has asked to resend.
+func (s *DeadLetterStore) PendingResends() ([]*DeadLetter, error) {
+	return s.list(resendDir)
+}
+
+func (s *DeadLetterStore) list(sub string) ([]*DeadLetter, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("queue: list dead letters: %w", err)
+	}
+
+	var result []*DeadLetter
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		dl, err := s.read(filepath.Join(s.dir, sub, entry.Name()))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Moved by another process while listing
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, dl)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FailedAt.Before(result[j].FailedAt)
+	})
+	return result, nil
+}
+
+func (s *DeadLetterStore) read(path string) (*DeadLetter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dl DeadLetter
+	if err := json.Unmarshal(data, &dl); err != nil {
+		return nil, fmt.Errorf("queue: decode dead letter %s: %w", filepath.Base(path), err)
+	}
+	return &dl, nil
+}
+
+// Get returns a dead letter that is awaiting an operator decision.
+func (s *DeadLetterStore) Get(id string) (*DeadLetter, error) {
+	if !validID(id) {
+		return nil, ErrDeadLetterNotFound
+	}
+	dl, err := s.read(s.path(deadDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dl, err
+}
+
+// Resend hands a dead letter back to the emitter for another delivery cycle.
+func (s *DeadLetterStore) Resend(id string) error {
+	return s.move(id, deadDir, resendDir)
+}
+
+// Discard permanently removes a dead letter.
+func (s *DeadLetterStore) Discard(id string) error {
+	if !validID(id) {
+		return ErrDeadLetterNotFound
+	}
+	err := os.Remove(s.path(deadDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrDeadLetterNotFound
+	}
+	return err
+}
+
+// Delivered removes a resent dead letter once the emitter has delivered it.
+func (s *DeadLetterStore) Delivered(id string) error {
+	if !validID(id) {
+		return ErrDeadLetterNotFound
+	}
+	err := os.Remove(s.path(resendDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrDeadLetterNotFound
+	}
+	return err
+}
+
+// Requeue returns a resent dead letter to the dead letter queue after another
+// failed delivery cycle, recording the new attempt count and error.
+func (s *DeadLetterStore) Requeue(dl *DeadLetter) error {
+	if err := s.Add(dl); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(resendDir, dl.ID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *DeadLetterStore) move(id, from, to string) error {
+	if !validID(id) {
+		return ErrDeadLetterNotFound
+	}
+	err := os.Rename(s.path(from, id), s.path(to, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Join(s.dir, to))
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"errors"
+	"radiology-assignment/internal/models"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterStore_Lifecycle(t *testing.T) {
+	store, err := NewDeadLetterStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDeadLetterStore failed: %v", err)
+	}
+
+	first := &DeadLetter{Event: &models.AssignmentEvent{Study: &models.Study{ID: "S1"}}, Attempts: 5, FailedAt: time.Now().Add(-time.Minute)}
+	second := &DeadLetter{ID: "manual", Event: &models.AssignmentEvent{Study: &models.Study{ID: "S2"}}, Attempts: 1}
+	for _, dl := range []*DeadLetter{first, second} {
+		if err := store.Add(dl); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if first.ID == "" {
+		t.Fatal("Expected an ID to be allocated")
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID {
+		t.Fatalf("Expected 2 dead letters oldest first, got %v", list)
+	}
+
+	got, err := store.Get("manual")
+	if err != nil || got.Event.Study.ID != "S2" {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	if err := store.Resend(first.ID); err != nil {
+		t.Fatalf("Resend failed: %v", err)
+	}
+	pending, _ := store.PendingResends()
+	if len(pending) != 1 || pending[0].ID != first.ID {
+		t.Errorf("Expected %s pending resend, got %v", first.ID, pending)
+	}
+	if err := store.Delivered(first.ID); err != nil {
+		t.Errorf("Delivered failed: %v", err)
+	}
+
+	if err := store.Discard("manual"); err != nil {
+		t.Errorf("Discard failed: %v", err)
+	}
+	list, _ = store.List()
+	if len(list) != 0 {
+		t.Errorf("Expected empty store, got %d entries", len(list))
+	}
+}
+
+func TestDeadLetterStore_NotFound(t *testing.T) {
+	store, _ := NewDeadLetterStore(t.TempDir())
+	for _, id := range []string{"missing", "../escape", ""} {
+		if err := store.Resend(id); !errors.Is(err, ErrDeadLetterNotFound) {
+			t.Errorf("Resend(%q): expected ErrDeadLetterNotFound, got %v", id, err)
+		}
+		if err := store.Discard(id); !errors.Is(err, ErrDeadLetterNotFound) {
+			t.Errorf("Discard(%q): expected ErrDeadLetterNotFound, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package queue
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// On-disk layout: every record is an 8 byte header (payload length and CRC32
+// of the payload, both big-endian uint32) followed by the JSON encoded value.
+const (
+	journalFile  = "records.journal"
+	headerSize   = 8
+	maxRecordLen = 16 << 20
+
+	DefaultPollInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrCorrupt = errors.New("queue: journal record failed checksum")
+	ErrClosed  = errors.New("queue: journal closed")
+)
+
+// Journal is an append-only, fsync'd log of T. The listener appends inbound
+// studies before acknowledging a message, so once the sender sees an AA the
+// order survives a crash of any process; the engine uses a second journal to
+// hand completed assignments to the emitter.
+type Journal[T any] struct {
+	mu   sync.Mutex
+	dir  string
+	file *os.File
+	size int64
+}
+
+// Open opens (or creates) the journal in dir. A record left half-written by a
+// crash is truncated away; it was never acknowledged so the sender will retry.
+func Open[T any](dir string) (*Journal[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("queue: create journal dir: %w", err)
+	}
+
+	path := filepath.Join(dir, journalFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("queue: open journal: %w", err)
+	}
+
+	valid, err := scanValid[T](f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("queue: truncate torn record: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := syncDir(dir); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Journal[T]{dir: dir, file: f, size: valid}, nil
+}
+
+// scanValid returns the offset just past the last complete record. A record
+// failing its checksum is only tolerated as the final record of the file,
+// where it is the remains of an interrupted write.
+func scanValid[T any](f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	for {
+		_, next, err := readRecord[T](f, offset)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return offset, nil
+			}
+			if errors.Is(err, ErrCorrupt) && lastRecordEnd(f, offset) >= info.Size() {
+				return offset, nil
+			}
+			return 0, fmt.Errorf("queue: journal offset %d: %w", offset, err)
+		}
+		offset = next
+	}
+}
+
+// lastRecordEnd returns where the record at offset claims to end.
+func lastRecordEnd(r io.ReaderAt, offset int64) int64 {
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil {
+		return offset
+	}
+	return offset + headerSize + int64(binary.BigEndian.Uint32(header[0:4]))
+}
+
+// Append durably writes v to the journal and returns its offset. It only
+// returns once the record has been fsync'd.
+func (j *Journal[T]) Append(v *T) (int64, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("queue: encode record: %w", err)
+	}
+	if len(payload) > maxRecordLen {
+		return 0, fmt.Errorf("queue: record of %d bytes exceeds limit", len(payload))
+	}
+
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return 0, ErrClosed
+	}
+
+	offset := j.size
+	if _, err := j.file.WriteAt(buf, offset); err != nil {
+		// Drop whatever part of the record made it to disk
+		j.file.Truncate(offset)
+		return 0, fmt.Errorf("queue: write record: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Truncate(offset)
+		return 0, fmt.Errorf("queue: fsync: %w", err)
+	}
+	j.size += int64(len(buf))
+	return offset, nil
+}
+
+// Close closes the journal file.
+func (j *Journal[T]) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Record is a value read back from the journal.
+type Record[T any] struct {
+	Offset int64
+	Next   int64 // offset of the following record, persisted on Commit
+	Value  *T
+}
+
+// Consumer reads the journal from a persisted cursor. Delivery is
+// at-least-once: a record is redelivered after a restart unless Commit was
+// called for it, so processing must be idempotent (e.g. on Study.ID).
+type Consumer[T any] struct {
+	name         string
+	dir          string
+	file         *os.File
+	cursor       int64
+	PollInterval time.Duration
+}
+
+// NewConsumer opens a named reader over the journal in dir. The name scopes
+// the cursor file so several independent consumers can share a journal.
+func NewConsumer[T any](dir, name string) (*Consumer[T], error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("queue: invalid consumer name %q", name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("queue: create journal dir: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_RDONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("queue: open journal: %w", err)
+	}
+
+	c := &Consumer[T]{name: name, dir: dir, file: f, PollInterval: DefaultPollInterval}
+	cursor, err := c.loadCursor()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	c.cursor = cursor
+	return c, nil
+}
+
+func (c *Consumer[T]) cursorPath() string {
+	return filepath.Join(c.dir, c.name+".cursor")
+}
+
+func (c *Consumer[T]) loadCursor() (int64, error) {
+	data, err := os.ReadFile(c.cursorPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("queue: read cursor: %w", err)
+	}
+	cursor, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("queue: invalid cursor file: %w", err)
+	}
+	return cursor, nil
+}
+
+// Cursor returns the offset of the next uncommitted record.
+func (c *Consumer[T]) Cursor() int64 {
+	return c.cursor
+}
+
+// Next blocks until the record at the cursor is available or ctx is done.
+// Calling Next again without Commit returns the same record.
+func (c *Consumer[T]) Next(ctx context.Context) (*Record[T], error) {
+	for {
+		v, next, err := readRecord[T](c.file, c.cursor)
+		if err == nil {
+			return &Record[T]{Offset: c.cursor, Next: next, Value: v}, nil
+		}
+		// EOF, a record still being written, or a torn tail the listener
+		// will truncate on restart: wait for the next append
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) && !c.isTornTail(err) {
+			return nil, fmt.Errorf("queue: journal offset %d: %w", c.cursor, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+func (c *Consumer[T]) isTornTail(err error) bool {
+	if !errors.Is(err, ErrCorrupt) {
+		return false
+	}
+	info, statErr := c.file.Stat()
+	return statErr == nil && lastRecordEnd(c.file, c.cursor) >= info.Size()
+}
+
+// Commit marks rec as processed and durably advances the cursor past it.
+func (c *Consumer[T]) Commit(rec *Record[T]) error {
+	tmp := c.cursorPath() + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("queue: write cursor: %w", err)
+	}
+	if _, err := f.WriteString(strconv.FormatInt(rec.Next, 10)); err != nil {
+		f.Close()
+		return fmt.Errorf("queue: write cursor: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("queue: fsync cursor: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.cursorPath()); err != nil {
+		return fmt.Errorf("queue: replace cursor: %w", err)
+	}
+	if err := syncDir(c.dir); err != nil {
+		return err
+	}
+	c.cursor = rec.Next
+	return nil
+}
+
+// Close closes the consumer's read handle.
+func (c *Consumer[T]) Close() error {
+	return c.file.Close()
+}
+
+// readRecord decodes the record at offset and returns it with the offset of
+// the next record.
+func readRecord[T any](r io.ReaderAt, offset int64) (*T, int64, error) {
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			// A partial header at the tail is an in-flight or torn write
+			n, _ := r.ReadAt(header[:1], offset)
+			if n > 0 {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+		}
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if length > maxRecordLen {
+		return nil, 0, ErrCorrupt
+	}
+
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+headerSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, 0, ErrCorrupt
+	}
+
+	var v T
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, 0, fmt.Errorf("queue: decode record: %w", err)
+	}
+	return &v, offset + headerSize + int64(length), nil
+}
+
+// syncDir fsyncs a directory so newly created or renamed files survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("queue: fsync dir: %w", err)
+	}
+	return nil
+}
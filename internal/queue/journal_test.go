@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"radiology-assignment/internal/models"
+	"testing"
+	"time"
+)
+
+func nextWithTimeout(t *testing.T, c *Consumer[models.Study]) (*Record[models.Study], error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	return c.Next(ctx)
+}
+
+func TestJournal_AppendAndConsume(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open[models.Study](dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer j.Close()
+
+	for _, id := range []string{"S1", "S2"} {
+		if _, err := j.Append(&models.Study{ID: id, Modality: "CT"}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	c, err := NewConsumer[models.Study](dir, "engine")
+	if err != nil {
+		t.Fatalf("NewConsumer failed: %v", err)
+	}
+	c.PollInterval = 5 * time.Millisecond
+
+	rec, err := nextWithTimeout(t, c)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if rec.Value.ID != "S1" {
+		t.Errorf("Expected S1, got %s", rec.Value.ID)
+	}
+
+	// Without a commit the same record is redelivered
+	again, _ := nextWithTimeout(t, c)
+	if again == nil || again.Value.ID != "S1" {
+		t.Errorf("Expected S1 to be redelivered before commit")
+	}
+
+	if err := c.Commit(rec); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	c.Close()
+
+	// A restarted consumer resumes after the committed record
+	c, err = NewConsumer[models.Study](dir, "engine")
+	if err != nil {
+		t.Fatalf("NewConsumer failed: %v", err)
+	}
+	defer c.Close()
+	c.PollInterval = 5 * time.Millisecond
+
+	rec, err = nextWithTimeout(t, c)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if rec.Value.ID != "S2" {
+		t.Errorf("Expected S2 after restart, got %s", rec.Value.ID)
+	}
+	if err := c.Commit(rec); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if _, err := nextWithTimeout(t, c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Next to block at end of journal, got %v", err)
+	}
+}
+
+func TestJournal_TruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open[models.Study](dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := j.Append(&models.Study{ID: "S1"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	j.Close()
+
+	// Simulate a crash part way through writing a second record
+	path := filepath.Join(dir, journalFile)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open journal file: %v", err)
+	}
+	f.Write([]byte{0, 0, 0, 50, 1, 2, 3, 4, '{', '"'})
+	f.Close()
+
+	c, err := NewConsumer[models.Study](dir, "engine")
+	if err != nil {
+		t.Fatalf("NewConsumer failed: %v", err)
+	}
+	defer c.Close()
+	c.PollInterval = 5 * time.Millisecond
+
+	rec, _ := nextWithTimeout(t, c)
+	if rec == nil || rec.Value.ID != "S1" {
+		t.Fatalf("Expected S1 before torn record")
+	}
+	c.Commit(rec)
+	if _, err := nextWithTimeout(t, c); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected consumer to wait on torn record, got %v", err)
+	}
+
+	j, err = Open[models.Study](dir)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer j.Close()
+	if _, err := j.Append(&models.Study{ID: "S2"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	rec, err = nextWithTimeout(t, c)
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if rec.Value.ID != "S2" {
+		t.Errorf("Expected S2 written over the torn record, got %s", rec.Value.ID)
+	}
+}
+
+func TestJournal_AppendAfterClose(t *testing.T) {
+	j, err := Open[models.Study](t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	j.Close()
+	if _, err := j.Append(&models.Study{ID: "S1"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}